@@ -4,39 +4,158 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"redditclone/pkg/account"
+	"redditclone/pkg/blob"
+	"redditclone/pkg/cache"
 	"redditclone/pkg/handlers"
 	"redditclone/pkg/key"
 	"redditclone/pkg/logger"
+	"redditclone/pkg/mail"
+	"redditclone/pkg/media"
+	"redditclone/pkg/metrics"
 	"redditclone/pkg/middleware"
+	"redditclone/pkg/oidc"
+	"redditclone/pkg/outbox"
 	"redditclone/pkg/posts"
+	"redditclone/pkg/report"
 	"redditclone/pkg/session"
+	"redditclone/pkg/token"
+	"redditclone/pkg/tracing"
+	"redditclone/pkg/unfurl"
 	"redditclone/pkg/user"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
+const (
+	defaultHideThreshold = 3
+	cacheTTL             = 5 * time.Minute
+	lruCapacity          = 10000
+	outboxDedupTTL       = 24 * time.Hour
+)
+
+func newCache() cache.Cache {
+	addr := os.Getenv("RedisAddr")
+	if addr == "" {
+		return cache.NewLRU(lruCapacity)
+	}
+	return cache.NewRedis(redis.NewClient(&redis.Options{Addr: addr}), "redditclone:")
+}
+
+func newOutboxSink(seen outbox.SeenStore) (outbox.Sink, error) {
+	var sink outbox.Sink = outbox.NewWriterSink(os.Stdout)
+	target := os.Getenv("OutboxSink")
+	if path, ok := strings.CutPrefix(target, "file:"); ok {
+		fileSink, err := outbox.NewFileSink(path)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	}
+	return outbox.NewIdempotentSink(sink, seen, outboxDedupTTL), nil
+}
+
+func newBlobStorage() (blob.Storage, error) {
+	if os.Getenv("BlobStorage") == "s3" {
+		signer := blob.NewSigner(os.Getenv("S3AccessKey"), os.Getenv("S3SecretKey"), os.Getenv("S3Region"))
+		return blob.NewS3(os.Getenv("S3Endpoint"), os.Getenv("S3Bucket"), signer), nil
+	}
+	dir := os.Getenv("MediaDir")
+	if dir == "" {
+		dir = "uploads"
+	}
+	return blob.NewLocal(dir)
+}
+
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("MailFrom")
+	if from == "" {
+		from = "redditclone <noreply@localhost>"
+	}
+	target := os.Getenv("Mailer")
+	if target == "smtp" {
+		return mail.NewSMTPMailer(os.Getenv("SMTPAddr"), from, os.Getenv("SMTPUser"), os.Getenv("SMTPPassword")), nil
+	}
+	if path, ok := strings.CutPrefix(target, "file:"); ok {
+		return mail.NewFileMailer(path, from)
+	}
+	return mail.NewWriterMailer(os.Stdout, from), nil
+}
+
+func publicURL() string {
+	if url := os.Getenv("PublicURL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// newOIDC returns nil when no provider is configured.
+func newOIDC(redirectURL string) (*oidc.Client, error) {
+	issuer := os.Getenv("OIDCIssuer")
+	if issuer == "" {
+		return nil, nil
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	endpoints, err := oidc.Discover(ctx, httpClient, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return oidc.NewClient(oidc.Config{
+		ClientID:     os.Getenv("OIDCClientID"),
+		ClientSecret: os.Getenv("OIDCClientSecret"),
+		RedirectURL:  redirectURL,
+	}, endpoints, httpClient), nil
+}
+
+func hideThreshold() int64 {
+	threshold, err := strconv.ParseInt(os.Getenv("ReportHideThreshold"), 10, 64)
+	if err != nil {
+		return defaultHideThreshold
+	}
+	return threshold
+}
+
+func logConfig() logger.Config {
+	encoding := os.Getenv("LogEncoding")
+	if encoding == "" {
+		encoding = logger.EncodingConsole
+	}
+	level := os.Getenv("LogLevel")
+	if level == "" {
+		level = "Info"
+	}
+	return logger.Config{
+		Level:       level,
+		Encoding:    encoding,
+		Development: encoding == logger.EncodingConsole,
+		Sampling:    os.Getenv("LogSampling") == "true",
+		File:        os.Getenv("LogFile"),
+		MaxSizeMB:   100,
+		MaxBackups:  5,
+		MaxAgeDays:  30,
+	}
+}
+
 // 05_web_app\99_hw\redditclone\cmd\redditclone\main.go
 func main() {
-	zapConfig := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapcore.InfoLevel),
-		Development:      true,
-		Encoding:         "console",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
-	}
-	logger, err := logger.NewCustomLogger(zapConfig)
+	logger, err := logger.New(logConfig())
 	if err != nil {
 		panic(err.Error())
 	}
+	defer logger.Sync()
 
 	dsn := "root:1234@tcp(localhost:3306)/redditclone?"
 	dsn += "charset=utf8"
@@ -52,8 +171,9 @@ func main() {
 	}
 	fmt.Println("Connected to SQL!")
 
-	// Mongo connection
-	connectionString := "mongodb://localhost:27017"
+	// Mongo connection, post writes and their outbox events share a
+	// transaction, which needs a replica set (see utils/docker)
+	connectionString := "mongodb://localhost:27017/?replicaSet=rs0"
 	connection, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(connectionString))
 	if err != nil {
 		panic(err)
@@ -65,6 +185,8 @@ func main() {
 
 	fmt.Println("Connected to MongoDB!")
 	collection := connection.Database("redditclone").Collection("posts")
+	reportsCollection := connection.Database("redditclone").Collection("reports")
+	outboxCollection := connection.Database("redditclone").Collection("outbox")
 
 	defer func() {
 		if err = connection.Disconnect(context.TODO()); err != nil {
@@ -74,12 +196,79 @@ func main() {
 	}()
 
 	////
-	repo := user.NewUserSQLRepo(db)
-	sess := session.NewSessionSQLRepo(db)
-	postsRepo := posts.NewPostsMongoRepo(collection)
-	if err = sess.DownloadKey(); err != nil {
+	var traceOut io.Writer
+	if os.Getenv("TraceExport") == "stdout" {
+		traceOut = os.Stdout
+	}
+	tracerProvider, err := tracing.NewProvider(traceOut)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = tracerProvider.Shutdown(context.Background()); err != nil {
+			log.Println(err)
+		}
+	}()
+	appTracing := tracing.NewTracing(tracerProvider)
+	appMetrics := metrics.NewMetrics()
+
+	appCache := newCache()
+
+	repo := cache.NewUserRepo(metrics.NewUserRepo(tracing.NewUserRepo(user.NewUserSQLRepo(db), appTracing), appMetrics), appCache, cacheTTL)
+	sqlSess := session.NewSessionSQLRepo(db)
+	postsMongo := posts.NewPostsMongoRepoWithOutbox(collection, outboxCollection)
+	if err = postsMongo.EnsureIndexes(context.Background()); err != nil {
+		panic(err)
+	}
+	migrated, err := postsMongo.MigrateComments(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	if migrated > 0 {
+		logger.Log("Info", fmt.Sprintf("moved %d embedded comments to the comments collection", migrated))
+	}
+	postsRepo := cache.NewPostsRepository(metrics.NewPostsRepository(tracing.NewPostsRepository(postsMongo, appTracing), appMetrics), appCache, cacheTTL)
+	reportsRepo := report.NewReportsMongoRepo(reportsCollection)
+	if err = reportsRepo.EnsureIndexes(context.Background()); err != nil {
+		panic(err)
+	}
+	if err = sqlSess.DownloadKey(); err != nil {
 		panic(err.Error())
 	}
+	sess := cache.NewSessionManager(metrics.NewSessionManager(tracing.NewSessionManager(sqlSess, appTracing), appMetrics), appCache, cacheTTL)
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	unfurler := unfurl.NewUnfurler(unfurl.DefaultTimeout, unfurl.DefaultMaxBytes)
+	unfurlWorker := unfurl.NewWorker(unfurler, postsRepo, logger, 100)
+	go unfurlWorker.Run(workersCtx)
+
+	outboxSink, err := newOutboxSink(appCache)
+	if err != nil {
+		panic(err)
+	}
+	relay := outbox.NewRelay(outboxCollection, outboxSink, logger)
+	go relay.Run(workersCtx)
+
+	accounts := account.NewService(repo, sess, postsRepo, account.NewJobSQLRepo(db), logger, 100)
+	go accounts.Run(workersCtx)
+	if err = accounts.Resume(workersCtx); err != nil {
+		logger.Log("Error", err.Error())
+	}
+
+	mailer, err := newMailer()
+	if err != nil {
+		panic(err)
+	}
+	tokenKey := []byte(os.Getenv("TokenKey"))
+	if len(tokenKey) == 0 {
+		tokenKey = sqlSess.GetKey().([]byte)
+	}
+
+	oidcClient, err := newOIDC(publicURL() + "/api/oidc/callback")
+	if err != nil {
+		panic(err)
+	}
+
 	var key key.Key = "author"
 	userHandler := &handlers.UserHandler{
 		Logger:     logger,
@@ -87,49 +276,60 @@ func main() {
 		Session:    sess,
 		PostsRepo:  postsRepo,
 		ContextKey: key,
+		Mailer:     mailer,
+		Tokens:     token.NewSigner(tokenKey),
+		PublicURL:  publicURL(),
+		OIDC:       oidcClient,
 	}
 	postsHandler := &handlers.PostsHandler{
 		Logger:     logger,
 		PostsRepo:  postsRepo,
 		Session:    sess,
 		ContextKey: key,
+		Unfurl:     unfurlWorker,
 	}
-	r := mux.NewRouter()
-	r.Handle("/", http.FileServer(http.Dir("../../static/html")))
-	r.HandleFunc("/api/login", userHandler.LogIn)
-	r.HandleFunc("/api/register", userHandler.SignIn)
-	r.HandleFunc("/api/posts/", postsHandler.All).Methods("GET")
-	r.HandleFunc("/api/user/{USER_LOGIN}", userHandler.GetUserPosts).Methods("GET")
-
-	newPostHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, http.HandlerFunc(postsHandler.NewPost)))
-	r.Handle("/api/posts", newPostHandler).Methods("POST")
-
-	r.HandleFunc("/api/post/{POST_ID}", postsHandler.GetPost).Methods("GET")
-	r.HandleFunc("/api/posts/{CATEGORY_NAME}", postsHandler.GetPostsByCategory).Methods("GET")
-
-	deletePostHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, middleware.Authorize(key, logger, postsRepo, http.HandlerFunc(postsHandler.DeletePost))))
-	r.Handle("/api/post/{POST_ID}", deletePostHandler).Methods("DELETE")
-
-	newCommentHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, http.HandlerFunc(postsHandler.AddComment)))
-	r.Handle("/api/post/{POST_ID}", newCommentHandler).Methods("POST")
-
-	deleteCommentHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, middleware.Authorize(key, logger, postsRepo, http.HandlerFunc(postsHandler.DeleteComment))))
-	r.Handle("/api/post/{POST_ID}/{COMMENT_ID}", deleteCommentHandler).Methods("DELETE")
-
-	upvoteHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, http.HandlerFunc(postsHandler.Vote)))
-	r.Handle("/api/post/{POST_ID}/upvote", upvoteHandler).Methods("GET")
-
-	downvoteHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, http.HandlerFunc(postsHandler.Vote)))
-	r.Handle("/api/post/{POST_ID}/downvote", downvoteHandler).Methods("GET")
-
-	unvoteHandler := middleware.JWT(key, logger, sess, middleware.Authenticate(key, logger, repo, http.HandlerFunc(postsHandler.UnVote)))
-	r.Handle("/api/post/{POST_ID}/unvote", unvoteHandler).Methods("GET")
-
-	r.PathPrefix("/static/css/").Handler(http.StripPrefix("/static/css/", http.FileServer(http.Dir("../../static/css"))))
-	r.PathPrefix("/static/js/").Handler(http.StripPrefix("/static/js/", http.FileServer(http.Dir("../../static/js"))))
+	accountHandler := &handlers.AccountHandler{
+		Logger:     logger,
+		Accounts:   accounts,
+		ContextKey: key,
+	}
+	blobs, err := newBlobStorage()
+	if err != nil {
+		panic(err)
+	}
+	mediaHandler := &handlers.MediaHandler{
+		Logger:     logger,
+		PostsRepo:  postsRepo,
+		Blobs:      blobs,
+		Processor:  media.NewProcessor(),
+		ContextKey: key,
+	}
+	reportsHandler := &handlers.ReportsHandler{
+		Logger:        logger,
+		PostsRepo:     postsRepo,
+		Reports:       reportsRepo,
+		ContextKey:    key,
+		HideThreshold: hideThreshold(),
+	}
+	r := newRouter(routes{
+		Key:            key,
+		Logger:         logger,
+		LogLevel:       logger.Level(),
+		Sessions:       sess,
+		Users:          repo,
+		PostsRepo:      postsRepo,
+		Metrics:        appMetrics,
+		Tracing:        appTracing,
+		UserHandler:    userHandler,
+		PostsHandler:   postsHandler,
+		AccountHandler: accountHandler,
+		ReportsHandler: reportsHandler,
+		MediaHandler:   mediaHandler,
+	})
 
 	nw := middleware.Logging(logger, r)
 	nw = middleware.Panic(logger, nw)
+	nw = middleware.RequestID(logger, nw)
 	err = http.ListenAndServe(":8080", nw)
 	if err != nil {
 		panic("server didn't start")
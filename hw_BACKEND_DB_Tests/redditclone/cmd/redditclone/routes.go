@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/openapi"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/router"
+	"redditclone/pkg/session"
+	"redditclone/pkg/tracing"
+	"redditclone/pkg/user"
+
+	"github.com/gorilla/mux"
+)
+
+type routes struct {
+	Key            key.Key
+	Logger         logger.Logger
+	LogLevel       http.Handler
+	Sessions       session.SessionManager
+	Users          user.UserRepo
+	PostsRepo      posts.PostsRepository
+	Metrics        *metrics.Metrics
+	Tracing        *tracing.Tracing
+	UserHandler    *handlers.UserHandler
+	PostsHandler   *handlers.PostsHandler
+	AccountHandler *handlers.AccountHandler
+	ReportsHandler *handlers.ReportsHandler
+	MediaHandler   *handlers.MediaHandler
+}
+
+func apiRoutes(rt routes) *router.Builder {
+	b := router.NewBuilder(rt.Key, rt.Logger, rt.Sessions, rt.Users, rt.PostsRepo)
+
+	b.HandleFunc(router.Public, "/api/login", rt.UserHandler.LogIn, "POST")
+	b.HandleFunc(router.Public, "/api/login/2fa", rt.UserHandler.LogInTwoFactor, "POST")
+	b.HandleFunc(router.Public, "/api/register", rt.UserHandler.SignIn, "POST")
+	b.Handle(router.Public, "/api/openapi.json", openapi.Handler(), "GET")
+	b.HandleFunc(router.Public, "/api/posts/", rt.PostsHandler.All, "GET")
+	b.HandleFunc(router.Public, "/api/user/{USER_LOGIN}", rt.UserHandler.GetUserPosts, "GET")
+
+	b.HandleFunc(router.Authenticated, "/api/user/me/export", rt.AccountHandler.Export, "GET")
+	b.HandleFunc(router.Authenticated, "/api/user/me", rt.AccountHandler.Delete, "DELETE")
+	b.HandleFunc(router.Authenticated, "/api/user/me/email", rt.UserHandler.SetEmail, "POST")
+	b.HandleFunc(router.Authenticated, "/api/user/me/2fa", rt.UserHandler.EnrollTwoFactor, "POST")
+	b.HandleFunc(router.Authenticated, "/api/user/me/2fa/confirm", rt.UserHandler.ConfirmTwoFactor, "POST")
+	b.HandleFunc(router.Authenticated, "/api/user/me/2fa", rt.UserHandler.DisableTwoFactor, "DELETE")
+	b.HandleFunc(router.Public, "/api/user/verify", rt.UserHandler.Verify, "POST")
+	b.HandleFunc(router.Public, "/api/oidc/login", rt.UserHandler.OIDCLogin, "GET")
+	b.HandleFunc(router.Public, "/api/oidc/callback", rt.UserHandler.OIDCCallback, "GET")
+	b.HandleFunc(router.Public, "/api/password/reset", rt.UserHandler.RequestReset, "POST")
+	b.HandleFunc(router.Public, "/api/password/reset/confirm", rt.UserHandler.ConfirmReset, "POST")
+
+	b.HandleFunc(router.Authenticated, "/api/posts", rt.PostsHandler.NewPost, "POST")
+	b.HandleFunc(router.Authenticated, "/api/posts/image", rt.MediaHandler.NewImagePost, "POST")
+	b.HandleFunc(router.Public, "/api/media/{KEY}", rt.MediaHandler.Get, "GET")
+	b.HandleFunc(router.Public, "/api/post/{POST_ID}", rt.PostsHandler.GetPost, "GET")
+	b.HandleFunc(router.Public, "/api/posts/{CATEGORY_NAME}", rt.PostsHandler.GetPostsByCategory, "GET")
+	b.HandleFunc(router.Owner, "/api/post/{POST_ID}", rt.PostsHandler.DeletePost, "DELETE")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}", rt.PostsHandler.AddComment, "POST")
+	b.HandleFunc(router.Owner, "/api/post/{POST_ID}/{COMMENT_ID}", rt.PostsHandler.DeleteComment, "DELETE")
+	b.HandleFunc(router.Public, "/api/post/{POST_ID}/comments", rt.PostsHandler.GetComments, "GET")
+
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/upvote", rt.PostsHandler.Vote, "GET")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/downvote", rt.PostsHandler.Vote, "GET")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/unvote", rt.PostsHandler.UnVote, "GET")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/{COMMENT_ID}/upvote", rt.PostsHandler.VoteComment, "POST")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/{COMMENT_ID}/downvote", rt.PostsHandler.VoteComment, "POST")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/{COMMENT_ID}/unvote", rt.PostsHandler.VoteComment, "POST")
+
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/report", rt.ReportsHandler.Report, "POST")
+	b.HandleFunc(router.Authenticated, "/api/post/{POST_ID}/{COMMENT_ID}/report", rt.ReportsHandler.Report, "POST")
+	b.HandleFunc(router.Moderator, "/api/moderation/reports", rt.ReportsHandler.Queue, "GET")
+	b.HandleFunc(router.Moderator, "/api/moderation/post/{POST_ID}/approve", rt.ReportsHandler.Approve, "POST")
+	b.HandleFunc(router.Moderator, "/api/moderation/post/{POST_ID}/{COMMENT_ID}/approve", rt.ReportsHandler.Approve, "POST")
+	b.HandleFunc(router.Moderator, "/api/moderation/post/{POST_ID}/remove", rt.ReportsHandler.Remove, "POST")
+	b.HandleFunc(router.Moderator, "/api/moderation/post/{POST_ID}/{COMMENT_ID}/remove", rt.ReportsHandler.Remove, "POST")
+
+	b.Handle(router.Admin, "/api/admin/loglevel", rt.LogLevel, "GET", "PUT")
+	return b
+}
+
+func newRouter(rt routes) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(rt.Tracing.Middleware, rt.Metrics.Middleware)
+	r.Handle("/metrics", rt.Metrics.Handler()).Methods("GET")
+	r.Handle("/", http.FileServer(http.Dir("../../static/html")))
+
+	apiRoutes(rt).Mount(r)
+
+	r.PathPrefix("/static/css/").Handler(http.StripPrefix("/static/css/", http.FileServer(http.Dir("../../static/css"))))
+	r.PathPrefix("/static/js/").Handler(http.StripPrefix("/static/js/", http.FileServer(http.Dir("../../static/js"))))
+
+	return r
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"redditclone/pkg/handlers"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/metrics"
+	"redditclone/pkg/openapi"
+	"redditclone/pkg/openapi/client"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/router"
+	"redditclone/pkg/session"
+	"redditclone/pkg/tracing"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func testRouter(ctrl *gomock.Controller) (*mux.Router, *posts.MockPostsRepository) {
+	var key key.Key = "author"
+	log := logger.NopLogger{}
+	postsRepo := posts.NewMockPostsRepository(ctrl)
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	return newRouter(routes{
+		Key:            key,
+		Logger:         log,
+		LogLevel:       http.NotFoundHandler(),
+		Sessions:       sess,
+		Users:          users,
+		PostsRepo:      postsRepo,
+		Metrics:        metrics.NewMetrics(),
+		Tracing:        tracing.NewTracing(noop.NewTracerProvider()),
+		UserHandler:    &handlers.UserHandler{Logger: log, UserRepo: users, Session: sess, PostsRepo: postsRepo, ContextKey: key},
+		PostsHandler:   &handlers.PostsHandler{Logger: log, PostsRepo: postsRepo, Session: sess, ContextKey: key},
+		AccountHandler: &handlers.AccountHandler{Logger: log, ContextKey: key},
+		ReportsHandler: &handlers.ReportsHandler{Logger: log, PostsRepo: postsRepo, ContextKey: key},
+	}), postsRepo
+}
+
+type spec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+func routerOperations(t *testing.T, r *mux.Router) []string {
+	ops := make([]string, 0)
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/api/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("route %s accepts any method", path)
+			return nil
+		}
+		for _, method := range methods {
+			ops = append(ops, method+" "+path)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	sort.Strings(ops)
+	return ops
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r, _ := testRouter(ctrl)
+
+	doc := spec{}
+	assert.Nil(t, json.Unmarshal(openapi.Spec, &doc))
+	documented := make([]string, 0)
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(documented)
+
+	assert.Equal(t, documented, routerOperations(t, r))
+}
+
+func TestSpecRefsResolve(t *testing.T) {
+	doc := spec{}
+	assert.Nil(t, json.Unmarshal(openapi.Spec, &doc))
+	for _, part := range strings.Split(string(openapi.Spec), `"$ref": "#/components/schemas/`)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("unresolved schema %s", name)
+		}
+	}
+}
+
+func TestClientAgainstRouter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r, postsRepo := testRouter(ctrl)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	api, err := client.NewClientWithResponses(server.URL)
+	assert.Nil(t, err)
+
+	postsRepo.EXPECT().GetAllPosts(gomock.Any()).Return([]posts.Post{{ID: "1", Title: "title", Type: "text"}}, nil)
+	list, err := api.ListPostsWithResponse(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 200, list.StatusCode())
+	if assert.NotNil(t, list.JSON200) {
+		assert.Equal(t, "title", (*list.JSON200)[0].Title)
+	}
+
+	created, err := api.CreatePostWithResponse(context.Background(), client.CreatePostJSONRequestBody{Title: "title", Type: "text", Category: "music"})
+	assert.Nil(t, err)
+	assert.Equal(t, 400, created.StatusCode())
+	if assert.NotNil(t, created.JSON400) {
+		assert.Equal(t, "token not found", created.JSON400.Message)
+	}
+}
+
+func TestMutatingRoutesProtected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var key key.Key = "author"
+	rt := routes{
+		Key:            key,
+		Logger:         logger.NopLogger{},
+		LogLevel:       http.NotFoundHandler(),
+		UserHandler:    &handlers.UserHandler{},
+		PostsHandler:   &handlers.PostsHandler{},
+		AccountHandler: &handlers.AccountHandler{},
+		ReportsHandler: &handlers.ReportsHandler{},
+	}
+	// votes change state over GET, so the method alone does not tell
+	mutatingGets := map[string]bool{
+		"/api/post/{POST_ID}/upvote":   true,
+		"/api/post/{POST_ID}/downvote": true,
+		"/api/post/{POST_ID}/unvote":   true,
+	}
+	// the account recovery routes and the second login step are authorized by
+	// the one-time token in the body
+	publicWrites := map[string]bool{
+		"/api/login":                  true,
+		"/api/login/2fa":              true,
+		"/api/register":               true,
+		"/api/user/verify":            true,
+		"/api/password/reset":         true,
+		"/api/password/reset/confirm": true,
+	}
+
+	seen := make(map[string]bool)
+	for _, route := range apiRoutes(rt).Routes() {
+		assert.NotEmpty(t, route.Methods, route.Path)
+		for _, method := range route.Methods {
+			mutating := method != http.MethodGet || mutatingGets[route.Path]
+			if mutating && route.Access == router.Public && !publicWrites[route.Path] {
+				t.Errorf("%s %s is mutating but public", method, route.Path)
+			}
+		}
+		seen[route.Path] = true
+		if strings.HasPrefix(route.Path, "/api/moderation/") && route.Access < router.Moderator {
+			t.Errorf("%s is %s, want moderator", route.Path, route.Access)
+		}
+		if strings.HasPrefix(route.Path, "/api/admin/") && route.Access != router.Admin {
+			t.Errorf("%s is %s, want admin", route.Path, route.Access)
+		}
+	}
+	for path := range mutatingGets {
+		if !seen[path] {
+			t.Errorf("%s is listed as mutating but not routed", path)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"redditclone/pkg/comments"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+)
+
+type Profile struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Login    string `json:"login"`
+	Role     string `json:"role"`
+	Email    string `json:"email,omitempty"`
+	Verified bool   `json:"verified"`
+}
+
+type CommentRecord struct {
+	PostID  string           `json:"postId"`
+	Comment comments.Comment `json:"comment"`
+}
+
+// VoteRecord is a vote on a post, or on one of its comments when CommentID
+// is set.
+type VoteRecord struct {
+	PostID    string `json:"postId"`
+	CommentID string `json:"commentId,omitempty"`
+	Vote      int    `json:"vote"`
+}
+
+type Export struct {
+	Generated time.Time           `json:"generated"`
+	User      Profile             `json:"user"`
+	Sessions  []session.SessionDB `json:"sessions"`
+	Posts     []posts.Post        `json:"posts"`
+	Comments  []CommentRecord     `json:"comments"`
+	Votes     []VoteRecord        `json:"votes"`
+}
+
+//go:generate mockgen -source account.go -destination account_mock.go -package account Accounts
+type Accounts interface {
+	Export(ctx context.Context, userID string) (Export, error)
+	ScheduleDeletion(ctx context.Context, userID string) (Job, error)
+}
+
+func collect(profile Profile, sessions []session.SessionDB, activity []posts.Post, commentVotes []posts.CommentVote) Export {
+	export := Export{
+		Generated: time.Now(),
+		User:      profile,
+		Sessions:  sessions,
+		Posts:     make([]posts.Post, 0),
+		Comments:  make([]CommentRecord, 0),
+		Votes:     make([]VoteRecord, 0),
+	}
+	for _, post := range activity {
+		if post.Author.ID == profile.ID {
+			export.Posts = append(export.Posts, post)
+		}
+		for _, comment := range post.Comments {
+			if comment.Author.ID == profile.ID {
+				export.Comments = append(export.Comments, CommentRecord{PostID: post.ID, Comment: comment})
+			}
+		}
+		for _, vote := range post.Votes {
+			if vote.User == profile.ID {
+				export.Votes = append(export.Votes, VoteRecord{PostID: post.ID, Vote: vote.Vote})
+			}
+		}
+	}
+	for _, vote := range commentVotes {
+		export.Votes = append(export.Votes, VoteRecord{PostID: vote.PostID, CommentID: vote.CommentID, Vote: vote.Vote})
+	}
+	return export
+}
+
+// WriteZip stores every part of the export as its own JSON file.
+func WriteZip(w io.Writer, export Export) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"sessions.json", export.Sessions},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"votes.json", export.Votes},
+	}
+	for _, file := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.Generated,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func profileOf(u user.User) Profile {
+	return Profile{
+		ID:       u.ID,
+		Username: u.Username,
+		Login:    u.Login,
+		Role:     u.Role,
+		Email:    u.Email,
+		Verified: u.Verified,
+	}
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: account.go
+
+// Package account is a generated GoMock package.
+package account
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAccounts is a mock of Accounts interface.
+type MockAccounts struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountsMockRecorder
+}
+
+// MockAccountsMockRecorder is the mock recorder for MockAccounts.
+type MockAccountsMockRecorder struct {
+	mock *MockAccounts
+}
+
+// NewMockAccounts creates a new mock instance.
+func NewMockAccounts(ctrl *gomock.Controller) *MockAccounts {
+	mock := &MockAccounts{ctrl: ctrl}
+	mock.recorder = &MockAccountsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccounts) EXPECT() *MockAccountsMockRecorder {
+	return m.recorder
+}
+
+// Export mocks base method.
+func (m *MockAccounts) Export(ctx context.Context, userID string) (Export, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, userID)
+	ret0, _ := ret[0].(Export)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockAccountsMockRecorder) Export(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockAccounts)(nil).Export), ctx, userID)
+}
+
+// ScheduleDeletion mocks base method.
+func (m *MockAccounts) ScheduleDeletion(ctx context.Context, userID string) (Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleDeletion", ctx, userID)
+	ret0, _ := ret[0].(Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleDeletion indicates an expected call of ScheduleDeletion.
+func (mr *MockAccountsMockRecorder) ScheduleDeletion(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleDeletion", reflect.TypeOf((*MockAccounts)(nil).ScheduleDeletion), ctx, userID)
+}
@@ -0,0 +1,44 @@
+package account
+
+import (
+	"context"
+	"time"
+)
+
+// Deletion steps in the order they run. Sessions go first so the account
+// can't be used while the rest is being removed.
+const (
+	StepSessions = "sessions"
+	StepVotes    = "votes"
+	StepComments = "comments"
+	StepPosts    = "posts"
+	StepUser     = "user"
+	StepDone     = "done"
+)
+
+var steps = []string{StepSessions, StepVotes, StepComments, StepPosts, StepUser, StepDone}
+
+type Job struct {
+	UserID  string    `json:"userId"`
+	Step    string    `json:"step"`
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+//go:generate mockgen -source job.go -destination job_mock.go -package account JobRepo
+type JobRepo interface {
+	// Create returns the existing job when the user already has one.
+	Create(ctx context.Context, userID string) (Job, error)
+	Pending(ctx context.Context) ([]Job, error)
+	SetStep(ctx context.Context, userID string, step string, errMsg string) error
+}
+
+func nextStep(step string) string {
+	for i, s := range steps {
+		if s == step && i+1 < len(steps) {
+			return steps[i+1]
+		}
+	}
+	return StepDone
+}
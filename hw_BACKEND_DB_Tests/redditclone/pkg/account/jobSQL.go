@@ -0,0 +1,85 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type JobSQLRepo struct {
+	DB *sql.DB
+}
+
+func NewJobSQLRepo(db *sql.DB) *JobSQLRepo {
+	return &JobSQLRepo{
+		DB: db,
+	}
+}
+
+func (j *JobSQLRepo) Create(ctx context.Context, userID string) (Job, error) {
+	now := time.Now()
+	_, err := j.DB.ExecContext(ctx,
+		"INSERT IGNORE INTO account_deletions (`userid`, `step`, `error`, `created`, `updated`) VALUES (?, ?, '', ?, ?)",
+		userID,
+		StepSessions,
+		now.Unix(),
+		now.Unix(),
+	)
+	if err != nil {
+		return Job{}, fmt.Errorf("error in job create %w", err)
+	}
+	row := j.DB.QueryRowContext(ctx, "SELECT userid, step, error, created, updated FROM account_deletions WHERE userid = ?",
+		userID,
+	)
+	return scanJob(row)
+}
+
+func (j *JobSQLRepo) Pending(ctx context.Context) ([]Job, error) {
+	jobs := make([]Job, 0)
+	rows, err := j.DB.QueryContext(ctx, "SELECT userid, step, error, created, updated FROM account_deletions WHERE step <> ?",
+		StepDone,
+	)
+	if err != nil {
+		return jobs, fmt.Errorf("error in job pending %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (j *JobSQLRepo) SetStep(ctx context.Context, userID string, step string, errMsg string) error {
+	_, err := j.DB.ExecContext(ctx,
+		"UPDATE account_deletions SET step = ?, error = ?, updated = ? WHERE userid = ?",
+		step,
+		errMsg,
+		time.Now().Unix(),
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("error in job setstep %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row scanner) (Job, error) {
+	job := Job{}
+	var created, updated int64
+	err := row.Scan(&job.UserID, &job.Step, &job.Error, &created, &updated)
+	if err != nil {
+		return Job{}, fmt.Errorf("error in job scan %w", err)
+	}
+	job.Created = time.Unix(created, 0)
+	job.Updated = time.Unix(updated, 0)
+	return job, nil
+}
@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: job.go
+
+// Package account is a generated GoMock package.
+package account
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockJobRepo is a mock of JobRepo interface.
+type MockJobRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobRepoMockRecorder
+}
+
+// MockJobRepoMockRecorder is the mock recorder for MockJobRepo.
+type MockJobRepoMockRecorder struct {
+	mock *MockJobRepo
+}
+
+// NewMockJobRepo creates a new mock instance.
+func NewMockJobRepo(ctrl *gomock.Controller) *MockJobRepo {
+	mock := &MockJobRepo{ctrl: ctrl}
+	mock.recorder = &MockJobRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobRepo) EXPECT() *MockJobRepoMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockJobRepo) Create(ctx context.Context, userID string) (Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, userID)
+	ret0, _ := ret[0].(Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockJobRepoMockRecorder) Create(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockJobRepo)(nil).Create), ctx, userID)
+}
+
+// Pending mocks base method.
+func (m *MockJobRepo) Pending(ctx context.Context) ([]Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pending", ctx)
+	ret0, _ := ret[0].([]Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pending indicates an expected call of Pending.
+func (mr *MockJobRepoMockRecorder) Pending(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pending", reflect.TypeOf((*MockJobRepo)(nil).Pending), ctx)
+}
+
+// SetStep mocks base method.
+func (m *MockJobRepo) SetStep(ctx context.Context, userID, step, errMsg string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStep", ctx, userID, step, errMsg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStep indicates an expected call of SetStep.
+func (mr *MockJobRepoMockRecorder) SetStep(ctx, userID, step, errMsg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStep", reflect.TypeOf((*MockJobRepo)(nil).SetStep), ctx, userID, step, errMsg)
+}
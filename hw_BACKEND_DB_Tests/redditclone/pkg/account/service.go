@@ -0,0 +1,214 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Service exports accounts and runs deletion jobs. Every step can be
+// repeated safely, so a job interrupted by a crash resumes at its last
+// recorded step.
+type Service struct {
+	Users    user.UserRepo
+	Sessions session.SessionManager
+	Posts    posts.PostsRepository
+	Jobs     JobRepo
+	Logger   logger.Logger
+	queue    chan string
+}
+
+func NewService(users user.UserRepo, sessions session.SessionManager, postsRepo posts.PostsRepository, jobs JobRepo, logger logger.Logger, queueSize int) *Service {
+	return &Service{
+		Users:    users,
+		Sessions: sessions,
+		Posts:    postsRepo,
+		Jobs:     jobs,
+		Logger:   logger,
+		queue:    make(chan string, queueSize),
+	}
+}
+
+func (s *Service) Export(ctx context.Context, userID string) (Export, error) {
+	found, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	sessions, err := s.Sessions.GetSessions(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	activity, err := s.Posts.GetUserActivity(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	commentVotes, err := s.Posts.GetUserCommentVotes(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	return collect(profileOf(found), sessions, activity, commentVotes), nil
+}
+
+func (s *Service) ScheduleDeletion(ctx context.Context, userID string) (Job, error) {
+	job, err := s.Jobs.Create(ctx, userID)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Step != StepDone {
+		s.enqueue(userID)
+	}
+	return job, nil
+}
+
+func (s *Service) enqueue(userID string) {
+	select {
+	case s.queue <- userID:
+	default:
+		s.Logger.Log("Warn", "account deletion queue is full, job for user "+userID+" waits for resume")
+	}
+}
+
+// Resume queues jobs left unfinished by a previous run.
+func (s *Service) Resume(ctx context.Context) error {
+	jobs, err := s.Jobs.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		s.enqueue(job.UserID)
+	}
+	return nil
+}
+
+func (s *Service) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case userID := <-s.queue:
+			job, err := s.Jobs.Create(ctx, userID)
+			if err == nil {
+				err = s.RunJob(ctx, job)
+			}
+			if err != nil {
+				s.Logger.Log("Error", err.Error())
+			}
+		}
+	}
+}
+
+func (s *Service) RunJob(ctx context.Context, job Job) error {
+	for job.Step != StepDone {
+		if err := s.runStep(ctx, job.UserID, job.Step); err != nil {
+			err = fmt.Errorf("account deletion of %s failed at %s: %w", job.UserID, job.Step, err)
+			if setErr := s.Jobs.SetStep(ctx, job.UserID, job.Step, err.Error()); setErr != nil {
+				s.Logger.Log("Error", setErr.Error())
+			}
+			return err
+		}
+		next := nextStep(job.Step)
+		if err := s.Jobs.SetStep(ctx, job.UserID, next, ""); err != nil {
+			return err
+		}
+		job.Step = next
+	}
+	return nil
+}
+
+func (s *Service) runStep(ctx context.Context, userID string, step string) error {
+	switch step {
+	case StepSessions:
+		return s.Sessions.DeleteUserSessions(ctx, userID)
+	case StepVotes:
+		err := s.eachPost(ctx, userID, func(post posts.Post) error {
+			if post.Author.ID == userID || !votedBy(post, userID) {
+				return nil
+			}
+			_, err := s.Posts.UnVote(ctx, userID, post.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return s.unVoteComments(ctx, userID)
+	case StepComments:
+		return s.eachPost(ctx, userID, func(post posts.Post) error {
+			if post.Author.ID == userID || !commentedBy(post, userID) {
+				return nil
+			}
+			return s.Posts.AnonymizeComments(ctx, post.ID, userID)
+		})
+	case StepPosts:
+		return s.eachPost(ctx, userID, func(post posts.Post) error {
+			if post.Author.ID != userID {
+				return nil
+			}
+			err := s.Posts.DeletePost(ctx, post.ID)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil
+			}
+			return err
+		})
+	case StepUser:
+		return s.Users.DeleteUser(ctx, userID)
+	}
+	return fmt.Errorf("unknown step %q", step)
+}
+
+func (s *Service) eachPost(ctx context.Context, userID string, fn func(post posts.Post) error) error {
+	activity, err := s.Posts.GetUserActivity(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, post := range activity {
+		if err = fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unVoteComments clears the user's comment votes one post at a time, so
+// the scores are summed again and cached posts are dropped.
+func (s *Service) unVoteComments(ctx context.Context, userID string) error {
+	votes, err := s.Posts.GetUserCommentVotes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	done := make(map[string]bool)
+	for _, vote := range votes {
+		if done[vote.PostID] {
+			continue
+		}
+		if err = s.Posts.UnVoteComments(ctx, vote.PostID, userID); err != nil {
+			return err
+		}
+		done[vote.PostID] = true
+	}
+	return nil
+}
+
+func votedBy(post posts.Post, userID string) bool {
+	for _, vote := range post.Votes {
+		if vote.User == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func commentedBy(post posts.Post, userID string) bool {
+	for _, comment := range post.Comments {
+		if comment.Author.ID == userID {
+			return true
+		}
+	}
+	return false
+}
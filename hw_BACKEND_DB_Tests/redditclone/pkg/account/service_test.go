@@ -0,0 +1,139 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/comments"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+	"redditclone/pkg/vote"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type mocks struct {
+	users    *user.MockUserRepo
+	sessions *session.MockSessionManager
+	posts    *posts.MockPostsRepository
+	jobs     *MockJobRepo
+}
+
+func newTestService(ctrl *gomock.Controller) (*Service, mocks) {
+	m := mocks{
+		users:    user.NewMockUserRepo(ctrl),
+		sessions: session.NewMockSessionManager(ctrl),
+		posts:    posts.NewMockPostsRepository(ctrl),
+		jobs:     NewMockJobRepo(ctrl),
+	}
+	return NewService(m.users, m.sessions, m.posts, m.jobs, logger.NopLogger{}, 1), m
+}
+
+func activity() []posts.Post {
+	return []posts.Post{
+		{ID: "own", Author: author.Author{ID: "1"}, Votes: []vote.Vote{{User: "1", Vote: 1}}},
+		{
+			ID:       "other",
+			Author:   author.Author{ID: "2"},
+			Votes:    []vote.Vote{{User: "2", Vote: 1}, {User: "1", Vote: -1}},
+			Comments: []comments.Comment{{ID: "c1", Author: author.Author{ID: "1"}}, {ID: "c2", Author: author.Author{ID: "2"}}},
+		},
+	}
+}
+
+func commentVotes() []posts.CommentVote {
+	return []posts.CommentVote{
+		{PostID: "other", CommentID: "c2", Vote: 1},
+		{PostID: "third", CommentID: "c3", Vote: -1},
+		{PostID: "third", CommentID: "c4", Vote: 1},
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, m := newTestService(ctrl)
+	ctx := context.Background()
+
+	m.users.EXPECT().GetUser(ctx, "1").Return(user.User{ID: "1", Username: "abc", Password: "secret"}, nil)
+	m.sessions.EXPECT().GetSessions(ctx, "1").Return([]session.SessionDB{{ID: 1, UserID: "1"}}, nil)
+	m.posts.EXPECT().GetUserActivity(ctx, "1").Return(activity(), nil)
+	m.posts.EXPECT().GetUserCommentVotes(ctx, "1").Return(commentVotes(), nil)
+	export, err := service.Export(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, Profile{ID: "1", Username: "abc"}, export.User)
+	assert.Equal(t, 1, len(export.Sessions))
+	assert.Equal(t, 1, len(export.Posts))
+	assert.Equal(t, []CommentRecord{{PostID: "other", Comment: activity()[1].Comments[0]}}, export.Comments)
+	assert.Equal(t, []VoteRecord{
+		{PostID: "own", Vote: 1},
+		{PostID: "other", Vote: -1},
+		{PostID: "other", CommentID: "c2", Vote: 1},
+		{PostID: "third", CommentID: "c3", Vote: -1},
+		{PostID: "third", CommentID: "c4", Vote: 1},
+	}, export.Votes)
+
+	m.users.EXPECT().GetUser(ctx, "1").Return(user.User{}, fmt.Errorf("no rows"))
+	_, err = service.Export(ctx, "1")
+	assert.NotNil(t, err)
+}
+
+func TestRunJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, m := newTestService(ctrl)
+	ctx := context.Background()
+
+	gomock.InOrder(
+		m.sessions.EXPECT().DeleteUserSessions(ctx, "1").Return(nil),
+		m.jobs.EXPECT().SetStep(ctx, "1", StepVotes, "").Return(nil),
+		m.posts.EXPECT().GetUserActivity(ctx, "1").Return(activity(), nil),
+		m.posts.EXPECT().UnVote(ctx, "1", "other").Return(posts.Post{}, nil),
+		m.posts.EXPECT().GetUserCommentVotes(ctx, "1").Return(commentVotes(), nil),
+		m.posts.EXPECT().UnVoteComments(ctx, "other", "1").Return(nil),
+		m.posts.EXPECT().UnVoteComments(ctx, "third", "1").Return(nil),
+		m.jobs.EXPECT().SetStep(ctx, "1", StepComments, "").Return(nil),
+		m.posts.EXPECT().GetUserActivity(ctx, "1").Return(activity(), nil),
+		m.posts.EXPECT().AnonymizeComments(ctx, "other", "1").Return(fmt.Errorf("mongo down")),
+		m.jobs.EXPECT().SetStep(ctx, "1", StepComments, gomock.Any()).Return(nil),
+	)
+	err := service.RunJob(ctx, Job{UserID: "1", Step: StepSessions})
+	assert.NotNil(t, err)
+
+	// resumed from the failed step
+	gomock.InOrder(
+		m.posts.EXPECT().GetUserActivity(ctx, "1").Return(activity(), nil),
+		m.posts.EXPECT().AnonymizeComments(ctx, "other", "1").Return(nil),
+		m.jobs.EXPECT().SetStep(ctx, "1", StepPosts, "").Return(nil),
+		m.posts.EXPECT().GetUserActivity(ctx, "1").Return(activity(), nil),
+		m.posts.EXPECT().DeletePost(ctx, "own").Return(nil),
+		m.jobs.EXPECT().SetStep(ctx, "1", StepUser, "").Return(nil),
+		m.users.EXPECT().DeleteUser(ctx, "1").Return(nil),
+		m.jobs.EXPECT().SetStep(ctx, "1", StepDone, "").Return(nil),
+	)
+	err = service.RunJob(ctx, Job{UserID: "1", Step: StepComments})
+	assert.Nil(t, err)
+}
+
+func TestScheduleDeletion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, m := newTestService(ctrl)
+	ctx := context.Background()
+
+	m.jobs.EXPECT().Create(ctx, "1").Return(Job{UserID: "1", Step: StepSessions}, nil)
+	job, err := service.ScheduleDeletion(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, StepSessions, job.Step)
+	assert.Equal(t, "1", <-service.queue)
+
+	m.jobs.EXPECT().Create(ctx, "2").Return(Job{UserID: "2", Step: StepDone}, nil)
+	_, err = service.ScheduleDeletion(ctx, "2")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(service.queue))
+}
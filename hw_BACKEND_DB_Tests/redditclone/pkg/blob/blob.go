@@ -0,0 +1,26 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("blob not found")
+	ErrBadKey   = errors.New("bad blob key")
+)
+
+//go:generate mockgen -source blob.go -destination blob_mock.go -package blob Storage
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey keeps keys flat so they can't escape the local directory or the
+// bucket prefix.
+func validKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, "/\\") && key != "." && key != ".."
+}
@@ -0,0 +1,79 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type Local struct {
+	Dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{Dir: dir}, nil
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Dir, key)
+}
+
+// Put writes to a temporary file first so readers never see a partial blob.
+// The content type goes to a sidecar file next to the data.
+func (l *Local) Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) error {
+	if !validKey(key) {
+		return ErrBadKey
+	}
+	tmp, err := os.CreateTemp(l.Dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.WriteFile(l.path(key)+".type", []byte(contentType), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path(key))
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	if !validKey(key) {
+		return nil, "", ErrBadKey
+	}
+	file, err := os.Open(l.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	contentType, err := os.ReadFile(l.path(key) + ".type")
+	if err != nil {
+		contentType = []byte("application/octet-stream")
+	}
+	return file, string(contentType), nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrBadKey
+	}
+	err := os.Remove(l.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	os.Remove(l.path(key) + ".type")
+	return nil
+}
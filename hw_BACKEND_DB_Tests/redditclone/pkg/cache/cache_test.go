@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"redditclone/pkg/comments"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+	"redditclone/pkg/vote"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client, "test:"), server
+}
+
+func TestBackends(t *testing.T) {
+	redisCache, server := newRedis(t)
+	lru := NewLRU(10)
+	now := time.Now()
+	lru.now = func() time.Time { return now }
+
+	for name, backend := range map[string]Cache{"lru": lru, "redis": redisCache} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, ok, err := backend.Get(ctx, "a")
+			assert.Nil(t, err)
+			assert.False(t, ok)
+
+			assert.Nil(t, backend.Set(ctx, "a", []byte("1"), 0))
+			assert.Nil(t, backend.Set(ctx, "b", []byte("2"), time.Minute))
+			value, ok, err := backend.Get(ctx, "a")
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte("1"), value)
+
+			assert.Nil(t, backend.Delete(ctx, "a", "missing"))
+			_, ok, _ = backend.Get(ctx, "a")
+			assert.False(t, ok)
+
+			now = now.Add(2 * time.Minute)
+			server.FastForward(2 * time.Minute)
+			_, ok, _ = backend.Get(ctx, "b")
+			assert.False(t, ok)
+		})
+	}
+	assert.False(t, server.Exists("test:b"))
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(2)
+	_ = lru.Set(ctx, "a", []byte("1"), 0)
+	_ = lru.Set(ctx, "b", []byte("2"), 0)
+	_, _, _ = lru.Get(ctx, "a")
+	_ = lru.Set(ctx, "c", []byte("3"), 0)
+
+	assert.Equal(t, 2, lru.Len())
+	_, ok, _ := lru.Get(ctx, "b")
+	assert.False(t, ok, "least recently used key must be evicted")
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.True(t, ok)
+}
+
+func TestRedisError(t *testing.T) {
+	redisCache, server := newRedis(t)
+	server.Close()
+	_, ok, err := redisCache.Get(context.Background(), "a")
+	assert.False(t, ok)
+	assert.NotNil(t, err)
+}
+
+func TestPostsRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mock := posts.NewMockPostsRepository(ctrl)
+	repo := NewPostsRepository(mock, NewLRU(10), time.Minute)
+	post := posts.Post{ID: "1", Title: "title", Upvotes: 1, Comments: []comments.Comment{}}
+
+	// second read is served from cache
+	mock.EXPECT().GetPostByID(ctx, "1").Return(post, nil).Times(1)
+	res, err := repo.GetPostByID(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, post, res)
+	res, err = repo.GetPostByID(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, post, res)
+
+	// update drops the entry, the stored post may have newer votes than
+	// the one the caller wrote
+	post.Views = 5
+	stored := post
+	stored.Score = 3
+	mock.EXPECT().UpdatePost(ctx, post).Return(nil)
+	assert.Nil(t, repo.UpdatePost(ctx, post))
+	mock.EXPECT().GetPostByID(ctx, "1").Return(stored, nil)
+	res, _ = repo.GetPostByID(ctx, "1")
+	assert.Equal(t, 5, res.Views)
+	assert.Equal(t, 3, res.Score)
+
+	// delete drops the entry even when it fails
+	mock.EXPECT().SetHidden(ctx, "1", "", true).Return(fmt.Errorf("db"))
+	assert.NotNil(t, repo.SetHidden(ctx, "1", "", true))
+	mock.EXPECT().GetPostByID(ctx, "1").Return(posts.Post{}, fmt.Errorf("db"))
+	_, err = repo.GetPostByID(ctx, "1")
+	assert.NotNil(t, err)
+
+	// vote refreshes the entry with the returned post
+	voted := post
+	voted.Score = 2
+	mock.EXPECT().Vote(ctx, "1", vote.Vote{User: "2", Vote: 1}).Return(voted, nil)
+	_, err = repo.Vote(ctx, "1", vote.Vote{User: "2", Vote: 1})
+	assert.Nil(t, err)
+	res, err = repo.GetPostByID(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Score)
+
+	mock.EXPECT().DeletePost(ctx, "1").Return(nil)
+	assert.Nil(t, repo.DeletePost(ctx, "1"))
+	mock.EXPECT().GetPostByID(ctx, "1").Return(posts.Post{}, fmt.Errorf("not found"))
+	_, err = repo.GetPostByID(ctx, "1")
+	assert.NotNil(t, err)
+}
+
+func TestUserRepo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mock := user.NewMockUserRepo(ctrl)
+	redisCache, _ := newRedis(t)
+	repo := NewUserRepo(mock, redisCache, time.Minute)
+
+	// negative answers are not cached
+	mock.EXPECT().IsUser(ctx, "abc", "1").Return(false, nil)
+	mock.EXPECT().IsUser(ctx, "abc", "1").Return(true, nil).Times(1)
+	ok, err := repo.IsUser(ctx, "abc", "1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	for i := 0; i < 2; i++ {
+		ok, err = repo.IsUser(ctx, "abc", "1")
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+	mock.EXPECT().GetRole(ctx, "1").Return(user.RoleModerator, nil).Times(1)
+	for i := 0; i < 2; i++ {
+		role, err := repo.GetRole(ctx, "1")
+		assert.Nil(t, err)
+		assert.Equal(t, user.RoleModerator, role)
+	}
+}
+
+func TestSessionManager(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mock := session.NewMockSessionManager(ctrl)
+	sess := NewSessionManager(mock, NewLRU(10), time.Minute)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	// AddNewSess writes through, GetExp does not hit the database
+	mock.EXPECT().AddNewSess(ctx, "1", exp, int64(100)).Return(nil)
+	assert.Nil(t, sess.AddNewSess(ctx, "1", exp, 100))
+	assert.Equal(t, exp, sess.GetExp(ctx, "1", 100))
+
+	// DeleteSess invalidates even on error
+	mock.EXPECT().DeleteSess(ctx, "1", int64(100)).Return(fmt.Errorf("db"))
+	assert.NotNil(t, sess.DeleteSess(ctx, "1", 100))
+	mock.EXPECT().GetExp(ctx, "1", int64(100)).Return(int64(0)).Times(2)
+	assert.Equal(t, int64(0), sess.GetExp(ctx, "1", 100))
+	assert.Equal(t, int64(0), sess.GetExp(ctx, "1", 100))
+
+	// expired sessions are never cached
+	mock.EXPECT().GetExp(ctx, "2", int64(100)).Return(int64(1)).Times(2)
+	assert.Equal(t, int64(1), sess.GetExp(ctx, "2", 100))
+	assert.Equal(t, int64(1), sess.GetExp(ctx, "2", 100))
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/comments"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/preview"
+	"redditclone/pkg/vote"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PostsRepository caches single posts by id. Writes that return the new
+// post refresh the entry, the rest drop it. UpdatePost drops it too: the
+// caller's copy may carry votes older than what is stored.
+type PostsRepository struct {
+	Repo  posts.PostsRepository
+	Cache Cache
+	TTL   time.Duration
+}
+
+func NewPostsRepository(repo posts.PostsRepository, cache Cache, ttl time.Duration) *PostsRepository {
+	return &PostsRepository{
+		Repo:  repo,
+		Cache: cache,
+		TTL:   ttl,
+	}
+}
+
+func postKey(id string) string {
+	return "post:" + id
+}
+
+func (p *PostsRepository) store(ctx context.Context, post posts.Post) {
+	data, err := bson.Marshal(post)
+	if err != nil {
+		p.invalidate(ctx, post.ID)
+		return
+	}
+	if err = p.Cache.Set(ctx, postKey(post.ID), data, p.TTL); err != nil {
+		p.invalidate(ctx, post.ID)
+	}
+}
+
+func (p *PostsRepository) invalidate(ctx context.Context, id string) {
+	_ = p.Cache.Delete(ctx, postKey(id))
+}
+
+func (p *PostsRepository) GetPostByID(ctx context.Context, id string) (posts.Post, error) {
+	data, ok, err := p.Cache.Get(ctx, postKey(id))
+	if err == nil && ok {
+		post := posts.Post{}
+		if bson.Unmarshal(data, &post) == nil {
+			return post, nil
+		}
+	}
+	post, err := p.Repo.GetPostByID(ctx, id)
+	if err != nil {
+		return post, err
+	}
+	p.store(ctx, post)
+	return post, nil
+}
+
+func (p *PostsRepository) GetAllPosts(ctx context.Context) ([]posts.Post, error) {
+	return p.Repo.GetAllPosts(ctx)
+}
+
+func (p *PostsRepository) GetCategory(ctx context.Context, category string) ([]posts.Post, error) {
+	return p.Repo.GetCategory(ctx, category)
+}
+
+func (p *PostsRepository) GetByUserLogin(ctx context.Context, login string) ([]posts.Post, error) {
+	return p.Repo.GetByUserLogin(ctx, login)
+}
+
+func (p *PostsRepository) GetUserActivity(ctx context.Context, userID string) ([]posts.Post, error) {
+	return p.Repo.GetUserActivity(ctx, userID)
+}
+
+func (p *PostsRepository) AddPost(ctx context.Context, post posts.Post) (posts.Post, error) {
+	post, err := p.Repo.AddPost(ctx, post)
+	if err != nil {
+		return post, err
+	}
+	p.store(ctx, post)
+	return post, nil
+}
+
+func (p *PostsRepository) UpdatePost(ctx context.Context, post posts.Post) error {
+	defer p.invalidate(ctx, post.ID)
+	return p.Repo.UpdatePost(ctx, post)
+}
+
+func (p *PostsRepository) AddComment(ctx context.Context, postID string, comment comments.Comment) (posts.Post, error) {
+	return p.refresh(ctx, postID)(p.Repo.AddComment(ctx, postID, comment))
+}
+
+func (p *PostsRepository) DeleteComment(ctx context.Context, postID string, commentID string) (posts.Post, error) {
+	return p.refresh(ctx, postID)(p.Repo.DeleteComment(ctx, postID, commentID))
+}
+
+func (p *PostsRepository) Vote(ctx context.Context, postID string, vote vote.Vote) (posts.Post, error) {
+	return p.refresh(ctx, postID)(p.Repo.Vote(ctx, postID, vote))
+}
+
+func (p *PostsRepository) UnVote(ctx context.Context, username string, postID string) (posts.Post, error) {
+	return p.refresh(ctx, postID)(p.Repo.UnVote(ctx, username, postID))
+}
+
+func (p *PostsRepository) DeletePost(ctx context.Context, postID string) error {
+	defer p.invalidate(ctx, postID)
+	return p.Repo.DeletePost(ctx, postID)
+}
+
+func (p *PostsRepository) SetHidden(ctx context.Context, postID string, commentID string, hidden bool) error {
+	defer p.invalidate(ctx, postID)
+	return p.Repo.SetHidden(ctx, postID, commentID, hidden)
+}
+
+func (p *PostsRepository) SetPreview(ctx context.Context, postID string, preview preview.Preview) error {
+	defer p.invalidate(ctx, postID)
+	return p.Repo.SetPreview(ctx, postID, preview)
+}
+
+func (p *PostsRepository) AnonymizeComments(ctx context.Context, postID string, userID string) error {
+	defer p.invalidate(ctx, postID)
+	return p.Repo.AnonymizeComments(ctx, postID, userID)
+}
+
+func (p *PostsRepository) GetComments(ctx context.Context, postID string, page comments.Page) (comments.CommentsPage, error) {
+	return p.Repo.GetComments(ctx, postID, page)
+}
+
+func (p *PostsRepository) GetComment(ctx context.Context, postID string, commentID string) (comments.Comment, error) {
+	return p.Repo.GetComment(ctx, postID, commentID)
+}
+
+func (p *PostsRepository) VoteComment(ctx context.Context, postID string, commentID string, vote vote.Vote) (comments.Comment, error) {
+	defer p.invalidate(ctx, postID)
+	return p.Repo.VoteComment(ctx, postID, commentID, vote)
+}
+
+func (p *PostsRepository) GetUserCommentVotes(ctx context.Context, userID string) ([]posts.CommentVote, error) {
+	return p.Repo.GetUserCommentVotes(ctx, userID)
+}
+
+func (p *PostsRepository) UnVoteComments(ctx context.Context, postID string, userID string) error {
+	defer p.invalidate(ctx, postID)
+	return p.Repo.UnVoteComments(ctx, postID, userID)
+}
+
+func (p *PostsRepository) refresh(ctx context.Context, postID string) func(posts.Post, error) (posts.Post, error) {
+	return func(post posts.Post, err error) (posts.Post, error) {
+		if err != nil {
+			p.invalidate(ctx, postID)
+			return post, err
+		}
+		p.store(ctx, post)
+		return post, nil
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"redditclone/pkg/session"
+)
+
+type SessionManager struct {
+	Sessions session.SessionManager
+	Cache    Cache
+	TTL      time.Duration
+}
+
+func NewSessionManager(sessions session.SessionManager, cache Cache, ttl time.Duration) *SessionManager {
+	return &SessionManager{
+		Sessions: sessions,
+		Cache:    cache,
+		TTL:      ttl,
+	}
+}
+
+func sessionKey(id string, iat int64) string {
+	return "session:" + id + ":" + strconv.FormatInt(iat, 10)
+}
+
+func (s *SessionManager) GetKey() interface{} {
+	return s.Sessions.GetKey()
+}
+
+func (s *SessionManager) SetKey(key interface{}) {
+	s.Sessions.SetKey(key)
+}
+
+func (s *SessionManager) store(ctx context.Context, id string, iat int64, exp int64) {
+	ttl := time.Until(time.Unix(exp, 0))
+	if ttl <= 0 {
+		return
+	}
+	if s.TTL > 0 && ttl > s.TTL {
+		ttl = s.TTL
+	}
+	_ = s.Cache.Set(ctx, sessionKey(id, iat), []byte(strconv.FormatInt(exp, 10)), ttl)
+}
+
+func (s *SessionManager) GetExp(ctx context.Context, id string, iat int64) int64 {
+	if data, ok, err := s.Cache.Get(ctx, sessionKey(id, iat)); err == nil && ok {
+		if exp, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+			return exp
+		}
+	}
+	exp := s.Sessions.GetExp(ctx, id, iat)
+	if exp != 0 {
+		s.store(ctx, id, iat, exp)
+	}
+	return exp
+}
+
+func (s *SessionManager) AddNewSess(ctx context.Context, id string, exp int64, iat int64) error {
+	err := s.Sessions.AddNewSess(ctx, id, exp, iat)
+	if err != nil {
+		return err
+	}
+	s.store(ctx, id, iat, exp)
+	return nil
+}
+
+// DeleteSess drops the cached entry even when the database call fails, a
+// stale positive answer here would keep a revoked token alive.
+func (s *SessionManager) DeleteSess(ctx context.Context, userID string, iat int64) error {
+	defer s.Cache.Delete(ctx, sessionKey(userID, iat))
+	return s.Sessions.DeleteSess(ctx, userID, iat)
+}
+
+func (s *SessionManager) GetSessions(ctx context.Context, userID string) ([]session.SessionDB, error) {
+	return s.Sessions.GetSessions(ctx, userID)
+}
+
+func (s *SessionManager) DeleteUserSessions(ctx context.Context, userID string) error {
+	sessions, err := s.Sessions.GetSessions(ctx, userID)
+	if err == nil {
+		keys := make([]string, 0, len(sessions))
+		for _, sess := range sessions {
+			keys = append(keys, sessionKey(userID, sess.IAT))
+		}
+		if len(keys) > 0 {
+			defer s.Cache.Delete(ctx, keys...)
+		}
+	}
+	return s.Sessions.DeleteUserSessions(ctx, userID)
+}
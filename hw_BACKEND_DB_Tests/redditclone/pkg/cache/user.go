@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/user"
+)
+
+// UserRepo only remembers positive answers, so a freshly registered user is
+// never stuck behind a cached "not found".
+type UserRepo struct {
+	Repo  user.UserRepo
+	Cache Cache
+	TTL   time.Duration
+}
+
+func NewUserRepo(repo user.UserRepo, cache Cache, ttl time.Duration) *UserRepo {
+	return &UserRepo{
+		Repo:  repo,
+		Cache: cache,
+		TTL:   ttl,
+	}
+}
+
+func isUserKey(username string, id string) string {
+	return "user:" + id + ":" + username
+}
+
+func roleKey(id string) string {
+	return "role:" + id
+}
+
+func (u *UserRepo) AddNewUser(ctx context.Context, user user.User) (string, error) {
+	return u.Repo.AddNewUser(ctx, user)
+}
+
+func (u *UserRepo) Authenticate(ctx context.Context, user user.User) (string, error) {
+	return u.Repo.Authenticate(ctx, user)
+}
+
+func (u *UserRepo) IsUser(ctx context.Context, username string, id string) (bool, error) {
+	if _, ok, err := u.Cache.Get(ctx, isUserKey(username, id)); err == nil && ok {
+		return true, nil
+	}
+	isUser, err := u.Repo.IsUser(ctx, username, id)
+	if err != nil || !isUser {
+		return isUser, err
+	}
+	_ = u.Cache.Set(ctx, isUserKey(username, id), []byte{1}, u.TTL)
+	return true, nil
+}
+
+func (u *UserRepo) GetRole(ctx context.Context, id string) (string, error) {
+	if role, ok, err := u.Cache.Get(ctx, roleKey(id)); err == nil && ok {
+		return string(role), nil
+	}
+	role, err := u.Repo.GetRole(ctx, id)
+	if err != nil {
+		return role, err
+	}
+	_ = u.Cache.Set(ctx, roleKey(id), []byte(role), u.TTL)
+	return role, nil
+}
+
+func (u *UserRepo) GetUser(ctx context.Context, id string) (user.User, error) {
+	return u.Repo.GetUser(ctx, id)
+}
+
+// DeleteUser looks the user up first, the username is part of the IsUser key.
+func (u *UserRepo) DeleteUser(ctx context.Context, id string) error {
+	if found, err := u.Repo.GetUser(ctx, id); err == nil {
+		defer u.Cache.Delete(ctx, isUserKey(found.Username, id), roleKey(id))
+	} else {
+		defer u.Cache.Delete(ctx, roleKey(id))
+	}
+	return u.Repo.DeleteUser(ctx, id)
+}
+
+func (u *UserRepo) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	return u.Repo.GetUserByEmail(ctx, email)
+}
+
+func (u *UserRepo) SetEmail(ctx context.Context, id string, email string) error {
+	return u.Repo.SetEmail(ctx, id, email)
+}
+
+func (u *UserRepo) SetVerified(ctx context.Context, id string, email string) error {
+	return u.Repo.SetVerified(ctx, id, email)
+}
+
+func (u *UserRepo) SetPassword(ctx context.Context, id string, password string) error {
+	return u.Repo.SetPassword(ctx, id, password)
+}
+
+func (u *UserRepo) GetUserByIdentity(ctx context.Context, issuer string, subject string) (user.User, error) {
+	return u.Repo.GetUserByIdentity(ctx, issuer, subject)
+}
+
+func (u *UserRepo) LinkIdentity(ctx context.Context, id string, issuer string, subject string) error {
+	return u.Repo.LinkIdentity(ctx, id, issuer, subject)
+}
+
+func (u *UserRepo) GetTOTP(ctx context.Context, id string) (user.TOTP, error) {
+	return u.Repo.GetTOTP(ctx, id)
+}
+
+func (u *UserRepo) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	return u.Repo.SetTOTPSecret(ctx, id, secret)
+}
+
+func (u *UserRepo) EnableTOTP(ctx context.Context, id string, step int64, recoveryHashes []string) error {
+	return u.Repo.EnableTOTP(ctx, id, step, recoveryHashes)
+}
+
+func (u *UserRepo) DisableTOTP(ctx context.Context, id string) error {
+	return u.Repo.DisableTOTP(ctx, id)
+}
+
+func (u *UserRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	return u.Repo.UseTOTPStep(ctx, id, step)
+}
+
+func (u *UserRepo) UseRecoveryCode(ctx context.Context, id string, hash string) (bool, error) {
+	return u.Repo.UseRecoveryCode(ctx, id, hash)
+}
+
+func (u *UserRepo) TakeTOTPAttempt(ctx context.Context, id string) (bool, error) {
+	return u.Repo.TakeTOTPAttempt(ctx, id)
+}
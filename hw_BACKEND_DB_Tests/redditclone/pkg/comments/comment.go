@@ -11,6 +11,8 @@ type Comment struct {
 	Author  author.Author `json:"author"`
 	Body    string        `json:"body"`
 	ID      string        `json:"id"`
+	Score   int           `json:"score"`
+	Hidden  bool          `json:"-"`
 }
 
 type SimpleComment struct {
@@ -0,0 +1,94 @@
+package comments
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+const (
+	SortBest = "best"
+	SortNew  = "new"
+	SortOld  = "old"
+
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrBadSort   = errors.New("sort must be best, new or old")
+	ErrBadCursor = errors.New("bad cursor")
+	ErrBadLimit  = errors.New("bad limit")
+)
+
+// Page asks for the comments after the cursor in the given order. Comment
+// ids are ObjectID hex strings, so ordering by id is ordering by time.
+type Page struct {
+	Sort  string
+	After *Cursor
+	Limit int
+}
+
+type CommentsPage struct {
+	Comments []Comment `json:"comments"`
+	Next     string    `json:"next,omitempty"`
+}
+
+// Cursor points at the last comment of the previous page. Score is only
+// used by the best order.
+type Cursor struct {
+	Score int    `json:"s,omitempty"`
+	ID    string `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	cursor := &Cursor{}
+	if err = json.Unmarshal(data, cursor); err != nil || cursor.ID == "" {
+		return nil, ErrBadCursor
+	}
+	return cursor, nil
+}
+
+func NewPage(sort string, after string, limit string) (Page, error) {
+	page := Page{Sort: sort, Limit: DefaultPageSize}
+	switch sort {
+	case "":
+		page.Sort = SortBest
+	case SortBest, SortNew, SortOld:
+	default:
+		return Page{}, ErrBadSort
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxPageSize {
+			return Page{}, ErrBadLimit
+		}
+		page.Limit = n
+	}
+	if after != "" {
+		cursor, err := DecodeCursor(after)
+		if err != nil {
+			return Page{}, err
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
+
+func (p Page) CursorFor(comment Comment) string {
+	cursor := Cursor{ID: comment.ID}
+	if p.Sort == SortBest {
+		cursor.Score = comment.Score
+	}
+	return cursor.Encode()
+}
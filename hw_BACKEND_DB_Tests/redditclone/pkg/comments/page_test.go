@@ -0,0 +1,35 @@
+package comments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPage(t *testing.T) {
+	page, err := NewPage("", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, Page{Sort: SortBest, Limit: DefaultPageSize}, page)
+
+	after := Page{Sort: SortBest}.CursorFor(Comment{ID: "b", Score: 4})
+	page, err = NewPage(SortBest, after, "5")
+	assert.Nil(t, err)
+	assert.Equal(t, Page{Sort: SortBest, After: &Cursor{Score: 4, ID: "b"}, Limit: 5}, page)
+
+	_, err = NewPage("top", "", "")
+	assert.ErrorIs(t, err, ErrBadSort)
+	_, err = NewPage(SortNew, "", "101")
+	assert.ErrorIs(t, err, ErrBadLimit)
+	_, err = NewPage(SortNew, "", "0")
+	assert.ErrorIs(t, err, ErrBadLimit)
+	_, err = NewPage(SortNew, "!!", "")
+	assert.ErrorIs(t, err, ErrBadCursor)
+	_, err = NewPage(SortNew, Cursor{}.Encode(), "")
+	assert.ErrorIs(t, err, ErrBadCursor)
+}
+
+func TestCursorFor(t *testing.T) {
+	comment := Comment{ID: "a", Score: 3}
+	assert.Equal(t, Cursor{ID: "a"}.Encode(), Page{Sort: SortNew}.CursorFor(comment))
+	assert.Equal(t, Cursor{ID: "a", Score: 3}.Encode(), Page{Sort: SortBest}.CursorFor(comment))
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"redditclone/pkg/account"
+	"redditclone/pkg/author"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/response"
+)
+
+type AccountHandler struct {
+	Logger     logger.Logger
+	Accounts   account.Accounts
+	ContextKey key.Key
+}
+
+func (h *AccountHandler) log(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context(), h.Logger)
+}
+
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(h.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+
+	export, err := h.Accounts.Export(r.Context(), author.ID)
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	if r.URL.Query().Get("format") != "zip" {
+		response.ServerResponseWriter(w, 200, export)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="export-`+author.ID+`.zip"`)
+	w.WriteHeader(200)
+	if err = account.WriteZip(w, export); err != nil {
+		h.log(r).Log("Error", err.Error())
+	}
+}
+
+func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(h.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+
+	job, err := h.Accounts.ScheduleDeletion(r.Context(), author.ID)
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 202, job)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"redditclone/pkg/account"
+	"redditclone/pkg/author"
+	handlersTestsUtils "redditclone/pkg/handlers/testing"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func funcSwitcherAccount(funcName string, service interface{}, req *http.Request, w *httptest.ResponseRecorder) {
+	serviceAccount := service.(*AccountHandler)
+	switch funcName {
+	case "Export":
+		serviceAccount.Export(w, req)
+	case "Delete":
+		serviceAccount.Delete(w, req)
+	default:
+		return
+	}
+}
+
+func InitiateHandlerAccount(accounts *account.MockAccounts) *AccountHandler {
+	var key key.Key = "author"
+	return &AccountHandler{
+		Logger:     logger.NopLogger{},
+		Accounts:   accounts,
+		ContextKey: key,
+	}
+}
+
+func accountRequest(service *AccountHandler, method string, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	author := author.Author{
+		Username: "abc",
+		ID:       "12",
+	}
+	ctx := context.WithValue(req.Context(), service.ContextKey, &author)
+	return req.WithContext(ctx)
+}
+
+func TestAccountExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accounts := account.NewMockAccounts(ctrl)
+	service := InitiateHandlerAccount(accounts)
+
+	// no author
+	test := handlersTestsUtils.Testing{
+		Req:            httptest.NewRequest("GET", "/api/user/me/export", nil),
+		W:              httptest.NewRecorder(),
+		ExpectedStatus: 500,
+		Service:        service,
+		FuncName:       "Export",
+		T:              t,
+	}
+	handlersTestsUtils.StatusTesting(test, funcSwitcherAccount)
+
+	// export error
+	test.Req = accountRequest(service, "GET", "/api/user/me/export")
+	test.W = httptest.NewRecorder()
+	accounts.EXPECT().Export(gomock.Any(), "12").Return(account.Export{}, fmt.Errorf("db error"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherAccount)
+
+	// json
+	export := account.Export{User: account.Profile{ID: "12", Username: "abc"}}
+	test.Req = accountRequest(service, "GET", "/api/user/me/export")
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	test.Expected = handlersTestsUtils.ConvertToJSON(t, export)
+	accounts.EXPECT().Export(gomock.Any(), "12").Return(export, nil)
+	handlersTestsUtils.BodyTesting(test, funcSwitcherAccount)
+
+	// zip
+	w := httptest.NewRecorder()
+	accounts.EXPECT().Export(gomock.Any(), "12").Return(export, nil)
+	service.Export(w, accountRequest(service, "GET", "/api/user/me/export?format=zip"))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(archive.File))
+}
+
+func TestAccountDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accounts := account.NewMockAccounts(ctrl)
+	service := InitiateHandlerAccount(accounts)
+
+	// no author
+	test := handlersTestsUtils.Testing{
+		Req:            httptest.NewRequest("DELETE", "/api/user/me", nil),
+		W:              httptest.NewRecorder(),
+		ExpectedStatus: 500,
+		Service:        service,
+		FuncName:       "Delete",
+		T:              t,
+	}
+	handlersTestsUtils.StatusTesting(test, funcSwitcherAccount)
+
+	// schedule error
+	test.Req = accountRequest(service, "DELETE", "/api/user/me")
+	test.W = httptest.NewRecorder()
+	accounts.EXPECT().ScheduleDeletion(gomock.Any(), "12").Return(account.Job{}, fmt.Errorf("db error"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherAccount)
+
+	// OK
+	test.Req = accountRequest(service, "DELETE", "/api/user/me")
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 202
+	accounts.EXPECT().ScheduleDeletion(gomock.Any(), "12").Return(account.Job{UserID: "12", Step: account.StepSessions}, nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherAccount)
+}
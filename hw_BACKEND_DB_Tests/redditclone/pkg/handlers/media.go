@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/blob"
+	"redditclone/pkg/comments"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/media"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/response"
+	"redditclone/pkg/vote"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	mediaPrefix     = "/api/media/"
+	multipartMemory = 1 << 20
+)
+
+type MediaHandler struct {
+	Logger     logger.Logger
+	PostsRepo  posts.PostsRepository
+	Blobs      blob.Storage
+	Processor  *media.Processor
+	ContextKey key.Key
+}
+
+func (h *MediaHandler) log(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context(), h.Logger)
+}
+
+func (h *MediaHandler) NewImagePost(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(h.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+
+	// room for the form fields on top of the image itself
+	r.Body = http.MaxBytesReader(w, r.Body, h.Processor.MaxBytes+multipartMemory)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.ServerResponseWriter(w, 413, map[string]interface{}{"message": media.ErrTooLarge.Error()})
+			return
+		}
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad multipart form"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	title := strings.TrimSpace(r.FormValue("title"))
+	category := strings.TrimSpace(r.FormValue("category"))
+	if title == "" || category == "" {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "title and category are required"})
+		return
+	}
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "image is required"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.Processor.MaxBytes+1))
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+
+	processed, err := h.Processor.Process(data)
+	switch {
+	case errors.Is(err, media.ErrTooLarge), errors.Is(err, media.ErrTooManyPixels):
+		response.ServerResponseWriter(w, 413, map[string]interface{}{"message": err.Error()})
+		return
+	case errors.Is(err, media.ErrUnsupportedType):
+		response.ServerResponseWriter(w, 415, map[string]interface{}{"message": err.Error()})
+		return
+	case err != nil:
+		h.log(r).Log("Error", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+
+	id := primitive.NewObjectID().Hex()
+	imageKey := id + processed.Ext
+	thumbKey := id + "_thumb" + processed.ThumbnailExt
+	err = h.Blobs.Put(r.Context(), imageKey, processed.Image.ContentType, bytes.NewReader(data), int64(len(data)))
+	if err == nil {
+		err = h.Blobs.Put(r.Context(), thumbKey, processed.ThumbnailType, bytes.NewReader(processed.Thumbnail), int64(len(processed.Thumbnail)))
+	}
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		h.removeBlobs(r, imageKey, thumbKey)
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": "storage error"})
+		return
+	}
+
+	image := processed.Image
+	image.URL = mediaPrefix + imageKey
+	image.ThumbnailURL = mediaPrefix + thumbKey
+	post := posts.Post{
+		Score:            1,
+		Created:          time.Now(),
+		UpvotePercentage: 100,
+		Comments:         make([]comments.Comment, 0),
+		Type:             posts.TypeImage,
+		Title:            title,
+		Category:         category,
+		Text:             r.FormValue("text"),
+		Image:            &image,
+		Author:           *author,
+		Votes: []vote.Vote{{
+			User: author.ID,
+			Vote: 1,
+		}},
+	}
+	post, err = h.PostsRepo.AddPost(r.Context(), post)
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		h.removeBlobs(r, imageKey, thumbKey)
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 201, post)
+}
+
+func (h *MediaHandler) removeBlobs(r *http.Request, keys ...string) {
+	for _, key := range keys {
+		if err := h.Blobs.Delete(r.Context(), key); err != nil {
+			h.log(r).Log("Error", err.Error())
+		}
+	}
+}
+
+func (h *MediaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	body, contentType, err := h.Blobs.Get(r.Context(), mux.Vars(r)["KEY"])
+	switch {
+	case errors.Is(err, blob.ErrNotFound):
+		response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "not found"})
+		return
+	case errors.Is(err, blob.ErrBadKey):
+		w.WriteHeader(400)
+		return
+	case err != nil:
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": "storage error"})
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if _, err = io.Copy(w, body); err != nil {
+		h.log(r).Log("Error", err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/blob"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/media"
+	"redditclone/pkg/posts"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func InitiateHandlerMedia(rep *posts.MockPostsRepository, blobs *blob.MockStorage) *MediaHandler {
+	var key key.Key = "author"
+	return &MediaHandler{
+		Logger:     logger.NopLogger{},
+		PostsRepo:  rep,
+		Blobs:      blobs,
+		Processor:  media.NewProcessor(),
+		ContextKey: key,
+	}
+}
+
+func uploadRequest(t *testing.T, service *MediaHandler, fields map[string]string, file []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	if file != nil {
+		part, err := form.CreateFormFile("image", "cat.png")
+		assert.Nil(t, err)
+		part.Write(file)
+	}
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/posts/image", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	ctx := context.WithValue(req.Context(), service.ContextKey, &author.Author{Username: "abc", ID: "12"})
+	w := httptest.NewRecorder()
+	service.NewImagePost(w, req.WithContext(ctx))
+	return w
+}
+
+func TestNewImagePost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepo := posts.NewMockPostsRepository(ctrl)
+	blobs := blob.NewMockStorage(ctrl)
+	service := InitiateHandlerMedia(postsRepo, blobs)
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	fields := map[string]string{"title": "cat", "category": "funny"}
+
+	// missing title
+	w := uploadRequest(t, service, map[string]string{"category": "funny"}, buf.Bytes())
+	assert.Equal(t, 400, w.Code)
+
+	// missing file
+	w = uploadRequest(t, service, fields, nil)
+	assert.Equal(t, 400, w.Code)
+
+	// not an image
+	w = uploadRequest(t, service, fields, []byte("just text"))
+	assert.Equal(t, 415, w.Code)
+
+	// storage error
+	blobs.EXPECT().Put(gomock.Any(), gomock.Any(), "image/png", gomock.Any(), gomock.Any()).Return(fmt.Errorf("disk full"))
+	blobs.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	w = uploadRequest(t, service, fields, buf.Bytes())
+	assert.Equal(t, 500, w.Code)
+
+	// OK
+	blobs.EXPECT().Put(gomock.Any(), gomock.Any(), "image/png", gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	postsRepo.EXPECT().AddPost(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, post posts.Post) (posts.Post, error) {
+		assert.Equal(t, posts.TypeImage, post.Type)
+		assert.Equal(t, "12", post.Author.ID)
+		assert.True(t, strings.HasPrefix(post.Image.URL, "/api/media/"))
+		assert.True(t, strings.HasSuffix(post.Image.ThumbnailURL, "_thumb.png"))
+		post.ID = "1"
+		return post, nil
+	})
+	w = uploadRequest(t, service, fields, buf.Bytes())
+	assert.Equal(t, 201, w.Code)
+
+	// too large
+	service.Processor.MaxBytes = 10
+	w = uploadRequest(t, service, fields, bytes.Repeat([]byte{0}, 2<<20))
+	assert.Equal(t, 413, w.Code)
+}
+
+func TestGetMedia(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	blobs := blob.NewMockStorage(ctrl)
+	service := InitiateHandlerMedia(posts.NewMockPostsRepository(ctrl), blobs)
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/media/a.png", nil), map[string]string{"KEY": "a.png"})
+	blobs.EXPECT().Get(gomock.Any(), "a.png").Return(nil, "", blob.ErrNotFound)
+	w := httptest.NewRecorder()
+	service.Get(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	blobs.EXPECT().Get(gomock.Any(), "a.png").Return(io.NopCloser(strings.NewReader("png")), "image/png", nil)
+	w = httptest.NewRecorder()
+	service.Get(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "png", w.Body.String())
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"redditclone/pkg/oidc"
+	"redditclone/pkg/response"
+	"redditclone/pkg/user"
+)
+
+const (
+	// oidcLanding is the front-end page the callback sends the browser to,
+	// with the outcome in the fragment so it never reaches a server log.
+	oidcLanding     = "/"
+	oidcCookie      = "oidc_login"
+	oidcCookieAge   = 600
+	maxUsernameLen  = 32
+	usernameRetries = 5
+)
+
+func (u *UserHandler) loginCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcCookie,
+		Value:    value,
+		Path:     "/api/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(u.PublicURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func parseLoginCookie(r *http.Request) (oidc.Login, bool) {
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		return oidc.Login{}, false
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return oidc.Login{}, false
+	}
+	return oidc.Login{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, true
+}
+
+// OIDCLogin sends the browser to the identity provider. The state, nonce
+// and PKCE verifier wait in a short-lived cookie scoped to the callback.
+func (u *UserHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if u.OIDC == nil {
+		response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "oidc login is not configured"})
+		return
+	}
+	login := oidc.NewLogin()
+	http.SetCookie(w, u.loginCookie(login.State+"."+login.Nonce+"."+login.Verifier, oidcCookieAge))
+	http.Redirect(w, r, u.OIDC.AuthCodeURL(login), http.StatusFound)
+}
+
+// landOIDC ends the callback. The browser arrived by a redirect from the
+// provider, so it is sent on to the front-end with token, mfaToken or error.
+func (u *UserHandler) landOIDC(w http.ResponseWriter, r *http.Request, outcome url.Values) {
+	http.Redirect(w, r, u.PublicURL+oidcLanding+"#"+outcome.Encode(), http.StatusFound)
+}
+
+func (u *UserHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if u.OIDC == nil {
+		response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "oidc login is not configured"})
+		return
+	}
+	query := r.URL.Query()
+	login, ok := parseLoginCookie(r)
+	if !ok || subtle.ConstantTimeCompare([]byte(login.State), []byte(query.Get("state"))) != 1 {
+		u.landOIDC(w, r, url.Values{"error": {"login expired or state mismatch"}})
+		return
+	}
+	http.SetCookie(w, u.loginCookie("", -1))
+	if providerErr := query.Get("error"); providerErr != "" {
+		u.landOIDC(w, r, url.Values{"error": {"login failed: " + providerErr}})
+		return
+	}
+
+	claims, err := u.OIDC.Exchange(r.Context(), query.Get("code"), login)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		u.landOIDC(w, r, url.Values{"error": {"login failed"}})
+		return
+	}
+
+	found, err := u.oidcUser(r.Context(), claims)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		u.landOIDC(w, r, url.Values{"error": {dbError}})
+		return
+	}
+
+	tokenString, mfaToken, err := u.loginTokens(r.Context(), found)
+	switch {
+	case err != nil:
+		u.log(r).Log("Error", err.Error())
+		u.landOIDC(w, r, url.Values{"error": {dbError}})
+	case mfaToken != "":
+		u.landOIDC(w, r, url.Values{"mfaToken": {mfaToken}})
+	default:
+		u.landOIDC(w, r, url.Values{"token": {tokenString}})
+	}
+}
+
+// oidcUser finds the account linked to the identity. An unknown identity is
+// linked to the account with the same verified email, or gets a new account.
+func (u *UserHandler) oidcUser(ctx context.Context, claims oidc.Claims) (user.User, error) {
+	found, err := u.UserRepo.GetUserByIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		return found, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return user.User{}, err
+	}
+
+	email := ""
+	if claims.Email != "" && claims.EmailVerified {
+		email = claims.Email
+		found, err = u.UserRepo.GetUserByEmail(ctx, email)
+		switch {
+		case err == nil && found.Verified:
+			return found, u.UserRepo.LinkIdentity(ctx, found.ID, claims.Issuer, claims.Subject)
+		case err == nil:
+			// someone registered the address without confirming it
+			email = ""
+		case !errors.Is(err, sql.ErrNoRows):
+			return user.User{}, err
+		}
+	}
+
+	found, err = u.newOIDCUser(ctx, claims, email)
+	if err != nil {
+		return user.User{}, err
+	}
+	if email != "" {
+		if err = u.UserRepo.SetVerified(ctx, found.ID, email); err != nil {
+			return user.User{}, err
+		}
+	}
+	return found, u.UserRepo.LinkIdentity(ctx, found.ID, claims.Issuer, claims.Subject)
+}
+
+func (u *UserHandler) newOIDCUser(ctx context.Context, claims oidc.Claims, email string) (user.User, error) {
+	base := usernameFrom(claims)
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return user.User{}, err
+	}
+	// the account can only sign in through the provider until a password
+	// is set with a reset
+	created := user.User{Login: base, Password: hex.EncodeToString(password), Email: email}
+
+	var err error
+	for attempt := 0; attempt < usernameRetries; attempt++ {
+		created.Username = base
+		if attempt > 0 {
+			suffix := make([]byte, 2)
+			if _, err = rand.Read(suffix); err != nil {
+				return user.User{}, err
+			}
+			created.Username = fmt.Sprintf("%s-%s", base, hex.EncodeToString(suffix))
+		}
+		created.ID, err = u.UserRepo.AddNewUser(ctx, created)
+		if err == nil {
+			return created, nil
+		}
+	}
+	return user.User{}, err
+}
+
+func usernameFrom(claims oidc.Claims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, name)
+	if len(name) > maxUsernameLen {
+		name = name[:maxUsernameLen]
+	}
+	if name == "" {
+		name = "user"
+	}
+	return name
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/oidc"
+	"redditclone/pkg/oidc/mockidp"
+	"redditclone/pkg/session"
+	"redditclone/pkg/token"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const oidcRedirect = "http://localhost:8080/api/oidc/callback"
+
+func InitiateHandlerOIDC(t *testing.T, users *user.MockUserRepo, sess *session.MockSessionManager) (*UserHandler, *mockidp.Provider) {
+	idp, srv, err := mockidp.NewServer("redditclone", oidcRedirect)
+	require.Nil(t, err)
+	t.Cleanup(srv.Close)
+	endpoints, err := oidc.Discover(context.Background(), srv.Client(), idp.Issuer)
+	require.Nil(t, err)
+
+	var key key.Key = "author"
+	return &UserHandler{
+		Logger:     logger.NopLogger{},
+		UserRepo:   users,
+		Session:    sess,
+		ContextKey: key,
+		PublicURL:  "http://localhost:8080",
+		Tokens:     token.NewSigner([]byte("secret")),
+		OIDC:       oidc.NewClient(oidc.Config{ClientID: "redditclone", RedirectURL: oidcRedirect}, endpoints, srv.Client()),
+	}, idp
+}
+
+// oidcCallback runs the login handler, lets the provider approve and
+// returns the callback request carrying the login cookie.
+func oidcCallback(t *testing.T, service *UserHandler) *http.Request {
+	w := httptest.NewRecorder()
+	service.OIDCLogin(w, httptest.NewRequest("GET", "/api/oidc/login", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noFollow.Get(w.Header().Get("Location"))
+	require.Nil(t, err)
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.Nil(t, err)
+
+	req := httptest.NewRequest("GET", "/api/oidc/callback?"+back.RawQuery, nil)
+	req.AddCookie(cookies[0])
+	return req
+}
+
+func expectSession(users *user.MockUserRepo, sess *session.MockSessionManager, userID string) {
+	users.EXPECT().GetTOTP(gomock.Any(), userID).Return(user.TOTP{}, nil)
+	sess.EXPECT().AddNewSess(gomock.Any(), userID, gomock.Any(), gomock.Any()).Return(nil)
+	sess.EXPECT().GetExp(gomock.Any(), userID, gomock.Any()).Return(time.Now().Add(time.Hour).Unix())
+	sess.EXPECT().GetKey().Return([]byte("secret"))
+}
+
+// landing returns the fragment of the front-end page the callback sent the
+// browser to.
+func landing(t *testing.T, w *httptest.ResponseRecorder) url.Values {
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/", location.Scheme+"://"+location.Host+location.Path)
+	assert.Empty(t, location.RawQuery)
+	fragment, err := url.ParseQuery(location.Fragment)
+	require.Nil(t, err)
+	return fragment
+}
+
+func TestOIDCCallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	service, idp := InitiateHandlerOIDC(t, users, sess)
+
+	// not configured
+	w := httptest.NewRecorder()
+	(&UserHandler{}).OIDCLogin(w, httptest.NewRequest("GET", "/api/oidc/login", nil))
+	assert.Equal(t, 404, w.Code)
+
+	// no login cookie
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, httptest.NewRequest("GET", "/api/oidc/callback?code=a&state=b", nil))
+	assert.Equal(t, "login expired or state mismatch", landing(t, w).Get("error"))
+
+	// state mismatch
+	req := oidcCallback(t, service)
+	query := req.URL.Query()
+	query.Set("state", "forged")
+	req.URL.RawQuery = query.Encode()
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, req)
+	assert.Equal(t, "login expired or state mismatch", landing(t, w).Get("error"))
+
+	// linked identity
+	idp.Identity = mockidp.Identity{Subject: "42", PreferredUsername: "alice"}
+	req = oidcCallback(t, service)
+	users.EXPECT().GetUserByIdentity(gomock.Any(), idp.Issuer, "42").Return(user.User{ID: "7", Username: "alice"}, nil)
+	expectSession(users, sess, "7")
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, req)
+	fragment := landing(t, w)
+	assert.NotEmpty(t, fragment.Get("token"))
+	assert.Empty(t, fragment.Get("error"))
+
+	// with 2FA on the front-end gets the pre-auth token for /api/login/2fa
+	idp.Identity = mockidp.Identity{Subject: "42", PreferredUsername: "alice"}
+	req = oidcCallback(t, service)
+	users.EXPECT().GetUserByIdentity(gomock.Any(), idp.Issuer, "42").Return(user.User{ID: "7", Username: "alice"}, nil)
+	users.EXPECT().GetTOTP(gomock.Any(), "7").Return(user.TOTP{Secret: "S", Enabled: true}, nil)
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, req)
+	fragment = landing(t, w)
+	assert.Empty(t, fragment.Get("token"))
+	userID, err := service.Tokens.Parse(fragment.Get("mfaToken"), token.PurposeMFA)
+	assert.Nil(t, err)
+	assert.Equal(t, "7", userID)
+
+	// verified email of an existing account links to it
+	idp.Identity = mockidp.Identity{Subject: "43", Email: "bob@example.com", EmailVerified: true}
+	req = oidcCallback(t, service)
+	users.EXPECT().GetUserByIdentity(gomock.Any(), idp.Issuer, "43").Return(user.User{}, sql.ErrNoRows)
+	users.EXPECT().GetUserByEmail(gomock.Any(), "bob@example.com").Return(user.User{ID: "8", Username: "bob", Verified: true}, nil)
+	users.EXPECT().LinkIdentity(gomock.Any(), "8", idp.Issuer, "43").Return(nil)
+	expectSession(users, sess, "8")
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, req)
+	assert.NotEmpty(t, landing(t, w).Get("token"))
+
+	// new account, the first username is taken
+	idp.Identity = mockidp.Identity{Subject: "44", Email: "carol@example.com", EmailVerified: true, PreferredUsername: "carol!"}
+	req = oidcCallback(t, service)
+	users.EXPECT().GetUserByIdentity(gomock.Any(), idp.Issuer, "44").Return(user.User{}, sql.ErrNoRows)
+	users.EXPECT().GetUserByEmail(gomock.Any(), "carol@example.com").Return(user.User{}, sql.ErrNoRows)
+	gomock.InOrder(
+		users.EXPECT().AddNewUser(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) (string, error) {
+			assert.Equal(t, "carol", u.Username)
+			return "", sql.ErrConnDone
+		}),
+		users.EXPECT().AddNewUser(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, u user.User) (string, error) {
+			assert.Regexp(t, "^carol-[0-9a-f]{4}$", u.Username)
+			assert.Equal(t, "carol@example.com", u.Email)
+			assert.NotEmpty(t, u.Password)
+			return "9", nil
+		}),
+	)
+	users.EXPECT().SetVerified(gomock.Any(), "9", "carol@example.com").Return(nil)
+	users.EXPECT().LinkIdentity(gomock.Any(), "9", idp.Issuer, "44").Return(nil)
+	expectSession(users, sess, "9")
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, req)
+	assert.NotEmpty(t, landing(t, w).Get("token"))
+
+	// the code can't be replayed
+	w = httptest.NewRecorder()
+	service.OIDCCallback(w, req)
+	assert.Equal(t, "login failed", landing(t, w).Get("error"))
+}
+
+func TestUsernameFrom(t *testing.T) {
+	assert.Equal(t, "alice", usernameFrom(oidc.Claims{PreferredUsername: "alice"}))
+	assert.Equal(t, "bob_b", usernameFrom(oidc.Claims{Email: "bob_b@example.com"}))
+	assert.Equal(t, "user", usernameFrom(oidc.Claims{PreferredUsername: "ä ö"}))
+}
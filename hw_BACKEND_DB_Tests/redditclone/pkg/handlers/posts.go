@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -14,9 +15,11 @@ import (
 	"redditclone/pkg/posts"
 	"redditclone/pkg/response"
 	"redditclone/pkg/session"
+	"redditclone/pkg/unfurl"
 	"redditclone/pkg/vote"
 
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type PostsHandler struct {
@@ -24,6 +27,11 @@ type PostsHandler struct {
 	PostsRepo  posts.PostsRepository
 	Logger     logger.Logger
 	ContextKey key.Key
+	Unfurl     unfurl.Queue
+}
+
+func (p *PostsHandler) log(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context(), p.Logger)
 }
 
 var dbError string = "DB error"
@@ -31,7 +39,7 @@ var dbError string = "DB error"
 func (p *PostsHandler) All(w http.ResponseWriter, r *http.Request) {
 	posts, err := p.PostsRepo.GetAllPosts(r.Context())
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 	}
 	response.ServerResponseWriter(w, 200, posts)
@@ -56,6 +64,10 @@ func (p *PostsHandler) NewPost(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
+	if post.Type == posts.TypeImage {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "image posts are uploaded to /api/posts/image"})
+		return
+	}
 
 	author, ok := r.Context().Value(p.ContextKey).(*author.Author)
 	if !ok {
@@ -71,10 +83,13 @@ func (p *PostsHandler) NewPost(w http.ResponseWriter, r *http.Request) {
 
 	post, err = p.PostsRepo.AddPost(r.Context(), post)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
+	if p.Unfurl != nil {
+		p.Unfurl.Enqueue(post)
+	}
 
 	response.ServerResponseWriter(w, 201, post)
 }
@@ -89,21 +104,25 @@ func (p *PostsHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 
 	post, err := p.PostsRepo.GetPostByID(r.Context(), id)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
+	if post.Hidden {
+		response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "post not found"})
+		return
+	}
 
 	post.Views += 1
 
 	err = p.PostsRepo.UpdatePost(r.Context(), post)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
 
-	response.ServerResponseWriter(w, 201, post)
+	response.ServerResponseWriter(w, 201, post.Visible())
 }
 
 func (p *PostsHandler) GetPostsByCategory(w http.ResponseWriter, r *http.Request) {
@@ -116,7 +135,7 @@ func (p *PostsHandler) GetPostsByCategory(w http.ResponseWriter, r *http.Request
 
 	posts, err := p.PostsRepo.GetCategory(r.Context(), category)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
@@ -159,12 +178,12 @@ func (p *PostsHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 
 	post, err := p.PostsRepo.AddComment(r.Context(), id, newComm)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
 
-	response.ServerResponseWriter(w, 201, post)
+	response.ServerResponseWriter(w, 201, post.Visible())
 }
 
 func (p *PostsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
@@ -177,11 +196,32 @@ func (p *PostsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	}
 	post, err := p.PostsRepo.DeleteComment(r.Context(), postID, commID)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
-	response.ServerResponseWriter(w, 200, post)
+	response.ServerResponseWriter(w, 200, post.Visible())
+}
+
+func (p *PostsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	postID := mux.Vars(r)["POST_ID"]
+	if postID == "" {
+		w.WriteHeader(400)
+		return
+	}
+	query := r.URL.Query()
+	page, err := comments.NewPage(query.Get("sort"), query.Get("after"), query.Get("limit"))
+	if err != nil {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	found, err := p.PostsRepo.GetComments(r.Context(), postID, page)
+	if err != nil {
+		p.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 200, found)
 }
 
 func (p *PostsHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
@@ -193,7 +233,7 @@ func (p *PostsHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	}
 	err := p.PostsRepo.DeletePost(r.Context(), postID)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
@@ -228,12 +268,12 @@ func (p *PostsHandler) Vote(w http.ResponseWriter, r *http.Request) {
 
 	post, err := p.PostsRepo.Vote(r.Context(), id, newVote)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
 
-	response.ServerResponseWriter(w, 200, post)
+	response.ServerResponseWriter(w, 200, post.Visible())
 }
 
 func (p *PostsHandler) UnVote(w http.ResponseWriter, r *http.Request) {
@@ -252,10 +292,50 @@ func (p *PostsHandler) UnVote(w http.ResponseWriter, r *http.Request) {
 
 	post, err := p.PostsRepo.UnVote(r.Context(), author.ID, id)
 	if err != nil {
-		p.Logger.Log("Error", err.Error())
+		p.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	response.ServerResponseWriter(w, 200, post.Visible())
+}
+
+// VoteComment serves upvote, downvote and unvote of a comment, unvote is a
+// zero vote.
+func (p *PostsHandler) VoteComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["POST_ID"]
+	commID := vars["COMMENT_ID"]
+	if postID == "" || commID == "" {
+		w.WriteHeader(400)
+		return
+	}
+
+	author, ok := r.Context().Value(p.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+
+	newVote := vote.Vote{User: author.ID}
+	paths := strings.Split(r.URL.Path, "/")
+	switch paths[len(paths)-1] {
+	case "upvote":
+		newVote.Vote = 1
+	case "downvote":
+		newVote.Vote = -1
+	}
+
+	comment, err := p.PostsRepo.VoteComment(r.Context(), postID, commID, newVote)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "comment not found"})
+		return
+	}
+	if err != nil {
+		p.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
 
-	response.ServerResponseWriter(w, 200, post)
+	response.ServerResponseWriter(w, 200, comment)
 }
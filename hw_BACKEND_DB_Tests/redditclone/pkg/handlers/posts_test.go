@@ -21,8 +21,6 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
 func funcSwitcher(funcName string, service interface{}, req *http.Request, w *httptest.ResponseRecorder) {
@@ -34,6 +32,8 @@ func funcSwitcher(funcName string, service interface{}, req *http.Request, w *ht
 		servicePosts.AddComment(w, req)
 	case "DeleteComment":
 		servicePosts.DeleteComment(w, req)
+	case "GetComments":
+		servicePosts.GetComments(w, req)
 	case "DeletePost":
 		servicePosts.DeletePost(w, req)
 	case "GetPost":
@@ -46,24 +46,15 @@ func funcSwitcher(funcName string, service interface{}, req *http.Request, w *ht
 		servicePosts.UnVote(w, req)
 	case "Vote":
 		servicePosts.Vote(w, req)
+	case "VoteComment":
+		servicePosts.VoteComment(w, req)
 	default:
 		return
 	}
 }
 
 func InitiateHandler(rep *posts.MockPostsRepository) *PostsHandler {
-	zapConfig := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapcore.InfoLevel),
-		Development:      true,
-		Encoding:         "console",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"Nop"},
-		ErrorOutputPaths: []string{"Nop"},
-	}
-	logger, err := logger.NewCustomLogger(zapConfig)
-	if err != nil {
-		panic(err.Error())
-	}
+	logger := logger.NopLogger{}
 	var key key.Key = "author"
 	service := &PostsHandler{
 		PostsRepo:  rep,
@@ -231,6 +222,14 @@ func TestGetPost(t *testing.T) {
 	st.EXPECT().UpdatePost(test.Req.Context(), outPost).Return(fmt.Errorf("skddlsf"))
 	handlersTestsUtils.StatusTesting(test, funcSwitch)
 
+	// hidden post
+	test.Req = httptest.NewRequest("GET", "/api/post/{POST_ID}", nil)
+	test.Req = mux.SetURLVars(test.Req, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 404
+	st.EXPECT().GetPostByID(test.Req.Context(), "1").Return(posts.Post{ID: "1", Hidden: true}, nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
 	// OK
 	req := httptest.NewRequest("GET", "/api/post/{POST_ID}", nil)
 	test.Req = mux.SetURLVars(req, map[string]string{"POST_ID": "1"})
@@ -414,6 +413,52 @@ func TestDeleteComment(t *testing.T) {
 	handlersTestsUtils.BodyTesting(test, funcSwitch)
 }
 
+func TestGetComments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	funcSwitch := funcSwitcher
+	st := posts.NewMockPostsRepository(ctrl)
+	service := InitiateHandler(st)
+	var test handlersTestsUtils.Testing
+
+	// bad sort
+	test.Req = httptest.NewRequest("GET", "/api/post/1/comments?sort=top", nil)
+	test.Req = mux.SetURLVars(test.Req, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.FuncName = "GetComments"
+	test.ExpectedStatus = 400
+	test.Service = service
+	test.T = t
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
+	// bad cursor
+	test.Req = httptest.NewRequest("GET", "/api/post/1/comments?after=%21", nil)
+	test.Req = mux.SetURLVars(test.Req, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
+	// GetComments error
+	test.Req = httptest.NewRequest("GET", "/api/post/1/comments", nil)
+	test.Req = mux.SetURLVars(test.Req, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	st.EXPECT().GetComments(test.Req.Context(), "1", comments.Page{Sort: comments.SortBest, Limit: comments.DefaultPageSize}).Return(comments.CommentsPage{}, fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
+	// Ok
+	after := comments.Cursor{ID: "b"}.Encode()
+	page := comments.CommentsPage{Comments: []comments.Comment{{ID: "a"}}}
+	test.Req = httptest.NewRequest("GET", "/api/post/1/comments?sort=new&limit=1&after="+after, nil)
+	test.Req = mux.SetURLVars(test.Req, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	test.Expected = handlersTestsUtils.ConvertToJSON(t, page)
+	st.EXPECT().GetComments(test.Req.Context(), "1", comments.Page{Sort: comments.SortNew, After: &comments.Cursor{ID: "b"}, Limit: 1}).Return(page, nil).MaxTimes(2)
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+	handlersTestsUtils.BodyTesting(test, funcSwitch)
+}
+
 func TestDeletePost(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -565,3 +610,55 @@ func TestUnVote(t *testing.T) {
 	handlersTestsUtils.StatusTesting(test, funcSwitch)
 	handlersTestsUtils.BodyTesting(test, funcSwitch)
 }
+
+func TestVoteComment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	funcSwitch := funcSwitcher
+	st := posts.NewMockPostsRepository(ctrl)
+	service := InitiateHandler(st)
+	var test handlersTestsUtils.Testing
+	author := author.Author{
+		ID:       "12",
+		Username: "abc",
+	}
+	request := func(path string) *http.Request {
+		req := httptest.NewRequest("POST", path, nil)
+		req = mux.SetURLVars(req, map[string]string{"POST_ID": "1", "COMMENT_ID": "a"})
+		return req.WithContext(context.WithValue(req.Context(), service.ContextKey, &author))
+	}
+
+	// empty URL
+	test.Req = httptest.NewRequest("POST", "/api/post/1/a/upvote", nil)
+	test.W = httptest.NewRecorder()
+	test.FuncName = "VoteComment"
+	test.ExpectedStatus = 400
+	test.Service = service
+	test.T = t
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
+	// no such comment
+	test.Req = request("/api/post/1/a/downvote")
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 404
+	st.EXPECT().VoteComment(test.Req.Context(), "1", "a", vote.Vote{User: "12", Vote: -1}).Return(comments.Comment{}, fmt.Errorf("error in votecomment: %w", mongo.ErrNoDocuments))
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
+	// VoteComment error
+	test.Req = request("/api/post/1/a/unvote")
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	st.EXPECT().VoteComment(test.Req.Context(), "1", "a", vote.Vote{User: "12"}).Return(comments.Comment{}, fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+
+	// OK
+	voted := comments.Comment{ID: "a", Score: 1}
+	test.Req = request("/api/post/1/a/upvote")
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	test.Expected = handlersTestsUtils.ConvertToJSON(t, voted)
+	st.EXPECT().VoteComment(test.Req.Context(), "1", "a", vote.Vote{User: "12", Vote: 1}).Return(voted, nil).MaxTimes(2)
+	handlersTestsUtils.StatusTesting(test, funcSwitch)
+	handlersTestsUtils.BodyTesting(test, funcSwitch)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"time"
+
+	"redditclone/pkg/author"
+	mailer "redditclone/pkg/mail"
+	"redditclone/pkg/response"
+	"redditclone/pkg/token"
+	"redditclone/pkg/user"
+)
+
+const (
+	verifyTTL = 48 * time.Hour
+	resetTTL  = time.Hour
+)
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+type tokenRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func emailTaken(err error) bool {
+	return errors.Is(err, user.ErrEmailTaken)
+}
+
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// verifyState changes once the address is verified or replaced, which
+// retires the tokens sent for it.
+func verifyState(u user.User) string {
+	return strconv.FormatBool(u.Verified) + ":" + u.Email
+}
+
+func resetState(u user.User) string {
+	return strconv.FormatInt(u.PasswordChanged, 10)
+}
+
+func readJSON(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (u *UserHandler) sendVerification(ctx context.Context, found user.User) error {
+	tok := u.Tokens.Sign(token.PurposeVerify, found.ID, verifyState(found), verifyTTL)
+	return u.Mailer.Send(ctx, mailer.Message{
+		To:      found.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nto confirm this address send the token below to POST %s/api/user/verify.\n\n%s\n\nThe token expires in %s.\n",
+			found.Username, u.PublicURL, tok, verifyTTL),
+	})
+}
+
+func (u *UserHandler) sendReset(ctx context.Context, found user.User) error {
+	tok := u.Tokens.Sign(token.PurposeReset, found.ID, resetState(found), resetTTL)
+	return u.Mailer.Send(ctx, mailer.Message{
+		To:      found.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nto choose a new password send the token below with it to POST %s/api/password/reset/confirm.\n\n%s\n\nThe token expires in %s. If you did not ask for a reset, ignore this email.\n",
+			found.Username, u.PublicURL, tok, resetTTL),
+	})
+}
+
+// tokenUser loads the user a token was issued for and checks the token
+// against the user's current state.
+func (u *UserHandler) tokenUser(ctx context.Context, tok string, purpose string, state func(user.User) string) (user.User, error) {
+	userID, err := u.Tokens.Parse(tok, purpose)
+	if err != nil {
+		return user.User{}, err
+	}
+	found, err := u.UserRepo.GetUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user.User{}, token.ErrInvalid
+	}
+	if err != nil {
+		return user.User{}, err
+	}
+	if err = u.Tokens.Verify(tok, purpose, state(found)); err != nil {
+		return user.User{}, err
+	}
+	return found, nil
+}
+
+func tokenError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, token.ErrInvalid) || errors.Is(err, token.ErrExpired) {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": err.Error()})
+		return true
+	}
+	return false
+}
+
+func (u *UserHandler) SetEmail(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(u.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+	req := emailRequest{}
+	if err := readJSON(r, &req); err != nil || !validEmail(req.Email) {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad email"})
+		return
+	}
+
+	err := u.UserRepo.SetEmail(r.Context(), author.ID, req.Email)
+	if emailTaken(err) {
+		response.ServerResponseWriter(w, 409, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	found := user.User{ID: author.ID, Username: author.Username, Email: req.Email}
+	if err = u.sendVerification(r.Context(), found); err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": "could not send email"})
+		return
+	}
+	response.ServerResponseWriter(w, 202, map[string]interface{}{"message": "verification sent"})
+}
+
+func (u *UserHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	req := tokenRequest{}
+	if err := readJSON(r, &req); err != nil {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad request"})
+		return
+	}
+	found, err := u.tokenUser(r.Context(), req.Token, token.PurposeVerify, verifyState)
+	if tokenError(w, err) {
+		return
+	}
+	if err == nil {
+		err = u.UserRepo.SetVerified(r.Context(), found.ID, found.Email)
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 200, map[string]interface{}{"message": "email verified"})
+}
+
+// RequestReset answers the same whether or not the address is known, so it
+// can't be used to find out who is registered.
+func (u *UserHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+	req := emailRequest{}
+	if err := readJSON(r, &req); err != nil || !validEmail(req.Email) {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad email"})
+		return
+	}
+
+	found, err := u.UserRepo.GetUserByEmail(r.Context(), req.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	case found.Verified:
+		if err = u.sendReset(r.Context(), found); err != nil {
+			u.log(r).Log("Error", err.Error())
+		}
+	}
+	response.ServerResponseWriter(w, 202, map[string]interface{}{"message": "if the address is verified, a reset email is on its way"})
+}
+
+func (u *UserHandler) ConfirmReset(w http.ResponseWriter, r *http.Request) {
+	req := tokenRequest{}
+	if err := readJSON(r, &req); err != nil || req.Password == "" {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "token and password are required"})
+		return
+	}
+	found, err := u.tokenUser(r.Context(), req.Token, token.PurposeReset, resetState)
+	if tokenError(w, err) {
+		return
+	}
+	if err == nil {
+		err = u.UserRepo.SetPassword(r.Context(), found.ID, req.Password)
+	}
+	if err == nil {
+		err = u.Session.DeleteUserSessions(r.Context(), found.ID)
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 200, map[string]interface{}{"message": "password changed"})
+}
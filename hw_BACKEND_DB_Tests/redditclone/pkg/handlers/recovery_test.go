@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"redditclone/pkg/author"
+	handlersTestsUtils "redditclone/pkg/handlers/testing"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/mail"
+	"redditclone/pkg/session"
+	"redditclone/pkg/token"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func funcSwitcherRecovery(funcName string, service interface{}, req *http.Request, w *httptest.ResponseRecorder) {
+	serviceUser := service.(*UserHandler)
+	switch funcName {
+	case "SetEmail":
+		serviceUser.SetEmail(w, req)
+	case "Verify":
+		serviceUser.Verify(w, req)
+	case "RequestReset":
+		serviceUser.RequestReset(w, req)
+	case "ConfirmReset":
+		serviceUser.ConfirmReset(w, req)
+	default:
+		return
+	}
+}
+
+func InitiateHandlerRecovery(users *user.MockUserRepo, sess *session.MockSessionManager, mailer *mail.MockMailer) *UserHandler {
+	var key key.Key = "author"
+	return &UserHandler{
+		Logger:     logger.NopLogger{},
+		UserRepo:   users,
+		Session:    sess,
+		ContextKey: key,
+		Mailer:     mailer,
+		Tokens:     token.NewSigner([]byte("secret")),
+		PublicURL:  "http://localhost:8080",
+	}
+}
+
+func recoveryRequest(service *UserHandler, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/user/me/email", bytes.NewBufferString(body))
+	ctx := context.WithValue(req.Context(), service.ContextKey, &author.Author{Username: "abc", ID: "12"})
+	return req.WithContext(ctx)
+}
+
+// sentToken captures the token from the next mail the handler sends.
+func sentToken(mailer *mail.MockMailer, to string, tok *string) {
+	mailer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msg mail.Message) error {
+		if msg.To != to {
+			return fmt.Errorf("mail to %s, want %s", msg.To, to)
+		}
+		for _, line := range strings.Split(msg.Body, "\n") {
+			if strings.Count(line, ".") == 1 && !strings.Contains(line, " ") {
+				*tok = line
+			}
+		}
+		return nil
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	mailer := mail.NewMockMailer(ctrl)
+	service := InitiateHandlerRecovery(users, sess, mailer)
+
+	test := handlersTestsUtils.Testing{
+		Service:  service,
+		FuncName: "SetEmail",
+		T:        t,
+	}
+
+	// bad email
+	test.Req = recoveryRequest(service, `{"email": "Abc <abc@example.com>"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// taken
+	test.Req = recoveryRequest(service, `{"email": "abc@example.com"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 409
+	users.EXPECT().SetEmail(test.Req.Context(), "12", "abc@example.com").Return(user.ErrEmailTaken)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// mail error
+	test.Req = recoveryRequest(service, `{"email": "abc@example.com"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	users.EXPECT().SetEmail(test.Req.Context(), "12", "abc@example.com").Return(nil)
+	mailer.EXPECT().Send(test.Req.Context(), gomock.Any()).Return(fmt.Errorf("smtp down"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// OK
+	var tok string
+	test.Req = recoveryRequest(service, `{"email": "abc@example.com"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 202
+	users.EXPECT().SetEmail(test.Req.Context(), "12", "abc@example.com").Return(nil)
+	sentToken(mailer, "abc@example.com", &tok)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+	assert.NotEmpty(t, tok)
+
+	unverified := user.User{ID: "12", Username: "abc", Email: "abc@example.com"}
+	test.FuncName = "Verify"
+
+	// token for a replaced address
+	test.Req = httptest.NewRequest("POST", "/api/user/verify", bytes.NewBufferString(`{"token": "`+tok+`"}`))
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	users.EXPECT().GetUser(test.Req.Context(), "12").Return(user.User{ID: "12", Email: "new@example.com"}, nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// OK
+	test.Req = httptest.NewRequest("POST", "/api/user/verify", bytes.NewBufferString(`{"token": "`+tok+`"}`))
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	users.EXPECT().GetUser(test.Req.Context(), "12").Return(unverified, nil)
+	users.EXPECT().SetVerified(test.Req.Context(), "12", "abc@example.com").Return(nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// used twice
+	verified := unverified
+	verified.Verified = true
+	test.Req = httptest.NewRequest("POST", "/api/user/verify", bytes.NewBufferString(`{"token": "`+tok+`"}`))
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	users.EXPECT().GetUser(test.Req.Context(), "12").Return(verified, nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// user is gone
+	test.Req = httptest.NewRequest("POST", "/api/user/verify", bytes.NewBufferString(`{"token": "`+tok+`"}`))
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	users.EXPECT().GetUser(test.Req.Context(), "12").Return(user.User{}, sql.ErrNoRows)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	mailer := mail.NewMockMailer(ctrl)
+	service := InitiateHandlerRecovery(users, sess, mailer)
+
+	test := handlersTestsUtils.Testing{
+		Service:  service,
+		FuncName: "RequestReset",
+		T:        t,
+	}
+	resetRequest := func(body string) *http.Request {
+		return httptest.NewRequest("POST", "/api/password/reset", bytes.NewBufferString(body))
+	}
+
+	// unknown address looks the same as a known one
+	test.Req = resetRequest(`{"email": "nobody@example.com"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 202
+	users.EXPECT().GetUserByEmail(test.Req.Context(), "nobody@example.com").Return(user.User{}, sql.ErrNoRows)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// unverified address gets no mail
+	test.Req = resetRequest(`{"email": "abc@example.com"}`)
+	test.W = httptest.NewRecorder()
+	users.EXPECT().GetUserByEmail(test.Req.Context(), "abc@example.com").Return(user.User{ID: "12", Email: "abc@example.com"}, nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// db error
+	test.Req = resetRequest(`{"email": "abc@example.com"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	users.EXPECT().GetUserByEmail(test.Req.Context(), "abc@example.com").Return(user.User{}, fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// OK
+	found := user.User{ID: "12", Username: "abc", Email: "abc@example.com", Verified: true, PasswordChanged: 5}
+	var tok string
+	test.Req = resetRequest(`{"email": "abc@example.com"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 202
+	users.EXPECT().GetUserByEmail(test.Req.Context(), "abc@example.com").Return(found, nil)
+	sentToken(mailer, "abc@example.com", &tok)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+	assert.NotEmpty(t, tok)
+
+	test.FuncName = "ConfirmReset"
+	confirm := func(body string) *http.Request {
+		return httptest.NewRequest("POST", "/api/password/reset/confirm", bytes.NewBufferString(body))
+	}
+
+	// no password
+	test.Req = confirm(`{"token": "` + tok + `"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// verification token does not reset passwords
+	other := service.Tokens.Sign(token.PurposeVerify, "12", verifyState(found), time.Hour)
+	test.Req = confirm(`{"token": "` + other + `", "password": "new"}`)
+	test.W = httptest.NewRecorder()
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// OK, sessions end
+	test.Req = confirm(`{"token": "` + tok + `", "password": "new"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	users.EXPECT().GetUser(test.Req.Context(), "12").Return(found, nil)
+	users.EXPECT().SetPassword(test.Req.Context(), "12", "new").Return(nil)
+	sess.EXPECT().DeleteUserSessions(test.Req.Context(), "12").Return(nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+
+	// used twice
+	changed := found
+	changed.PasswordChanged = 6
+	test.Req = confirm(`{"token": "` + tok + `", "password": "again"}`)
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	users.EXPECT().GetUser(test.Req.Context(), "12").Return(changed, nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherRecovery)
+}
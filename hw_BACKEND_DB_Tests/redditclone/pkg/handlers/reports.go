@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/report"
+	"redditclone/pkg/response"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportsHandler struct {
+	Logger        logger.Logger
+	PostsRepo     posts.PostsRepository
+	Reports       report.ReportRepository
+	ContextKey    key.Key
+	HideThreshold int64
+}
+
+func (h *ReportsHandler) log(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context(), h.Logger)
+}
+
+func (h *ReportsHandler) Report(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["POST_ID"]
+	commentID := vars["COMMENT_ID"]
+	if postID == "" {
+		w.WriteHeader(400)
+		return
+	}
+
+	author, ok := r.Context().Value(h.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+
+	js, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+
+	simple := report.SimpleReport{}
+	err = json.Unmarshal(js, &simple)
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+	simple.Reason = strings.TrimSpace(simple.Reason)
+	if simple.Reason == "" {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "reason is required"})
+		return
+	}
+
+	newReport := report.Report{
+		Type:     report.TypePost,
+		PostID:   postID,
+		Reporter: *author,
+		Reason:   simple.Reason,
+		Created:  time.Now(),
+	}
+	if commentID != "" {
+		if _, err = h.PostsRepo.GetComment(r.Context(), postID, commentID); err != nil {
+			h.log(r).Log("Error", err.Error())
+			response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "comment not found"})
+			return
+		}
+		newReport.Type = report.TypeComment
+		newReport.CommentID = commentID
+	} else if _, err = h.PostsRepo.GetPostByID(r.Context(), postID); err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 404, map[string]interface{}{"message": "post not found"})
+		return
+	}
+
+	count, err := h.Reports.AddReport(r.Context(), newReport)
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	if h.HideThreshold > 0 && count >= h.HideThreshold {
+		err = h.PostsRepo.SetHidden(r.Context(), postID, commentID, true)
+		if err != nil {
+			h.log(r).Log("Error", err.Error())
+			response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+			return
+		}
+	}
+
+	response.ServerResponseWriter(w, 201, map[string]interface{}{"message": "reported"})
+}
+
+func (h *ReportsHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.Reports.GetOpen(r.Context())
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 200, report.Queue(reports))
+}
+
+func (h *ReportsHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["POST_ID"]
+	commentID := vars["COMMENT_ID"]
+	if postID == "" {
+		w.WriteHeader(400)
+		return
+	}
+
+	err := h.PostsRepo.SetHidden(r.Context(), postID, commentID, false)
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	err = h.Reports.Resolve(r.Context(), postID, commentID)
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	response.ServerResponseWriter(w, 200, map[string]interface{}{"message": "approved"})
+}
+
+func (h *ReportsHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["POST_ID"]
+	commentID := vars["COMMENT_ID"]
+	if postID == "" {
+		w.WriteHeader(400)
+		return
+	}
+
+	var err error
+	if commentID != "" {
+		_, err = h.PostsRepo.DeleteComment(r.Context(), postID, commentID)
+		if err == nil {
+			err = h.Reports.Resolve(r.Context(), postID, commentID)
+		}
+	} else {
+		err = h.PostsRepo.DeletePost(r.Context(), postID)
+		if err == nil {
+			err = h.Reports.ResolvePost(r.Context(), postID)
+		}
+	}
+	if err != nil {
+		h.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	response.ServerResponseWriter(w, 200, map[string]interface{}{"message": "removed"})
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/comments"
+	handlersTestsUtils "redditclone/pkg/handlers/testing"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/report"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func funcSwitcherReports(funcName string, service interface{}, req *http.Request, w *httptest.ResponseRecorder) {
+	serviceReports := service.(*ReportsHandler)
+	switch funcName {
+	case "Report":
+		serviceReports.Report(w, req)
+	case "Queue":
+		serviceReports.Queue(w, req)
+	case "Approve":
+		serviceReports.Approve(w, req)
+	case "Remove":
+		serviceReports.Remove(w, req)
+	default:
+		return
+	}
+}
+
+func InitiateHandlerReports(rep *posts.MockPostsRepository, reports *report.MockReportRepository) *ReportsHandler {
+	logger := logger.NopLogger{}
+	var key key.Key = "author"
+	service := &ReportsHandler{
+		Logger:        logger,
+		PostsRepo:     rep,
+		Reports:       reports,
+		ContextKey:    key,
+		HideThreshold: 2,
+	}
+	return service
+}
+
+func reportRequest(service *ReportsHandler, body string, vars map[string]string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/post/1/report", bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, vars)
+	author := author.Author{
+		Username: "abc",
+		ID:       "12",
+	}
+	ctx := context.WithValue(req.Context(), service.ContextKey, &author)
+	return req.WithContext(ctx)
+}
+
+func TestReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepo := posts.NewMockPostsRepository(ctrl)
+	reports := report.NewMockReportRepository(ctrl)
+	service := InitiateHandlerReports(postsRepo, reports)
+
+	// empty post id
+	test := handlersTestsUtils.Testing{
+		Req:            httptest.NewRequest("POST", "/api/post//report", nil),
+		W:              httptest.NewRecorder(),
+		ExpectedStatus: 400,
+		Service:        service,
+		FuncName:       "Report",
+		T:              t,
+	}
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// context error
+	test.Req = mux.SetURLVars(httptest.NewRequest("POST", "/api/post/1/report", nil), map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// empty reason
+	test.Req = reportRequest(service, `{"reason": "  "}`, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// no such post
+	test.Req = reportRequest(service, `{"reason": "spam"}`, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 404
+	postsRepo.EXPECT().GetPostByID(test.Req.Context(), "1").Return(posts.Post{}, fmt.Errorf("not found"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	post := posts.Post{ID: "1"}
+
+	// no such comment
+	test.Req = reportRequest(service, `{"reason": "spam"}`, map[string]string{"POST_ID": "1", "COMMENT_ID": "6"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 404
+	postsRepo.EXPECT().GetComment(test.Req.Context(), "1", "6").Return(comments.Comment{}, fmt.Errorf("not found"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// AddReport error
+	test.Req = reportRequest(service, `{"reason": "spam"}`, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	postsRepo.EXPECT().GetPostByID(test.Req.Context(), "1").Return(post, nil)
+	reports.EXPECT().AddReport(test.Req.Context(), gomock.Any()).Return(int64(0), fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// below threshold
+	test.Req = reportRequest(service, `{"reason": "spam"}`, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 201
+	postsRepo.EXPECT().GetPostByID(test.Req.Context(), "1").Return(post, nil)
+	reports.EXPECT().AddReport(test.Req.Context(), gomock.Any()).Return(int64(1), nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// threshold reached, comment gets hidden
+	test.Req = reportRequest(service, `{"reason": "rude"}`, map[string]string{"POST_ID": "1", "COMMENT_ID": "5"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 201
+	postsRepo.EXPECT().GetComment(test.Req.Context(), "1", "5").Return(comments.Comment{ID: "5"}, nil)
+	reports.EXPECT().AddReport(test.Req.Context(), gomock.Any()).DoAndReturn(func(ctx context.Context, r report.Report) (int64, error) {
+		if r.Type != report.TypeComment || r.CommentID != "5" || r.Reporter.ID != "12" || r.Reason != "rude" {
+			t.Errorf("unexpected report %+v", r)
+		}
+		return 2, nil
+	})
+	postsRepo.EXPECT().SetHidden(test.Req.Context(), "1", "5", true).Return(nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// SetHidden error
+	test.Req = reportRequest(service, `{"reason": "spam"}`, map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	postsRepo.EXPECT().GetPostByID(test.Req.Context(), "1").Return(post, nil)
+	reports.EXPECT().AddReport(test.Req.Context(), gomock.Any()).Return(int64(3), nil)
+	postsRepo.EXPECT().SetHidden(test.Req.Context(), "1", "", true).Return(fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+}
+
+func TestReportsQueue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepo := posts.NewMockPostsRepository(ctrl)
+	reports := report.NewMockReportRepository(ctrl)
+	service := InitiateHandlerReports(postsRepo, reports)
+
+	// error
+	test := handlersTestsUtils.Testing{
+		Req:            httptest.NewRequest("GET", "/api/moderation/reports", nil),
+		W:              httptest.NewRecorder(),
+		ExpectedStatus: 500,
+		Service:        service,
+		FuncName:       "Queue",
+		T:              t,
+	}
+	reports.EXPECT().GetOpen(test.Req.Context()).Return(nil, fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// OK
+	open := []report.Report{
+		{ID: "a", Type: report.TypePost, PostID: "1"},
+		{ID: "b", Type: report.TypeComment, PostID: "1", CommentID: "5"},
+		{ID: "c", Type: report.TypePost, PostID: "1"},
+	}
+	test.W = httptest.NewRecorder()
+	test.Expected = handlersTestsUtils.ConvertToJSON(t, []report.QueueItem{
+		{Type: report.TypePost, PostID: "1", Reports: []report.Report{open[0], open[2]}},
+		{Type: report.TypeComment, PostID: "1", CommentID: "5", Reports: []report.Report{open[1]}},
+	})
+	reports.EXPECT().GetOpen(test.Req.Context()).Return(open, nil)
+	handlersTestsUtils.BodyTesting(test, funcSwitcherReports)
+}
+
+func TestReportsModeration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepo := posts.NewMockPostsRepository(ctrl)
+	reports := report.NewMockReportRepository(ctrl)
+	service := InitiateHandlerReports(postsRepo, reports)
+
+	// empty post id
+	test := handlersTestsUtils.Testing{
+		Req:            httptest.NewRequest("POST", "/api/moderation/post//approve", nil),
+		W:              httptest.NewRecorder(),
+		ExpectedStatus: 400,
+		Service:        service,
+		FuncName:       "Approve",
+		T:              t,
+	}
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+	test.FuncName = "Remove"
+	test.W = httptest.NewRecorder()
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// approve error
+	test.FuncName = "Approve"
+	test.Req = mux.SetURLVars(httptest.NewRequest("POST", "/api/moderation/post/1/approve", nil), map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	postsRepo.EXPECT().SetHidden(test.Req.Context(), "1", "", false).Return(fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// resolve error
+	test.W = httptest.NewRecorder()
+	postsRepo.EXPECT().SetHidden(test.Req.Context(), "1", "", false).Return(nil)
+	reports.EXPECT().Resolve(test.Req.Context(), "1", "").Return(fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// approve OK
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	postsRepo.EXPECT().SetHidden(test.Req.Context(), "1", "", false).Return(nil)
+	reports.EXPECT().Resolve(test.Req.Context(), "1", "").Return(nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// remove comment
+	test.FuncName = "Remove"
+	test.Req = mux.SetURLVars(httptest.NewRequest("POST", "/api/moderation/post/1/5/remove", nil), map[string]string{"POST_ID": "1", "COMMENT_ID": "5"})
+	test.W = httptest.NewRecorder()
+	postsRepo.EXPECT().DeleteComment(test.Req.Context(), "1", "5").Return(posts.Post{}, nil)
+	reports.EXPECT().Resolve(test.Req.Context(), "1", "5").Return(nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// remove post error
+	test.Req = mux.SetURLVars(httptest.NewRequest("POST", "/api/moderation/post/1/remove", nil), map[string]string{"POST_ID": "1"})
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 500
+	postsRepo.EXPECT().DeletePost(test.Req.Context(), "1").Return(fmt.Errorf("db"))
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+
+	// remove post OK
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 200
+	postsRepo.EXPECT().DeletePost(test.Req.Context(), "1").Return(nil)
+	reports.EXPECT().ResolvePost(test.Req.Context(), "1").Return(nil)
+	handlersTestsUtils.StatusTesting(test, funcSwitcherReports)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/response"
+	"redditclone/pkg/token"
+	"redditclone/pkg/totp"
+	"redditclone/pkg/user"
+)
+
+const (
+	totpIssuer = "redditclone"
+	mfaTTL     = 5 * time.Minute
+)
+
+// errTOTPLocked is returned by checkSecondFactor during a lockout.
+var errTOTPLocked = errors.New("too many attempts, try again later")
+
+type secondFactorRequest struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// mfaState changes with every accepted code, TOTP or recovery, so a pre-auth
+// token is good for one login only.
+func mfaState(t user.TOTP) string {
+	return t.Secret + ":" + strconv.FormatInt(t.Accepted, 10)
+}
+
+// loginTokens starts the session for a user who passed the first factor.
+// When 2FA is enabled there is no session yet, only a pre-auth token to
+// exchange at /api/login/2fa.
+func (u *UserHandler) loginTokens(ctx context.Context, found user.User) (sessionToken string, mfaToken string, err error) {
+	second, err := u.UserRepo.GetTOTP(ctx, found.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if second.Enabled {
+		return "", u.Tokens.Sign(token.PurposeMFA, found.ID, mfaState(second), mfaTTL), nil
+	}
+	sessionToken, err = u.startSession(ctx, found)
+	return sessionToken, "", err
+}
+
+// finishLogin answers a login request with the session token, or with the
+// pre-auth token when 2FA is enabled.
+func (u *UserHandler) finishLogin(w http.ResponseWriter, r *http.Request, found user.User) {
+	tokenString, mfaToken, err := u.loginTokens(r.Context(), found)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	if mfaToken != "" {
+		response.ServerResponseWriter(w, 202, map[string]interface{}{
+			"message":  "two-factor code required",
+			"mfaToken": mfaToken,
+		})
+		return
+	}
+	response.ServerResponseWriter(w, 201, map[string]interface{}{"token": tokenString})
+}
+
+// checkSecondFactor accepts a current TOTP code that was not used before or
+// an unused recovery code. Every attempt is counted as a failure before the
+// code is looked at and an accepted code clears the count, so parallel
+// guesses cannot get past the lockout.
+func (u *UserHandler) checkSecondFactor(ctx context.Context, id string, second user.TOTP, req secondFactorRequest) (bool, error) {
+	allowed, err := u.UserRepo.TakeTOTPAttempt(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		return false, errTOTPLocked
+	}
+	if req.RecoveryCode != "" {
+		return u.UserRepo.UseRecoveryCode(ctx, id, totp.HashRecoveryCode(req.RecoveryCode))
+	}
+	if step, valid := totp.Validate(second.Secret, req.Code, time.Now()); valid {
+		return u.UserRepo.UseTOTPStep(ctx, id, step)
+	}
+	return false, nil
+}
+
+func (u *UserHandler) LogInTwoFactor(w http.ResponseWriter, r *http.Request) {
+	req := secondFactorRequest{}
+	if err := readJSON(r, &req); err != nil {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad request"})
+		return
+	}
+	userID, err := u.Tokens.Parse(req.MFAToken, token.PurposeMFA)
+	if tokenError(w, err) {
+		return
+	}
+	second, err := u.UserRepo.GetTOTP(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = token.ErrInvalid
+	}
+	if err == nil {
+		err = u.Tokens.Verify(req.MFAToken, token.PurposeMFA, mfaState(second))
+	}
+	if tokenError(w, err) {
+		return
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	ok, err := u.checkSecondFactor(r.Context(), userID, second, req)
+	if errors.Is(err, errTOTPLocked) {
+		response.ServerResponseWriter(w, 429, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	if !ok {
+		response.ServerResponseWriter(w, 401, map[string]interface{}{"message": "bad code"})
+		return
+	}
+
+	found, err := u.UserRepo.GetUser(r.Context(), userID)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	tokenString, err := u.startSession(r.Context(), found)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	response.ServerResponseWriter(w, 201, map[string]interface{}{"token": tokenString})
+}
+
+// EnrollTwoFactor stores a new secret. It is not enforced until a code from
+// it is sent to ConfirmTwoFactor.
+func (u *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(u.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+	second, err := u.UserRepo.GetTOTP(r.Context(), author.ID)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	if second.Enabled {
+		response.ServerResponseWriter(w, 409, map[string]interface{}{"message": "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err == nil {
+		err = u.UserRepo.SetTOTPSecret(r.Context(), author.ID, secret)
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	response.ServerResponseWriter(w, 200, map[string]interface{}{
+		"secret": secret,
+		"uri":    totp.ProvisioningURI(totpIssuer, author.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor enables 2FA and ends every other session, since those
+// were opened with the password alone.
+func (u *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(u.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+	req := secondFactorRequest{}
+	if err := readJSON(r, &req); err != nil {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad request"})
+		return
+	}
+	second, err := u.UserRepo.GetTOTP(r.Context(), author.ID)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	if second.Enabled {
+		response.ServerResponseWriter(w, 409, map[string]interface{}{"message": "two-factor authentication is already enabled"})
+		return
+	}
+	if second.Secret == "" {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "enrollment not started"})
+		return
+	}
+	step, valid := totp.Validate(second.Secret, req.Code, time.Now())
+	if !valid {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad code"})
+		return
+	}
+
+	codes, err := totp.NewRecoveryCodes()
+	if err == nil {
+		hashes := make([]string, 0, len(codes))
+		for _, code := range codes {
+			hashes = append(hashes, totp.HashRecoveryCode(code))
+		}
+		err = u.UserRepo.EnableTOTP(r.Context(), author.ID, step, hashes)
+	}
+	if err == nil {
+		err = u.Session.DeleteUserSessions(r.Context(), author.ID)
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+
+	tokenString, err := u.startSession(r.Context(), user.User{ID: author.ID, Username: author.Username})
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	response.ServerResponseWriter(w, 200, map[string]interface{}{
+		"token":         tokenString,
+		"recoveryCodes": codes,
+	})
+}
+
+func (u *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	author, ok := r.Context().Value(u.ContextKey).(*author.Author)
+	if !ok {
+		w.WriteHeader(500)
+		return
+	}
+	req := secondFactorRequest{}
+	if err := readJSON(r, &req); err != nil {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad request"})
+		return
+	}
+	second, err := u.UserRepo.GetTOTP(r.Context(), author.ID)
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	if !second.Enabled {
+		response.ServerResponseWriter(w, 409, map[string]interface{}{"message": "two-factor authentication is not enabled"})
+		return
+	}
+
+	ok, err = u.checkSecondFactor(r.Context(), author.ID, second, req)
+	if errors.Is(err, errTOTPLocked) {
+		response.ServerResponseWriter(w, 429, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	if err == nil && ok {
+		err = u.UserRepo.DisableTOTP(r.Context(), author.ID)
+	}
+	if err != nil {
+		u.log(r).Log("Error", err.Error())
+		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
+		return
+	}
+	if !ok {
+		response.ServerResponseWriter(w, 401, map[string]interface{}{"message": "bad code"})
+		return
+	}
+	response.ServerResponseWriter(w, 200, map[string]interface{}{"message": "two-factor authentication disabled"})
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"redditclone/pkg/session"
+	"redditclone/pkg/token"
+	"redditclone/pkg/totp"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func currentCode(t *testing.T) (string, int64) {
+	step := totp.Step(time.Now())
+	code, err := totp.Code(testSecret, step)
+	assert.Nil(t, err)
+	return code, step
+}
+
+func TestEnrollTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	service := InitiateHandlerRecovery(users, sess, nil)
+
+	// already enabled
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{Secret: testSecret, Enabled: true}, nil)
+	w := httptest.NewRecorder()
+	service.EnrollTwoFactor(w, recoveryRequest(service, ""))
+	assert.Equal(t, 409, w.Code)
+
+	// new secret
+	var secret string
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{}, nil)
+	users.EXPECT().SetTOTPSecret(gomock.Any(), "12", gomock.Any()).DoAndReturn(func(_, _ interface{}, s string) error {
+		secret = s
+		return nil
+	})
+	w = httptest.NewRecorder()
+	service.EnrollTwoFactor(w, recoveryRequest(service, ""))
+	assert.Equal(t, 200, w.Code)
+	resp := map[string]string{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, secret, resp["secret"])
+	assert.Equal(t, totp.ProvisioningURI("redditclone", "abc", secret), resp["uri"])
+}
+
+func TestConfirmTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	service := InitiateHandlerRecovery(users, sess, nil)
+	code, step := currentCode(t)
+
+	// enrollment not started
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{}, nil)
+	w := httptest.NewRecorder()
+	service.ConfirmTwoFactor(w, recoveryRequest(service, `{"code":"`+code+`"}`))
+	assert.Equal(t, 400, w.Code)
+
+	// wrong code
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{Secret: testSecret}, nil)
+	w = httptest.NewRecorder()
+	service.ConfirmTwoFactor(w, recoveryRequest(service, `{"code":"000000x"}`))
+	assert.Equal(t, 400, w.Code)
+
+	// enabled, the old sessions end and the codes are stored hashed
+	var hashes []string
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{Secret: testSecret}, nil)
+	users.EXPECT().EnableTOTP(gomock.Any(), "12", step, gomock.Any()).DoAndReturn(func(_, _, _ interface{}, h []string) error {
+		hashes = h
+		return nil
+	})
+	sess.EXPECT().DeleteUserSessions(gomock.Any(), "12").Return(nil)
+	sess.EXPECT().AddNewSess(gomock.Any(), "12", gomock.Any(), gomock.Any()).Return(nil)
+	sess.EXPECT().GetExp(gomock.Any(), "12", gomock.Any()).Return(time.Now().Add(time.Hour).Unix())
+	sess.EXPECT().GetKey().Return([]byte("secret"))
+	w = httptest.NewRecorder()
+	service.ConfirmTwoFactor(w, recoveryRequest(service, `{"code":"`+code+`"}`))
+	assert.Equal(t, 200, w.Code)
+	resp := struct {
+		Token         string   `json:"token"`
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Token)
+	if assert.Len(t, resp.RecoveryCodes, len(hashes)) {
+		for i, code := range resp.RecoveryCodes {
+			assert.Equal(t, totp.HashRecoveryCode(code), hashes[i])
+		}
+	}
+}
+
+func TestLogInTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	service := InitiateHandlerRecovery(users, sess, nil)
+	enabled := user.TOTP{Secret: testSecret, Enabled: true, LastStep: 10}
+	code, step := currentCode(t)
+
+	// the password step hands out a pre-auth token
+	users.EXPECT().Authenticate(gomock.Any(), gomock.Any()).Return("12", nil)
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	w := httptest.NewRecorder()
+	service.LogIn(w, httptest.NewRequest("POST", "/api/login", bytes.NewBufferString(`{"username":"abc","password":"pass"}`)))
+	assert.Equal(t, 202, w.Code)
+	challenge := map[string]string{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	mfaToken := challenge["mfaToken"]
+	assert.NotEmpty(t, mfaToken)
+	assert.NotContains(t, w.Body.String(), `"token"`)
+
+	login := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		service.LogInTwoFactor(w, httptest.NewRequest("POST", "/api/login/2fa", bytes.NewBufferString(body)))
+		return w
+	}
+
+	// not a pre-auth token
+	reset := service.Tokens.Sign(token.PurposeReset, "12", "0", time.Hour)
+	assert.Equal(t, 400, login(`{"mfaToken":"`+reset+`","code":"`+code+`"}`).Code)
+
+	// wrong code, the attempt was counted before the check
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(true, nil)
+	assert.Equal(t, 401, login(`{"mfaToken":"`+mfaToken+`","code":"abcdef"}`).Code)
+
+	// locked out, the code is not even looked at
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(false, nil)
+	assert.Equal(t, 429, login(`{"mfaToken":"`+mfaToken+`","code":"`+code+`"}`).Code)
+
+	// the code was already used
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(true, nil)
+	users.EXPECT().UseTOTPStep(gomock.Any(), "12", step).Return(false, nil)
+	assert.Equal(t, 401, login(`{"mfaToken":"`+mfaToken+`","code":"`+code+`"}`).Code)
+
+	// recovery code
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(true, nil)
+	users.EXPECT().UseRecoveryCode(gomock.Any(), "12", totp.HashRecoveryCode("abcde-fghij")).Return(true, nil)
+	users.EXPECT().GetUser(gomock.Any(), "12").Return(user.User{ID: "12", Username: "abc"}, nil)
+	sess.EXPECT().AddNewSess(gomock.Any(), "12", gomock.Any(), gomock.Any()).Return(nil)
+	sess.EXPECT().GetExp(gomock.Any(), "12", gomock.Any()).Return(time.Now().Add(time.Hour).Unix())
+	sess.EXPECT().GetKey().Return([]byte("secret"))
+	w = login(`{"mfaToken":"` + mfaToken + `","recoveryCode":"ABCDE-FGHIJ"}`)
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"token"`)
+
+	// the recovery login counted as accepted, which retires the pre-auth token
+	used := enabled
+	used.Accepted++
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(used, nil)
+	assert.Equal(t, 400, login(`{"mfaToken":"`+mfaToken+`","recoveryCode":"KLMNO-PQRST"}`).Code)
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(used, nil)
+	assert.Equal(t, 400, login(`{"mfaToken":"`+mfaToken+`","code":"`+code+`"}`).Code)
+
+	// storage error
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{}, fmt.Errorf("db error"))
+	assert.Equal(t, 500, login(`{"mfaToken":"`+mfaToken+`","code":"`+code+`"}`).Code)
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := user.NewMockUserRepo(ctrl)
+	sess := session.NewMockSessionManager(ctrl)
+	service := InitiateHandlerRecovery(users, sess, nil)
+	enabled := user.TOTP{Secret: testSecret, Enabled: true}
+	code, step := currentCode(t)
+
+	// not enabled
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(user.TOTP{}, nil)
+	w := httptest.NewRecorder()
+	service.DisableTwoFactor(w, recoveryRequest(service, `{"code":"`+code+`"}`))
+	assert.Equal(t, 409, w.Code)
+
+	// wrong code
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(true, nil)
+	w = httptest.NewRecorder()
+	service.DisableTwoFactor(w, recoveryRequest(service, `{"code":"123"}`))
+	assert.Equal(t, 401, w.Code)
+
+	// locked out
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(false, nil)
+	w = httptest.NewRecorder()
+	service.DisableTwoFactor(w, recoveryRequest(service, `{"code":"`+code+`"}`))
+	assert.Equal(t, 429, w.Code)
+
+	// OK
+	users.EXPECT().GetTOTP(gomock.Any(), "12").Return(enabled, nil)
+	users.EXPECT().TakeTOTPAttempt(gomock.Any(), "12").Return(true, nil)
+	users.EXPECT().UseTOTPStep(gomock.Any(), "12", step).Return(true, nil)
+	users.EXPECT().DisableTOTP(gomock.Any(), "12").Return(nil)
+	w = httptest.NewRecorder()
+	service.DisableTwoFactor(w, recoveryRequest(service, `{"code":"`+code+`"}`))
+	assert.Equal(t, 200, w.Code)
+}
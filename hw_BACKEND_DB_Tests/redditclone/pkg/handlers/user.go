@@ -9,9 +9,12 @@ import (
 
 	"redditclone/pkg/key"
 	"redditclone/pkg/logger"
+	"redditclone/pkg/mail"
+	"redditclone/pkg/oidc"
 	"redditclone/pkg/posts"
 	"redditclone/pkg/response"
 	"redditclone/pkg/session"
+	"redditclone/pkg/token"
 	"redditclone/pkg/user"
 
 	"github.com/dgrijalva/jwt-go"
@@ -24,6 +27,14 @@ type UserHandler struct {
 	Session    session.SessionManager
 	PostsRepo  posts.PostsRepository
 	ContextKey key.Key
+	Mailer     mail.Mailer
+	Tokens     *token.Signer
+	PublicURL  string
+	OIDC       *oidc.Client
+}
+
+func (u *UserHandler) log(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context(), u.Logger)
 }
 
 func (u *UserHandler) makeToken(ctx context.Context, user user.User, iat int64) (string, error) {
@@ -42,6 +53,17 @@ func (u *UserHandler) makeToken(ctx context.Context, user user.User, iat int64)
 	return tokenString, nil
 }
 
+// startSession records a new session and returns its JWT, every login
+// method ends here.
+func (u *UserHandler) startSession(ctx context.Context, user user.User) (string, error) {
+	iat := time.Now().Unix()
+	err := u.Session.AddNewSess(ctx, user.ID, time.Now().Add(120*time.Hour).Unix(), iat)
+	if err != nil {
+		return "", err
+	}
+	return u.makeToken(ctx, user, iat)
+}
+
 func (u *UserHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -55,25 +77,33 @@ func (u *UserHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.Email != "" && !validEmail(user.Email) {
+		response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "bad email"})
+		return
+	}
+
 	user.ID, err = u.UserRepo.AddNewUser(r.Context(), user)
+	if emailTaken(err) {
+		response.ServerResponseWriter(w, 409, map[string]interface{}{"message": err.Error()})
+		return
+	}
 	if err != nil {
-		u.Logger.Log("Error", err.Error())
+		u.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": "user already exists"})
 		return
 	}
 
-	iat := time.Now().Unix()
-	err = u.Session.AddNewSess(r.Context(), user.ID, time.Now().Add(120*time.Hour).Unix(), iat)
+	tokenString, err := u.startSession(r.Context(), user)
 	if err != nil {
-		u.Logger.Log("Error", err.Error())
+		u.log(r).Log("Error", err.Error())
 		w.WriteHeader(500)
 		return
 	}
 
-	tokenString, err := u.makeToken(r.Context(), user, iat)
-	if err != nil {
-		w.WriteHeader(500)
-		return
+	if user.Email != "" {
+		if err = u.sendVerification(r.Context(), user); err != nil {
+			u.log(r).Log("Error", err.Error())
+		}
 	}
 
 	response.ServerResponseWriter(w, 201, map[string]interface{}{"token": tokenString})
@@ -95,25 +125,12 @@ func (u *UserHandler) LogIn(w http.ResponseWriter, r *http.Request) {
 
 	user.ID, err = u.UserRepo.Authenticate(r.Context(), user)
 	if err != nil {
-		u.Logger.Log("Error", err.Error())
+		u.log(r).Log("Error", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": "password or login not right"})
 		return
 	}
 
-	iat := time.Now().Unix()
-	err = u.Session.AddNewSess(r.Context(), user.ID, time.Now().Add(120*time.Hour).Unix(), iat)
-	if err != nil {
-		w.WriteHeader(500)
-		return
-	}
-
-	tokenString, err := u.makeToken(r.Context(), user, iat)
-	if err != nil {
-		w.WriteHeader(500)
-		return
-	}
-
-	response.ServerResponseWriter(w, 201, map[string]interface{}{"token": tokenString})
+	u.finishLogin(w, r, user)
 }
 
 func (u *UserHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
@@ -125,7 +142,7 @@ func (u *UserHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 	}
 	posts, err := u.PostsRepo.GetByUserLogin(r.Context(), userLogin)
 	if err != nil {
-		u.Logger.Log("Info", err.Error())
+		u.log(r).Log("Info", err.Error())
 		response.ServerResponseWriter(w, 500, map[string]interface{}{"message": dbError})
 		return
 	}
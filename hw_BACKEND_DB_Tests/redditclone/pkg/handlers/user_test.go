@@ -18,8 +18,6 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
 func funcSwitcherUser(funcName string, service interface{}, req *http.Request, w *httptest.ResponseRecorder) {
@@ -37,18 +35,7 @@ func funcSwitcherUser(funcName string, service interface{}, req *http.Request, w
 }
 
 func InitiateHandlerUser(rep *posts.MockPostsRepository, users *user.MockUserRepo, sess *session.MockSessionManager) *UserHandler {
-	zapConfig := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapcore.InfoLevel),
-		Development:      true,
-		Encoding:         "console",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"Nop"},
-		ErrorOutputPaths: []string{"Nop"},
-	}
-	logger, err := logger.NewCustomLogger(zapConfig)
-	if err != nil {
-		panic(err.Error())
-	}
+	logger := logger.NopLogger{}
 	var key key.Key = "author"
 	service := &UserHandler{
 		Logger:     logger,
@@ -125,6 +112,20 @@ func TestSignIn(t *testing.T) {
 	test.W = httptest.NewRecorder()
 	handlerstestsutils.StatusTesting(test, funcSwitcherUser)
 
+	// bad email
+	test.Req = httptest.NewRequest("GET", "/api/user/register", bytes.NewBufferString(`{"username": "abc", "password": "sad", "email": "abc"}`))
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 400
+	handlerstestsutils.StatusTesting(test, funcSwitcherUser)
+
+	// email taken
+	test.Req = httptest.NewRequest("GET", "/api/user/register", bytes.NewBufferString(`{"username": "abc", "password": "sad", "email": "abc@example.com"}`))
+	test.W = httptest.NewRecorder()
+	test.ExpectedStatus = 409
+	users.EXPECT().AddNewUser(test.Req.Context(), gomock.Any()).Return("", user.ErrEmailTaken)
+	handlerstestsutils.StatusTesting(test, funcSwitcherUser)
+	test.ExpectedStatus = 500
+
 	// addnew user error
 	user := user.User{
 		Username: "abc",
@@ -196,6 +197,9 @@ func TestLogIn(t *testing.T) {
 	test.W = httptest.NewRecorder()
 	handlerstestsutils.StatusTesting(test, funcSwitcherUser)
 
+	// no second factor for the logins below
+	users.EXPECT().GetTOTP(gomock.Any(), "1").Return(user.TOTP{}, nil).Times(3)
+
 	// authenticate user error
 	user := user.User{
 		Username: "abc",
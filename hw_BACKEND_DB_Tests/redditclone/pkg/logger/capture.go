@@ -0,0 +1,66 @@
+package logger
+
+import "sync"
+
+type Entry struct {
+	Level   string
+	Message string
+	Fields  map[string]interface{}
+}
+
+type captureSink struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// CaptureLogger keeps every entry in memory so tests can assert on what was
+// logged. Loggers derived with WithFields/WithError share the same entries.
+type CaptureLogger struct {
+	fields map[string]interface{}
+	sink   *captureSink
+}
+
+func NewCaptureLogger() *CaptureLogger {
+	return &CaptureLogger{
+		fields: map[string]interface{}{},
+		sink:   &captureSink{},
+	}
+}
+
+func (c *CaptureLogger) Entries() []Entry {
+	c.sink.mu.Lock()
+	defer c.sink.mu.Unlock()
+	entries := make([]Entry, len(c.sink.entries))
+	copy(entries, c.sink.entries)
+	return entries
+}
+
+func (c *CaptureLogger) LogW(level string, mesg string, keyValues map[string]interface{}) {
+	fields := c.merge(keyValues)
+	c.sink.mu.Lock()
+	defer c.sink.mu.Unlock()
+	c.sink.entries = append(c.sink.entries, Entry{Level: level, Message: mesg, Fields: fields})
+}
+
+func (c *CaptureLogger) Log(level string, mesg string) {
+	c.LogW(level, mesg, nil)
+}
+
+func (c *CaptureLogger) WithFields(keyValues map[string]interface{}) Logger {
+	return &CaptureLogger{fields: c.merge(keyValues), sink: c.sink}
+}
+
+func (c *CaptureLogger) WithError(err error) Logger {
+	return c.WithFields(map[string]interface{}{"error": err.Error()})
+}
+
+func (c *CaptureLogger) merge(keyValues map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(c.fields)+len(keyValues))
+	for k, v := range c.fields {
+		fields[k] = v
+	}
+	for k, v := range keyValues {
+		fields[k] = v
+	}
+	return fields
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	EncodingConsole = "console"
+	EncodingJSON    = "json"
+)
+
+type Config struct {
+	Level       string
+	Encoding    string
+	Development bool
+	Sampling    bool
+	File        string
+	MaxSizeMB   int
+	MaxBackups  int
+	MaxAgeDays  int
+}
+
+func New(config Config) (*CustomLogger, error) {
+	level := zap.NewAtomicLevelAt(convertLevel(config.Level))
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	switch config.Encoding {
+	case EncodingJSON:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case EncodingConsole, "":
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", config.Encoding)
+	}
+
+	var output zapcore.WriteSyncer = zapcore.Lock(os.Stdout)
+	if config.File != "" {
+		output = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSizeMB,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAgeDays,
+		})
+	}
+
+	core := zapcore.NewCore(encoder, output, level)
+	if config.Sampling {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
+	}
+
+	options := []zap.Option{zap.ErrorOutput(zapcore.Lock(os.Stderr))}
+	if config.Development {
+		options = append(options, zap.Development())
+	}
+	return &CustomLogger{zapLog: zap.New(core, options...), level: level}, nil
+}
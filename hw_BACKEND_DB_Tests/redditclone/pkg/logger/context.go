@@ -0,0 +1,16 @@
+package logger
+
+import "context"
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+	return fallback
+}
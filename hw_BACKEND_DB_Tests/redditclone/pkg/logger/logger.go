@@ -9,6 +9,7 @@ import (
 
 type CustomLogger struct {
 	zapLog *zap.Logger
+	level  zap.AtomicLevel
 }
 
 func NewCustomLogger(config zap.Config) (*CustomLogger, error) {
@@ -16,7 +17,15 @@ func NewCustomLogger(config zap.Config) (*CustomLogger, error) {
 	if err != nil {
 		return nil, fmt.Errorf("can't create logger")
 	}
-	return &CustomLogger{zapLog: logger}, nil
+	return &CustomLogger{zapLog: logger, level: config.Level}, nil
+}
+
+func (cl *CustomLogger) Level() zap.AtomicLevel {
+	return cl.level
+}
+
+func (cl *CustomLogger) Sync() error {
+	return cl.zapLog.Sync()
 }
 
 func (cl *CustomLogger) LogW(level string, msg string, keyValues map[string]interface{}) {
@@ -41,11 +50,11 @@ func (cl *CustomLogger) WithFields(keyValues map[string]interface{}) Logger {
 		zapFields[i] = zap.Any(key, value)
 		i++
 	}
-	return &CustomLogger{zapLog: cl.zapLog.With(zapFields...)}
+	return &CustomLogger{zapLog: cl.zapLog.With(zapFields...), level: cl.level}
 }
 
 func (cl *CustomLogger) WithError(err error) Logger {
-	return &CustomLogger{zapLog: cl.zapLog.With(zap.Error(err))}
+	return &CustomLogger{zapLog: cl.zapLog.With(zap.Error(err)), level: cl.level}
 }
 
 func convertLevel(level string) zapcore.Level {
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestJSONFileLogger(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	logger, err := New(Config{
+		Level:    "Info",
+		Encoding: EncodingJSON,
+		File:     file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Log("Debug", "hidden")
+	logger.WithFields(map[string]interface{}{"request_id": "abc"}).Log("Info", "visible")
+	assert.Nil(t, logger.Sync())
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 1) {
+		entry := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "visible", entry["msg"])
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "abc", entry["request_id"])
+	}
+
+	_, err = New(Config{Encoding: "xml"})
+	assert.NotNil(t, err)
+}
+
+func TestRuntimeLevel(t *testing.T) {
+	logger, err := New(Config{Level: "Error", File: filepath.Join(t.TempDir(), "app.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := logger.WithFields(map[string]interface{}{"a": 1}).(*CustomLogger)
+	assert.Equal(t, zapcore.ErrorLevel, child.Level().Level())
+
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/loglevel", bytes.NewBufferString(`{"level":"debug"}`))
+	w := httptest.NewRecorder()
+	logger.Level().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, zapcore.DebugLevel, logger.Level().Level())
+	assert.Equal(t, zapcore.DebugLevel, child.Level().Level())
+}
+
+func TestCaptureLogger(t *testing.T) {
+	capture := NewCaptureLogger()
+	var logger Logger = capture
+
+	logger.Log("Info", "plain")
+	logger.WithFields(map[string]interface{}{"request_id": "1"}).
+		WithError(fmt.Errorf("boom")).
+		LogW("Error", "failed", map[string]interface{}{"post": "2"})
+
+	assert.Equal(t, []Entry{
+		{Level: "Info", Message: "plain", Fields: map[string]interface{}{}},
+		{Level: "Error", Message: "failed", Fields: map[string]interface{}{
+			"request_id": "1",
+			"error":      "boom",
+			"post":       "2",
+		}},
+	}, capture.Entries())
+
+	var nop Logger = NopLogger{}
+	nop.WithError(fmt.Errorf("boom")).Log("Error", "ignored")
+}
@@ -0,0 +1,15 @@
+package logger
+
+type NopLogger struct{}
+
+func (NopLogger) LogW(level string, mesg string, keyValues map[string]interface{}) {}
+
+func (NopLogger) Log(level string, mesg string) {}
+
+func (n NopLogger) WithFields(keyValues map[string]interface{}) Logger {
+	return n
+}
+
+func (n NopLogger) WithError(err error) Logger {
+	return n
+}
@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+//go:generate mockgen -source mail.go -destination mail_mock.go -package mail Mailer
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func format(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+func validHeader(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("mail header contains a line break")
+		}
+	}
+	return nil
+}
+
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPMailer sends through addr (host:port). PLAIN auth is used when a
+// username is set, net/smtp refuses it without TLS except on localhost.
+func NewSMTPMailer(addr string, from string, username string, password string) *SMTPMailer {
+	m := &SMTPMailer{
+		Addr: addr,
+		From: from,
+		send: smtp.SendMail,
+	}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := validHeader(msg.To, msg.Subject); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.send(m.Addr, m.Auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// WriterMailer writes messages instead of sending them, for local runs.
+type WriterMailer struct {
+	mu   sync.Mutex
+	From string
+	W    io.Writer
+}
+
+func NewWriterMailer(w io.Writer, from string) *WriterMailer {
+	return &WriterMailer{W: w, From: from}
+}
+
+func NewFileMailer(path string, from string) (*WriterMailer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterMailer(file, from), nil
+}
+
+func (m *WriterMailer) Send(ctx context.Context, msg Message) error {
+	if err := validHeader(msg.To, msg.Subject); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.W.Write(append(format(m.From, msg), "\r\n\r\n"...))
+	return err
+}
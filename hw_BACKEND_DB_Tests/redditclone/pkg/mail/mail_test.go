@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewWriterMailer(&buf, "noreply@example.com")
+	err := mailer.Send(context.Background(), Message{To: "a@example.com", Subject: "Hello", Body: "line one\nline two"})
+	assert.Nil(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "From: noreply@example.com\r\n")
+	assert.Contains(t, out, "To: a@example.com\r\n")
+	assert.Contains(t, out, "Subject: Hello\r\n")
+	assert.Contains(t, out, "\r\n\r\nline one\r\nline two")
+
+	err = mailer.Send(context.Background(), Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "Hello"})
+	assert.NotNil(t, err)
+}
+
+func TestSMTPMailer(t *testing.T) {
+	mailer := NewSMTPMailer("smtp.example.com:587", "noreply@example.com", "user", "pass")
+	var gotAddr string
+	var gotTo []string
+	var gotMsg []byte
+	mailer.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, msg
+		assert.NotNil(t, a)
+		assert.Equal(t, "noreply@example.com", from)
+		return nil
+	}
+	err := mailer.Send(context.Background(), Message{To: "a@example.com", Subject: "Héllo", Body: "hi"})
+	assert.Nil(t, err)
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.Equal(t, []string{"a@example.com"}, gotTo)
+	assert.True(t, strings.Contains(string(gotMsg), "Subject: =?utf-8?q?H=C3=A9llo?=\r\n"), string(gotMsg))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, mailer.Send(ctx, Message{To: "a@example.com"}))
+}
@@ -0,0 +1,164 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	DefaultMaxBytes  = 5 << 20
+	DefaultMaxPixels = 40_000_000
+	DefaultThumbSize = 320
+)
+
+var (
+	ErrTooLarge        = errors.New("image is too large")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrUnsupportedType = errors.New("unsupported image type")
+)
+
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type Image struct {
+	URL          string `json:"url" bson:"url"`
+	ThumbnailURL string `json:"thumbnailUrl" bson:"thumbnailUrl"`
+	ContentType  string `json:"contentType" bson:"contentType"`
+	Width        int    `json:"width" bson:"width"`
+	Height       int    `json:"height" bson:"height"`
+	Size         int64  `json:"size" bson:"size"`
+}
+
+type Processed struct {
+	Image         Image
+	Thumbnail     []byte
+	ThumbnailType string
+	ThumbnailExt  string
+	Ext           string
+}
+
+type Processor struct {
+	MaxBytes  int64
+	MaxPixels int
+	ThumbSize int
+}
+
+func NewProcessor() *Processor {
+	return &Processor{
+		MaxBytes:  DefaultMaxBytes,
+		MaxPixels: DefaultMaxPixels,
+		ThumbSize: DefaultThumbSize,
+	}
+}
+
+// Process trusts only the sniffed content type and checks the dimensions
+// from the header before decoding, so huge images are rejected cheaply.
+func (p *Processor) Process(data []byte) (Processed, error) {
+	if int64(len(data)) > p.MaxBytes {
+		return Processed{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return Processed{}, ErrUnsupportedType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+	if config.Width*config.Height > p.MaxPixels {
+		return Processed{}, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	processed := Processed{
+		Image: Image{
+			ContentType: contentType,
+			Width:       config.Width,
+			Height:      config.Height,
+			Size:        int64(len(data)),
+		},
+		Ext: ext(contentType),
+	}
+	thumb := Thumbnail(img, p.ThumbSize)
+	buf := &bytes.Buffer{}
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(buf, thumb, &jpeg.Options{Quality: 85})
+		processed.ThumbnailType, processed.ThumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(buf, thumb)
+		processed.ThumbnailType, processed.ThumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return Processed{}, err
+	}
+	processed.Thumbnail = buf.Bytes()
+	return processed, nil
+}
+
+func ext(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// Thumbnail scales img down so its longest side is at most size, averaging
+// the source pixels that fall into each target pixel.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+		return dst
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, (ty+1)*h/th
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, (tx+1)*w/tw
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(tx, ty, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,55 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngBytes(t *testing.T, w int, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	p := NewProcessor()
+	p.ThumbSize = 50
+
+	processed, err := p.Process(pngBytes(t, 200, 100))
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", processed.Image.ContentType)
+	assert.Equal(t, 200, processed.Image.Width)
+	assert.Equal(t, ".png", processed.Ext)
+
+	thumb, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+	assert.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, 50, 25), thumb.Bounds())
+	r, _, _, _ := thumb.At(10, 10).RGBA()
+	assert.Equal(t, uint32(200), r>>8)
+
+	// sniffed, not trusted from the client
+	_, err = p.Process([]byte("<html><script>alert(1)</script></html>"))
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	p.MaxBytes = 10
+	_, err = p.Process(pngBytes(t, 10, 10))
+	assert.Equal(t, ErrTooLarge, err)
+
+	p.MaxBytes = DefaultMaxBytes
+	p.MaxPixels = 100
+	_, err = p.Process(pngBytes(t, 20, 20))
+	assert.Equal(t, ErrTooManyPixels, err)
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"redditclone/pkg/response"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Metrics struct {
+	Registry  *prometheus.Registry
+	Requests  *prometheus.CounterVec
+	Latency   *prometheus.HistogramVec
+	DBLatency *prometheus.HistogramVec
+	DBErrors  *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "redditclone",
+			Name:      "http_requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"route", "method", "status"}),
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "redditclone",
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		DBLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "redditclone",
+			Name:      "db_call_duration_seconds",
+			Help:      "Repository call latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "operation"}),
+		DBErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "redditclone",
+			Name:      "db_call_errors_total",
+			Help:      "Number of failed repository calls.",
+		}, []string{"repository", "operation"}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.Requests,
+		m.Latency,
+		m.DBLatency,
+		m.DBErrors,
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Middleware is meant for mux.Router.Use, so the matched route template
+// is known and raw paths with ids do not blow up label cardinality.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		start := time.Now()
+		recorder := response.NewStatusRecorder(w)
+		next.ServeHTTP(recorder, r)
+		status := strconv.Itoa(recorder.Status)
+		m.Requests.WithLabelValues(route, r.Method, status).Inc()
+		m.Latency.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *Metrics) observe(repository string, operation string, start time.Time, err error) {
+	m.DBLatency.WithLabelValues(repository, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.DBErrors.WithLabelValues(repository, operation).Inc()
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := NewMetrics()
+	r := mux.NewRouter()
+	r.Use(m.Middleware)
+	r.HandleFunc("/api/post/{POST_ID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(201)
+	}).Methods("GET")
+	r.HandleFunc("/api/post/{POST_ID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}).Methods("DELETE")
+	r.Handle("/metrics", m.Handler())
+
+	for _, id := range []string{"1", "2", "3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/post/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/api/post/1", nil))
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.Requests.WithLabelValues("/api/post/{POST_ID}", "GET", "201")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Requests.WithLabelValues("/api/post/{POST_ID}", "DELETE", "500")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.Latency))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(w.Result().Body)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(body), `redditclone_http_requests_total{method="GET",route="/api/post/{POST_ID}",status="201"} 3`))
+}
+
+func TestRepositories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMetrics()
+	ctx := context.Background()
+
+	postsMock := posts.NewMockPostsRepository(ctrl)
+	postsRepo := NewPostsRepository(postsMock, m)
+	postsMock.EXPECT().GetPostByID(ctx, "1").Return(posts.Post{ID: "1"}, nil)
+	postsMock.EXPECT().GetPostByID(ctx, "2").Return(posts.Post{}, fmt.Errorf("not found"))
+	post, err := postsRepo.GetPostByID(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", post.ID)
+	_, err = postsRepo.GetPostByID(ctx, "2")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DBErrors.WithLabelValues("posts", "GetPostByID")))
+
+	usersMock := user.NewMockUserRepo(ctrl)
+	users := NewUserRepo(usersMock, m)
+	usersMock.EXPECT().IsUser(ctx, "abc", "1").Return(true, nil)
+	ok, err := users.IsUser(ctx, "abc", "1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.DBErrors.WithLabelValues("users", "IsUser")))
+
+	sessMock := session.NewMockSessionManager(ctrl)
+	sess := NewSessionManager(sessMock, m)
+	sessMock.EXPECT().GetExp(ctx, "1", int64(10)).Return(int64(20))
+	sessMock.EXPECT().DeleteSess(ctx, "1", int64(10)).Return(fmt.Errorf("db error"))
+	assert.Equal(t, int64(20), sess.GetExp(ctx, "1", 10))
+	assert.NotNil(t, sess.DeleteSess(ctx, "1", 10))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.DBErrors.WithLabelValues("sessions", "DeleteSess")))
+
+	assert.Equal(t, 4, testutil.CollectAndCount(m.DBLatency))
+}
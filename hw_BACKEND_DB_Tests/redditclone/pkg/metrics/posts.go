@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/comments"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/preview"
+	"redditclone/pkg/vote"
+)
+
+type PostsRepository struct {
+	Repo    posts.PostsRepository
+	Metrics *Metrics
+}
+
+func NewPostsRepository(repo posts.PostsRepository, m *Metrics) *PostsRepository {
+	return &PostsRepository{
+		Repo:    repo,
+		Metrics: m,
+	}
+}
+
+func (p *PostsRepository) GetAllPosts(ctx context.Context) ([]posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.GetAllPosts(ctx)
+	p.Metrics.observe("posts", "GetAllPosts", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) AddPost(ctx context.Context, post posts.Post) (posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.AddPost(ctx, post)
+	p.Metrics.observe("posts", "AddPost", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) UpdatePost(ctx context.Context, post posts.Post) error {
+	start := time.Now()
+	err := p.Repo.UpdatePost(ctx, post)
+	p.Metrics.observe("posts", "UpdatePost", start, err)
+	return err
+}
+
+func (p *PostsRepository) GetPostByID(ctx context.Context, id string) (posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.GetPostByID(ctx, id)
+	p.Metrics.observe("posts", "GetPostByID", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) GetCategory(ctx context.Context, category string) ([]posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.GetCategory(ctx, category)
+	p.Metrics.observe("posts", "GetCategory", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) AddComment(ctx context.Context, postID string, comment comments.Comment) (posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.AddComment(ctx, postID, comment)
+	p.Metrics.observe("posts", "AddComment", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) DeleteComment(ctx context.Context, postID string, commentID string) (posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.DeleteComment(ctx, postID, commentID)
+	p.Metrics.observe("posts", "DeleteComment", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) DeletePost(ctx context.Context, postID string) error {
+	start := time.Now()
+	err := p.Repo.DeletePost(ctx, postID)
+	p.Metrics.observe("posts", "DeletePost", start, err)
+	return err
+}
+
+func (p *PostsRepository) GetByUserLogin(ctx context.Context, login string) ([]posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.GetByUserLogin(ctx, login)
+	p.Metrics.observe("posts", "GetByUserLogin", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) Vote(ctx context.Context, postID string, vote vote.Vote) (posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.Vote(ctx, postID, vote)
+	p.Metrics.observe("posts", "Vote", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) UnVote(ctx context.Context, username string, postID string) (posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.UnVote(ctx, username, postID)
+	p.Metrics.observe("posts", "UnVote", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) SetHidden(ctx context.Context, postID string, commentID string, hidden bool) error {
+	start := time.Now()
+	err := p.Repo.SetHidden(ctx, postID, commentID, hidden)
+	p.Metrics.observe("posts", "SetHidden", start, err)
+	return err
+}
+
+func (p *PostsRepository) SetPreview(ctx context.Context, postID string, preview preview.Preview) error {
+	start := time.Now()
+	err := p.Repo.SetPreview(ctx, postID, preview)
+	p.Metrics.observe("posts", "SetPreview", start, err)
+	return err
+}
+
+func (p *PostsRepository) GetUserActivity(ctx context.Context, userID string) ([]posts.Post, error) {
+	start := time.Now()
+	res, err := p.Repo.GetUserActivity(ctx, userID)
+	p.Metrics.observe("posts", "GetUserActivity", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) AnonymizeComments(ctx context.Context, postID string, userID string) error {
+	start := time.Now()
+	err := p.Repo.AnonymizeComments(ctx, postID, userID)
+	p.Metrics.observe("posts", "AnonymizeComments", start, err)
+	return err
+}
+
+func (p *PostsRepository) GetComments(ctx context.Context, postID string, page comments.Page) (comments.CommentsPage, error) {
+	start := time.Now()
+	res, err := p.Repo.GetComments(ctx, postID, page)
+	p.Metrics.observe("posts", "GetComments", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) GetComment(ctx context.Context, postID string, commentID string) (comments.Comment, error) {
+	start := time.Now()
+	res, err := p.Repo.GetComment(ctx, postID, commentID)
+	p.Metrics.observe("posts", "GetComment", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) VoteComment(ctx context.Context, postID string, commentID string, vote vote.Vote) (comments.Comment, error) {
+	start := time.Now()
+	res, err := p.Repo.VoteComment(ctx, postID, commentID, vote)
+	p.Metrics.observe("posts", "VoteComment", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) GetUserCommentVotes(ctx context.Context, userID string) ([]posts.CommentVote, error) {
+	start := time.Now()
+	res, err := p.Repo.GetUserCommentVotes(ctx, userID)
+	p.Metrics.observe("posts", "GetUserCommentVotes", start, err)
+	return res, err
+}
+
+func (p *PostsRepository) UnVoteComments(ctx context.Context, postID string, userID string) error {
+	start := time.Now()
+	err := p.Repo.UnVoteComments(ctx, postID, userID)
+	p.Metrics.observe("posts", "UnVoteComments", start, err)
+	return err
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/session"
+)
+
+type SessionManager struct {
+	Sessions session.SessionManager
+	Metrics  *Metrics
+}
+
+func NewSessionManager(sessions session.SessionManager, m *Metrics) *SessionManager {
+	return &SessionManager{
+		Sessions: sessions,
+		Metrics:  m,
+	}
+}
+
+func (s *SessionManager) GetKey() interface{} {
+	return s.Sessions.GetKey()
+}
+
+func (s *SessionManager) SetKey(key interface{}) {
+	s.Sessions.SetKey(key)
+}
+
+// GetExp reports no error, a missing session comes back as zero expiration.
+func (s *SessionManager) GetExp(ctx context.Context, id string, iat int64) int64 {
+	start := time.Now()
+	exp := s.Sessions.GetExp(ctx, id, iat)
+	s.Metrics.observe("sessions", "GetExp", start, nil)
+	return exp
+}
+
+func (s *SessionManager) AddNewSess(ctx context.Context, id string, exp int64, iat int64) error {
+	start := time.Now()
+	err := s.Sessions.AddNewSess(ctx, id, exp, iat)
+	s.Metrics.observe("sessions", "AddNewSess", start, err)
+	return err
+}
+
+func (s *SessionManager) DeleteSess(ctx context.Context, userID string, iat int64) error {
+	start := time.Now()
+	err := s.Sessions.DeleteSess(ctx, userID, iat)
+	s.Metrics.observe("sessions", "DeleteSess", start, err)
+	return err
+}
+
+func (s *SessionManager) GetSessions(ctx context.Context, userID string) ([]session.SessionDB, error) {
+	start := time.Now()
+	res, err := s.Sessions.GetSessions(ctx, userID)
+	s.Metrics.observe("sessions", "GetSessions", start, err)
+	return res, err
+}
+
+func (s *SessionManager) DeleteUserSessions(ctx context.Context, userID string) error {
+	start := time.Now()
+	err := s.Sessions.DeleteUserSessions(ctx, userID)
+	s.Metrics.observe("sessions", "DeleteUserSessions", start, err)
+	return err
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/user"
+)
+
+type UserRepo struct {
+	Repo    user.UserRepo
+	Metrics *Metrics
+}
+
+func NewUserRepo(repo user.UserRepo, m *Metrics) *UserRepo {
+	return &UserRepo{
+		Repo:    repo,
+		Metrics: m,
+	}
+}
+
+func (u *UserRepo) AddNewUser(ctx context.Context, user user.User) (string, error) {
+	start := time.Now()
+	res, err := u.Repo.AddNewUser(ctx, user)
+	u.Metrics.observe("users", "AddNewUser", start, err)
+	return res, err
+}
+
+func (u *UserRepo) Authenticate(ctx context.Context, user user.User) (string, error) {
+	start := time.Now()
+	res, err := u.Repo.Authenticate(ctx, user)
+	u.Metrics.observe("users", "Authenticate", start, err)
+	return res, err
+}
+
+func (u *UserRepo) IsUser(ctx context.Context, username string, id string) (bool, error) {
+	start := time.Now()
+	res, err := u.Repo.IsUser(ctx, username, id)
+	u.Metrics.observe("users", "IsUser", start, err)
+	return res, err
+}
+
+func (u *UserRepo) GetRole(ctx context.Context, id string) (string, error) {
+	start := time.Now()
+	res, err := u.Repo.GetRole(ctx, id)
+	u.Metrics.observe("users", "GetRole", start, err)
+	return res, err
+}
+
+func (u *UserRepo) GetUser(ctx context.Context, id string) (user.User, error) {
+	start := time.Now()
+	res, err := u.Repo.GetUser(ctx, id)
+	u.Metrics.observe("users", "GetUser", start, err)
+	return res, err
+}
+
+func (u *UserRepo) DeleteUser(ctx context.Context, id string) error {
+	start := time.Now()
+	err := u.Repo.DeleteUser(ctx, id)
+	u.Metrics.observe("users", "DeleteUser", start, err)
+	return err
+}
+
+func (u *UserRepo) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	start := time.Now()
+	res, err := u.Repo.GetUserByEmail(ctx, email)
+	u.Metrics.observe("users", "GetUserByEmail", start, err)
+	return res, err
+}
+
+func (u *UserRepo) SetEmail(ctx context.Context, id string, email string) error {
+	start := time.Now()
+	err := u.Repo.SetEmail(ctx, id, email)
+	u.Metrics.observe("users", "SetEmail", start, err)
+	return err
+}
+
+func (u *UserRepo) SetVerified(ctx context.Context, id string, email string) error {
+	start := time.Now()
+	err := u.Repo.SetVerified(ctx, id, email)
+	u.Metrics.observe("users", "SetVerified", start, err)
+	return err
+}
+
+func (u *UserRepo) SetPassword(ctx context.Context, id string, password string) error {
+	start := time.Now()
+	err := u.Repo.SetPassword(ctx, id, password)
+	u.Metrics.observe("users", "SetPassword", start, err)
+	return err
+}
+
+func (u *UserRepo) GetUserByIdentity(ctx context.Context, issuer string, subject string) (user.User, error) {
+	start := time.Now()
+	res, err := u.Repo.GetUserByIdentity(ctx, issuer, subject)
+	u.Metrics.observe("users", "GetUserByIdentity", start, err)
+	return res, err
+}
+
+func (u *UserRepo) LinkIdentity(ctx context.Context, id string, issuer string, subject string) error {
+	start := time.Now()
+	err := u.Repo.LinkIdentity(ctx, id, issuer, subject)
+	u.Metrics.observe("users", "LinkIdentity", start, err)
+	return err
+}
+
+func (u *UserRepo) GetTOTP(ctx context.Context, id string) (user.TOTP, error) {
+	start := time.Now()
+	res, err := u.Repo.GetTOTP(ctx, id)
+	u.Metrics.observe("users", "GetTOTP", start, err)
+	return res, err
+}
+
+func (u *UserRepo) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	start := time.Now()
+	err := u.Repo.SetTOTPSecret(ctx, id, secret)
+	u.Metrics.observe("users", "SetTOTPSecret", start, err)
+	return err
+}
+
+func (u *UserRepo) EnableTOTP(ctx context.Context, id string, step int64, recoveryHashes []string) error {
+	start := time.Now()
+	err := u.Repo.EnableTOTP(ctx, id, step, recoveryHashes)
+	u.Metrics.observe("users", "EnableTOTP", start, err)
+	return err
+}
+
+func (u *UserRepo) DisableTOTP(ctx context.Context, id string) error {
+	start := time.Now()
+	err := u.Repo.DisableTOTP(ctx, id)
+	u.Metrics.observe("users", "DisableTOTP", start, err)
+	return err
+}
+
+func (u *UserRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	start := time.Now()
+	res, err := u.Repo.UseTOTPStep(ctx, id, step)
+	u.Metrics.observe("users", "UseTOTPStep", start, err)
+	return res, err
+}
+
+func (u *UserRepo) UseRecoveryCode(ctx context.Context, id string, hash string) (bool, error) {
+	start := time.Now()
+	res, err := u.Repo.UseRecoveryCode(ctx, id, hash)
+	u.Metrics.observe("users", "UseRecoveryCode", start, err)
+	return res, err
+}
+
+func (u *UserRepo) TakeTOTPAttempt(ctx context.Context, id string) (bool, error) {
+	start := time.Now()
+	res, err := u.Repo.TakeTOTPAttempt(ctx, id)
+	u.Metrics.observe("users", "TakeTOTPAttempt", start, err)
+	return res, err
+}
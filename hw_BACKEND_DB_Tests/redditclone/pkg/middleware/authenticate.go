@@ -16,7 +16,7 @@ func Authenticate(contextKey key.Key, logger logger.Logger, uRepo user.UserRepo,
 		fmt.Println("AUTHENTICATE MIDDLEWARE", r.URL.Path)
 		author, ok := r.Context().Value(contextKey).(*author.Author)
 		if !ok {
-			logger.Log("Info", "Not in context")
+			requestLogger(r, logger).Log("Info", "Not in context")
 			return
 		}
 
@@ -19,7 +19,7 @@ func Authorize(contextKey key.Key, logger logger.Logger, pRepo posts.PostsReposi
 		fmt.Println("AuthorizeMiddleware", r.URL.Path)
 		author, ok := r.Context().Value(contextKey).(*author.Author)
 		if !ok {
-			logger.Log("Info", "not in context")
+			requestLogger(r, logger).Log("Info", "not in context")
 			return
 		}
 		vars := mux.Vars(r)
@@ -28,7 +28,7 @@ func Authorize(contextKey key.Key, logger logger.Logger, pRepo posts.PostsReposi
 		if commentID != "" && postID != "" {
 			err := CheckComment(r.Context(), postID, commentID, *author, pRepo)
 			if err != nil {
-				logger.Log("Error", err.Error())
+				requestLogger(r, logger).Log("Error", err.Error())
 				response.ServerResponseWriter(w, 400, err.Error())
 				return
 			}
@@ -38,7 +38,7 @@ func Authorize(contextKey key.Key, logger logger.Logger, pRepo posts.PostsReposi
 		if postID != "" {
 			err := CheckPost(r.Context(), postID, *author, pRepo)
 			if err != nil {
-				logger.Log("Error", err.Error())
+				requestLogger(r, logger).Log("Error", err.Error())
 				response.ServerResponseWriter(w, 400, err.Error())
 				return
 			}
@@ -50,14 +50,12 @@ func Authorize(contextKey key.Key, logger logger.Logger, pRepo posts.PostsReposi
 }
 
 func CheckComment(ctx context.Context, postID string, commentID string, author author.Author, pRepo posts.PostsRepository) error {
-	post, err := pRepo.GetPostByID(ctx, postID)
+	comment, err := pRepo.GetComment(ctx, postID, commentID)
 	if err != nil {
 		return err
 	}
-	for _, comment := range post.Comments {
-		if comment.ID == commentID && comment.Author.ID == author.ID && comment.Author.Username == author.Username {
-			return nil
-		}
+	if comment.Author.ID == author.ID && comment.Author.Username == author.Username {
+		return nil
 	}
 	return fmt.Errorf("comment not found")
 }
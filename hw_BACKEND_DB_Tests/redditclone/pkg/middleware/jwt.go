@@ -63,7 +63,7 @@ func JWT(contextKey key.Key, logger logger.Logger, sess session.SessionManager,
 		if curTime >= timeExp {
 			err = sess.DeleteSess(r.Context(), author.ID, iat)
 			if err != nil {
-				logger.Log("Error", err.Error())
+				requestLogger(r, logger).Log("Error", err.Error())
 				response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "db error"})
 			}
 			response.ServerResponseWriter(w, 400, map[string]interface{}{"message": "token expired or has incorrect time"})
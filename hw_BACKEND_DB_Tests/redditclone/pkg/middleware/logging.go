@@ -19,6 +19,6 @@ func Logging(logger logger.Logger, next http.Handler) http.Handler {
 			"url":         r.URL.Path,
 			"time":        time.Since(start),
 		}
-		logger.LogW("Info", "new request with params: ", fieldsMap)
+		requestLogger(r, logger).LogW("Info", "new request with params: ", fieldsMap)
 	})
 }
@@ -15,7 +15,7 @@ func Panic(log logger.Logger, next http.Handler) http.Handler {
 				fieldsMap := map[string]interface{}{
 					"panic": err,
 				}
-				log.LogW("Panic", "recovered: ", fieldsMap)
+				requestLogger(r, log).LogW("Panic", "recovered: ", fieldsMap)
 				http.Error(w, "Internal server error", 500)
 			}
 		}()
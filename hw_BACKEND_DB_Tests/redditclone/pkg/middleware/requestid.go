@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"redditclone/pkg/logger"
+	"redditclone/pkg/requestid"
+)
+
+func RequestID(log logger.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println("requestIDMiddleware", r.URL.Path)
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		ctx := requestid.NewContext(r.Context(), id)
+		ctx = logger.NewContext(ctx, log.WithFields(map[string]interface{}{"request_id": id}))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requestLogger(r *http.Request, fallback logger.Logger) logger.Logger {
+	return logger.FromContext(r.Context(), fallback)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"redditclone/pkg/logger"
+	"redditclone/pkg/requestid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	base := logger.NewCaptureLogger()
+
+	var gotID string
+	var gotLogger logger.Logger
+	handler := RequestID(base, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = requestid.FromContext(r.Context())
+		gotLogger = logger.FromContext(r.Context(), nil)
+		gotLogger.Log("Info", "handled")
+	}))
+
+	// incoming id is honoured
+	req := httptest.NewRequest("GET", "/api/posts/", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, "abc-123", gotID)
+	assert.Equal(t, "abc-123", w.Header().Get(requestid.Header))
+	entries := base.Entries()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "abc-123", entries[0].Fields["request_id"])
+	}
+
+	// missing id is generated
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/posts/", nil))
+	assert.Len(t, gotID, 32)
+	assert.Equal(t, gotID, w.Header().Get(requestid.Header))
+
+	// unsafe id is replaced
+	req = httptest.NewRequest("GET", "/api/posts/", nil)
+	req.Header.Set(requestid.Header, "bad id\nwith newline")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\nwith newline", gotID)
+	assert.True(t, requestid.Valid(gotID))
+}
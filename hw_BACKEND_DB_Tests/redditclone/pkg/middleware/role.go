@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/response"
+	"redditclone/pkg/user"
+)
+
+func Role(contextKey key.Key, logger logger.Logger, uRepo user.UserRepo, roles []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println("RoleMiddleware", r.URL.Path)
+		author, ok := r.Context().Value(contextKey).(*author.Author)
+		if !ok {
+			requestLogger(r, logger).Log("Info", "not in context")
+			return
+		}
+
+		role, err := uRepo.GetRole(r.Context(), author.ID)
+		if err != nil {
+			requestLogger(r, logger).Log("Error", err.Error())
+			response.ServerResponseWriter(w, 401, map[string]interface{}{"message": "db error"})
+			return
+		}
+
+		if slices.Contains(roles, role) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		response.ServerResponseWriter(w, 403, map[string]interface{}{"message": "not enough rights"})
+	})
+}
+
+// Moderator and Admin also require two-factor authentication.
+func Moderator(contextKey key.Key, logger logger.Logger, uRepo user.UserRepo, next http.Handler) http.Handler {
+	return Role(contextKey, logger, uRepo, []string{user.RoleModerator, user.RoleAdmin}, TwoFactor(contextKey, logger, uRepo, next))
+}
+
+func Admin(contextKey key.Key, logger logger.Logger, uRepo user.UserRepo, next http.Handler) http.Handler {
+	return Role(contextKey, logger, uRepo, []string{user.RoleAdmin}, TwoFactor(contextKey, logger, uRepo, next))
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/response"
+	"redditclone/pkg/user"
+)
+
+// TwoFactor only lets through users with 2FA enabled. Enabling it ends the
+// older sessions, so every session that gets here was opened with a code.
+func TwoFactor(contextKey key.Key, logger logger.Logger, uRepo user.UserRepo, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		author, ok := r.Context().Value(contextKey).(*author.Author)
+		if !ok {
+			requestLogger(r, logger).Log("Info", "not in context")
+			return
+		}
+
+		second, err := uRepo.GetTOTP(r.Context(), author.ID)
+		if err != nil {
+			requestLogger(r, logger).Log("Error", err.Error())
+			response.ServerResponseWriter(w, 401, map[string]interface{}{"message": "db error"})
+			return
+		}
+
+		if second.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		response.ServerResponseWriter(w, 403, map[string]interface{}{"message": "two-factor authentication required"})
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModeratorNeedsTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var contextKey key.Key = "author"
+	users := user.NewMockUserRepo(ctrl)
+	called := 0
+	handler := Moderator(contextKey, logger.NopLogger{}, users, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called++
+	}))
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/moderation/reports", nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextKey, &author.Author{ID: "1", Username: "mod"}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// plain users don't get to the second factor check
+	users.EXPECT().GetRole(gomock.Any(), "1").Return(user.RoleUser, nil)
+	assert.Equal(t, 403, request().Code)
+
+	users.EXPECT().GetRole(gomock.Any(), "1").Return(user.RoleModerator, nil)
+	users.EXPECT().GetTOTP(gomock.Any(), "1").Return(user.TOTP{Secret: "pending"}, nil)
+	w := request()
+	assert.Equal(t, 403, w.Code)
+	assert.Contains(t, w.Body.String(), "two-factor")
+
+	users.EXPECT().GetRole(gomock.Any(), "1").Return(user.RoleModerator, nil)
+	users.EXPECT().GetTOTP(gomock.Any(), "1").Return(user.TOTP{Secret: "SECRET", Enabled: true}, nil)
+	assert.Equal(t, 200, request().Code)
+	assert.Equal(t, 1, called)
+}
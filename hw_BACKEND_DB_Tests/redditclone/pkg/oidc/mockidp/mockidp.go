@@ -0,0 +1,221 @@
+// Package mockidp is an in-process OpenID provider for tests and offline
+// development. Every authorization request is approved for Identity.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"redditclone/pkg/oidc"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	codeTTL    = time.Minute
+	idTokenTTL = time.Hour
+	keyID      = "mock"
+)
+
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	identity    Identity
+	expires     time.Time
+}
+
+type Provider struct {
+	Issuer      string
+	ClientID    string
+	RedirectURL string
+	Identity    Identity
+	Now         func() time.Time
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+func New(issuer string, clientID string, redirectURL string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Issuer:      issuer,
+		ClientID:    clientID,
+		RedirectURL: redirectURL,
+		Identity:    Identity{Subject: "mock-user", Email: "mock@example.com", EmailVerified: true, PreferredUsername: "mock"},
+		Now:         time.Now,
+		key:         key,
+		codes:       make(map[string]grant),
+	}, nil
+}
+
+// NewServer starts the provider on a local listener; its URL is the issuer.
+func NewServer(clientID string, redirectURL string) (*Provider, *httptest.Server, error) {
+	p, err := New("", clientID, redirectURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv := httptest.NewServer(p.Handler())
+	p.Issuer = srv.URL
+	return p, srv, nil
+}
+
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func oauthError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Endpoints{
+		Issuer:   p.Issuer,
+		AuthURL:  p.Issuer + "/authorize",
+		TokenURL: p.Issuer + "/token",
+		JWKSURL:  p.Issuer + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": keyID,
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("redirect_uri") != p.RedirectURL {
+		// never redirect to an unregistered uri
+		oauthError(w, "invalid_request")
+		return
+	}
+	target, err := url.Parse(p.RedirectURL)
+	if err != nil {
+		oauthError(w, "invalid_request")
+		return
+	}
+	back := target.Query()
+	back.Set("state", query.Get("state"))
+	switch {
+	case query.Get("response_type") != "code":
+		back.Set("error", "unsupported_response_type")
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		back.Set("error", "invalid_request")
+	default:
+		code := randomString()
+		p.mu.Lock()
+		p.codes[code] = grant{
+			clientID:    p.ClientID,
+			redirectURI: p.RedirectURL,
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			identity:    p.Identity,
+			expires:     p.Now().Add(codeTTL),
+		}
+		p.mu.Unlock()
+		back.Set("code", code)
+	}
+	target.RawQuery = back.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		oauthError(w, "invalid_request")
+		return
+	}
+	p.mu.Lock()
+	g, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || p.Now().After(g.expires) ||
+		r.PostForm.Get("client_id") != g.clientID ||
+		r.PostForm.Get("redirect_uri") != g.redirectURI ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		oauthError(w, "invalid_grant")
+		return
+	}
+	idToken, err := p.IDToken(g.identity, g.nonce, g.clientID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// IDToken signs an id token the way the token endpoint does, tests use it
+// to hand-craft bad ones.
+func (p *Provider) IDToken(identity Identity, nonce string, audience string) (string, error) {
+	now := p.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer,
+		"sub":            identity.Subject,
+		"aud":            audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(idTokenTTL).Unix(),
+		"nonce":          nonce,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+	}
+	if identity.Name != "" {
+		claims["name"] = identity.Name
+	}
+	if identity.PreferredUsername != "" {
+		claims["preferred_username"] = identity.PreferredUsername
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(p.key)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
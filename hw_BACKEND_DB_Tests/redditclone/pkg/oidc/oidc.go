@@ -0,0 +1,285 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrBadIDToken = errors.New("bad id token")
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// Endpoints is the part of the discovery document the login flow needs.
+type Endpoints struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the identity claims read from a verified id token.
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type Client struct {
+	Config    Config
+	Endpoints Endpoints
+	HTTP      *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+func NewClient(config Config, endpoints Endpoints, httpClient *http.Client) *Client {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Client{
+		Config:    config,
+		Endpoints: endpoints,
+		HTTP:      httpClient,
+	}
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// Discover reads the provider's discovery document.
+func Discover(ctx context.Context, httpClient *http.Client, issuer string) (Endpoints, error) {
+	endpoints := Endpoints{}
+	err := getJSON(ctx, httpClient, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &endpoints)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	if endpoints.Issuer != issuer {
+		return Endpoints{}, fmt.Errorf("discovery issuer %q does not match %q", endpoints.Issuer, issuer)
+	}
+	return endpoints, nil
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Login holds the per-attempt secrets; they stay with the browser until the
+// provider redirects back.
+type Login struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+func NewLogin() Login {
+	return Login{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+	}
+}
+
+// Challenge is the S256 PKCE code challenge for a verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (c *Client) AuthCodeURL(login Login) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.Config.ClientID},
+		"redirect_uri":          {c.Config.RedirectURL},
+		"scope":                 {strings.Join(c.Config.Scopes, " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {Challenge(login.Verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(c.Endpoints.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.Endpoints.AuthURL + sep + query.Encode()
+}
+
+// Exchange trades the authorization code for an id token and verifies it.
+func (c *Client) Exchange(ctx context.Context, code string, login Login) (Claims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.Config.RedirectURL},
+		"client_id":     {c.Config.ClientID},
+		"code_verifier": {login.Verifier},
+	}
+	if c.Config.ClientSecret != "" {
+		form.Set("client_secret", c.Config.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoints.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return Claims{}, err
+	}
+	defer resp.Body.Close()
+
+	body := struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return Claims{}, fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Claims{}, fmt.Errorf("token endpoint: %s %s", resp.Status, body.Error)
+	}
+	return c.Verify(ctx, body.IDToken, login.Nonce)
+}
+
+func (c *Client) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	// an unknown kid usually means the provider rotated its keys
+	keys, err := fetchKeys(ctx, c.HTTP, c.Endpoints.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func fetchKeys(ctx context.Context, httpClient *http.Client, jwksURL string) (map[string]*rsa.PublicKey, error) {
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := getJSON(ctx, httpClient, jwksURL, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+func hasAudience(claims jwt.MapClaims, clientID string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an id
+// token.
+func (c *Client) Verify(ctx context.Context, raw string, nonce string) (Claims, error) {
+	tkn, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, kid)
+	})
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrBadIDToken, err.Error())
+	}
+	payload, ok := tkn.Claims.(jwt.MapClaims)
+	if !ok || !tkn.Valid {
+		return Claims{}, ErrBadIDToken
+	}
+	if _, ok = payload["exp"]; !ok {
+		return Claims{}, fmt.Errorf("%w: no expiry", ErrBadIDToken)
+	}
+	if !payload.VerifyIssuer(c.Endpoints.Issuer, true) {
+		return Claims{}, fmt.Errorf("%w: wrong issuer", ErrBadIDToken)
+	}
+	if !hasAudience(payload, c.Config.ClientID) {
+		return Claims{}, fmt.Errorf("%w: wrong audience", ErrBadIDToken)
+	}
+	if got, _ := payload["nonce"].(string); got != nonce {
+		return Claims{}, fmt.Errorf("%w: wrong nonce", ErrBadIDToken)
+	}
+
+	claims := Claims{Issuer: c.Endpoints.Issuer}
+	claims.Subject, _ = payload["sub"].(string)
+	claims.Email, _ = payload["email"].(string)
+	claims.EmailVerified, _ = payload["email_verified"].(bool)
+	claims.Name, _ = payload["name"].(string)
+	claims.PreferredUsername, _ = payload["preferred_username"].(string)
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: no subject", ErrBadIDToken)
+	}
+	return claims, nil
+}
@@ -0,0 +1,103 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"redditclone/pkg/oidc"
+	"redditclone/pkg/oidc/mockidp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8080/api/oidc/callback"
+
+func newClient(t *testing.T) (*mockidp.Provider, *oidc.Client) {
+	idp, srv, err := mockidp.NewServer("redditclone", redirectURL)
+	require.Nil(t, err)
+	t.Cleanup(srv.Close)
+
+	endpoints, err := oidc.Discover(context.Background(), srv.Client(), idp.Issuer)
+	require.Nil(t, err)
+	client := oidc.NewClient(oidc.Config{ClientID: "redditclone", RedirectURL: redirectURL}, endpoints, srv.Client())
+	return idp, client
+}
+
+// authorize follows the login redirect to the provider and returns the
+// query it redirects back with.
+func authorize(t *testing.T, client *oidc.Client, login oidc.Login) url.Values {
+	httpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := httpClient.Get(client.AuthCodeURL(login))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.Nil(t, err)
+	assert.Equal(t, redirectURL, back.Scheme+"://"+back.Host+back.Path)
+	return back.Query()
+}
+
+func TestLoginFlow(t *testing.T) {
+	idp, client := newClient(t)
+	idp.Identity = mockidp.Identity{Subject: "42", Email: "a@example.com", EmailVerified: true, PreferredUsername: "alice"}
+
+	login := oidc.NewLogin()
+	back := authorize(t, client, login)
+	assert.Equal(t, login.State, back.Get("state"))
+
+	claims, err := client.Exchange(context.Background(), back.Get("code"), login)
+	require.Nil(t, err)
+	assert.Equal(t, oidc.Claims{
+		Issuer:            idp.Issuer,
+		Subject:           "42",
+		Email:             "a@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+	}, claims)
+
+	// codes are single use
+	_, err = client.Exchange(context.Background(), back.Get("code"), login)
+	assert.NotNil(t, err)
+}
+
+func TestLoginFlowRejects(t *testing.T) {
+	idp, client := newClient(t)
+
+	// a stolen code is useless without the verifier
+	login := oidc.NewLogin()
+	back := authorize(t, client, login)
+	stolen := oidc.NewLogin()
+	stolen.Nonce = login.Nonce
+	_, err := client.Exchange(context.Background(), back.Get("code"), stolen)
+	assert.NotNil(t, err)
+
+	// the id token must answer this login's nonce
+	login = oidc.NewLogin()
+	back = authorize(t, client, login)
+	replayed := login
+	replayed.Nonce = "other"
+	_, err = client.Exchange(context.Background(), back.Get("code"), replayed)
+	assert.True(t, errors.Is(err, oidc.ErrBadIDToken), err)
+
+	// and be issued for this client
+	raw, err := idp.IDToken(idp.Identity, "n", "someone-else")
+	require.Nil(t, err)
+	_, err = client.Verify(context.Background(), raw, "n")
+	assert.True(t, errors.Is(err, oidc.ErrBadIDToken), err)
+
+	raw, err = idp.IDToken(idp.Identity, "n", "redditclone")
+	require.Nil(t, err)
+	_, err = client.Verify(context.Background(), raw+"x", "n")
+	assert.True(t, errors.Is(err, oidc.ErrBadIDToken), err)
+}
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oidc.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
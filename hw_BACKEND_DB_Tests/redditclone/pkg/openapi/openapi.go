@@ -0,0 +1,18 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:generate oapi-codegen -generate types,client -package client -o client/client.gen.go openapi.json
+
+//go:embed openapi.json
+var Spec []byte
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(Spec)
+	})
+}
@@ -0,0 +1,40 @@
+package outbox
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventPostCreated = "post_created"
+	EventPostVoted   = "post_voted"
+	EventPostUnvoted = "post_unvoted"
+	EventPostDeleted = "post_deleted"
+)
+
+// Event.ID doubles as the idempotency key: a relay crash between publishing
+// and marking the event may deliver it again with the same id.
+type Event struct {
+	ID        string                 `json:"id" bson:"_id"`
+	Type      string                 `json:"type" bson:"type"`
+	PostID    string                 `json:"postId" bson:"postId"`
+	Payload   map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`
+	Created   time.Time              `json:"created" bson:"created"`
+	Published bool                   `json:"-" bson:"published"`
+	Attempts  int                    `json:"-" bson:"attempts"`
+	// NextAttempt delays a retry after a failed publish, Failed is set once
+	// the relay gave up on the event.
+	NextAttempt time.Time `json:"-" bson:"nextAttempt,omitempty"`
+	Failed      bool      `json:"-" bson:"failed,omitempty"`
+}
+
+func NewEvent(eventType string, postID string, payload map[string]interface{}) Event {
+	return Event{
+		ID:      primitive.NewObjectID().Hex(),
+		Type:    eventType,
+		PostID:  postID,
+		Payload: payload,
+		Created: time.Now(),
+	}
+}
@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"redditclone/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type fakeSink struct {
+	events []Event
+	err    error
+	// refuse fails only the event with this id
+	refuse string
+}
+
+func (s *fakeSink) Publish(ctx context.Context, event Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	if event.ID == s.refuse {
+		return fmt.Errorf("rejected")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+type mapStore map[string][]byte
+
+func (m mapStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := m[key]
+	return value, ok, nil
+}
+
+func (m mapStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func eventDoc(t *testing.T, event Event) bson.D {
+	data, err := bson.Marshal(event)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	var doc bson.D
+	if err = bson.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	return doc
+}
+
+// updateSet returns the $set document of an update command.
+func updateSet(command bson.Raw) bson.Raw {
+	return command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+}
+
+func TestRelayOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	first := NewEvent(EventPostCreated, "1", nil)
+	second := NewEvent(EventPostVoted, "1", map[string]interface{}{"vote": 1})
+	updated := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+
+	mt.Run("OK", func(mt *mtest.T) {
+		sink := &fakeSink{}
+		relay := NewRelay(mt.Coll, sink, logger.NopLogger{})
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, eventDoc(t, first), eventDoc(t, second)),
+			updated,
+			updated,
+		)
+		n, err := relay.RelayOnce(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, []string{first.ID, second.ID}, []string{sink.events[0].ID, sink.events[1].ID})
+	})
+
+	mt.Run("sink error", func(mt *mtest.T) {
+		sink := &fakeSink{err: fmt.Errorf("broker down")}
+		relay := NewRelay(mt.Coll, sink, logger.NopLogger{})
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, eventDoc(t, first), eventDoc(t, second)),
+			updated,
+			updated,
+		)
+		n, err := relay.RelayOnce(context.Background())
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, n)
+
+		// both get a retry time, neither is given up on yet
+		for _, started := range mt.GetAllStartedEvents()[1:] {
+			set := updateSet(started.Command)
+			assert.Equal(t, int32(1), set.Lookup("attempts").Int32())
+			_, ok := set.Lookup("nextAttempt").TimeOK()
+			assert.True(t, ok)
+			_, err = set.LookupErr("failed")
+			assert.NotNil(t, err)
+		}
+	})
+
+	mt.Run("poison event", func(mt *mtest.T) {
+		poison := first
+		poison.Attempts = DefaultMaxAttempts - 1
+		sink := &fakeSink{refuse: poison.ID}
+		relay := NewRelay(mt.Coll, sink, logger.NopLogger{})
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, eventDoc(t, poison), eventDoc(t, second)),
+			updated,
+			updated,
+		)
+		n, err := relay.RelayOnce(context.Background())
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, n, "the event after a poison one must still go out")
+		assert.Equal(t, second.ID, sink.events[0].ID)
+
+		set := updateSet(mt.GetAllStartedEvents()[1].Command)
+		assert.True(t, set.Lookup("failed").Boolean())
+	})
+
+	mt.Run("find error", func(mt *mtest.T) {
+		relay := NewRelay(mt.Coll, &fakeSink{}, logger.NopLogger{})
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := relay.RelayOnce(context.Background())
+		assert.NotNil(t, err)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	relay := NewRelay(nil, &fakeSink{}, logger.NopLogger{})
+	relay.MaxBackoff = 10 * time.Second
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 4*time.Second, relay.backoff(3))
+	assert.Equal(t, 10*time.Second, relay.backoff(5))
+	assert.Equal(t, 10*time.Second, relay.backoff(100))
+}
+
+func TestWriterSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := NewWriterSink(buf)
+	event := NewEvent(EventPostDeleted, "1", nil)
+	assert.Nil(t, sink.Publish(context.Background(), event))
+
+	var got Event
+	assert.Nil(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &got))
+	assert.Equal(t, event.ID, got.ID)
+	assert.Equal(t, EventPostDeleted, got.Type)
+}
+
+func TestIdempotentSink(t *testing.T) {
+	inner := &fakeSink{}
+	sink := NewIdempotentSink(inner, mapStore{}, time.Minute)
+	event := NewEvent(EventPostCreated, "1", nil)
+
+	assert.Nil(t, sink.Publish(context.Background(), event))
+	assert.Nil(t, sink.Publish(context.Background(), event))
+	assert.Equal(t, 1, len(inner.events))
+}
@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"redditclone/pkg/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultBatchSize   = 100
+	DefaultInterval    = time.Second
+	DefaultMaxAttempts = 10
+	DefaultMaxBackoff  = 10 * time.Minute
+)
+
+// Relay moves unpublished events from the outbox collection to the sink in
+// insertion order. An event is marked only after the sink accepted it, so
+// delivery is at-least-once.
+//
+// An event the sink refuses is retried with exponential backoff and does not
+// hold back the ones after it; after MaxAttempts it is marked failed and left
+// in the collection for an operator.
+type Relay struct {
+	Outbox      *mongo.Collection
+	Sink        Sink
+	Logger      logger.Logger
+	BatchSize   int64
+	Interval    time.Duration
+	MaxAttempts int
+	MaxBackoff  time.Duration
+}
+
+func NewRelay(outbox *mongo.Collection, sink Sink, logger logger.Logger) *Relay {
+	return &Relay{
+		Outbox:      outbox,
+		Sink:        sink,
+		Logger:      logger,
+		BatchSize:   DefaultBatchSize,
+		Interval:    DefaultInterval,
+		MaxAttempts: DefaultMaxAttempts,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.Interval
+	for i := 1; i < attempts && wait < r.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > r.MaxBackoff {
+		wait = r.MaxBackoff
+	}
+	return wait
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.RelayOnce(ctx); err != nil {
+				r.Logger.Log("Error", err.Error())
+			}
+		}
+	}
+}
+
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events := make([]Event, 0)
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(r.BatchSize)
+	filter := bson.M{
+		"published":   false,
+		"failed":      bson.M{"$ne": true},
+		"nextAttempt": bson.M{"$not": bson.M{"$gt": time.Now()}},
+	}
+	c, err := r.Outbox.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("error in relay: %s", err.Error())
+	}
+	defer c.Close(ctx)
+	err = c.All(ctx, &events)
+	if err != nil {
+		return 0, fmt.Errorf("error in relay: %s", err.Error())
+	}
+
+	published := 0
+	var publishErr error
+	for _, event := range events {
+		err = r.Sink.Publish(ctx, event)
+		if err != nil {
+			if publishErr == nil {
+				publishErr = fmt.Errorf("error publishing event %s: %s", event.ID, err.Error())
+			}
+			r.retryLater(ctx, event, err)
+			continue
+		}
+		_, err = r.Outbox.UpdateByID(ctx, event.ID, bson.M{"$set": bson.M{"published": true, "publishedAt": time.Now()}})
+		if err != nil {
+			return published, fmt.Errorf("error marking event %s: %s", event.ID, err.Error())
+		}
+		published++
+	}
+	return published, publishErr
+}
+
+func (r *Relay) retryLater(ctx context.Context, event Event, cause error) {
+	attempts := event.Attempts + 1
+	update := bson.M{"attempts": attempts}
+	if attempts >= r.MaxAttempts {
+		update["failed"] = true
+		r.Logger.LogW("Error", "outbox event failed", map[string]interface{}{
+			"event":    event.ID,
+			"type":     event.Type,
+			"attempts": attempts,
+			"error":    cause.Error(),
+		})
+	} else {
+		update["nextAttempt"] = time.Now().Add(r.backoff(attempts))
+	}
+	if _, err := r.Outbox.UpdateByID(ctx, event.ID, bson.M{"$set": update}); err != nil {
+		r.Logger.Log("Error", fmt.Sprintf("error marking event %s: %s", event.ID, err.Error()))
+	}
+}
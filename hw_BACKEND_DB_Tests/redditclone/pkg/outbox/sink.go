@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+type WriterSink struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{W: w}
+}
+
+func NewFileSink(path string) (*WriterSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(file), nil
+}
+
+func (s *WriterSink) Publish(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.W.Write(append(line, '\n'))
+	return err
+}
+
+// Publisher is implemented by message broker clients (kafka, nats, ...).
+// The key is the event id so the broker side can deduplicate.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key string, payload []byte) error
+}
+
+type BrokerSink struct {
+	Broker Publisher
+	Topic  string
+}
+
+func NewBrokerSink(broker Publisher, topic string) *BrokerSink {
+	return &BrokerSink{
+		Broker: broker,
+		Topic:  topic,
+	}
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.Broker.Publish(ctx, s.Topic, event.ID, payload)
+}
+
+// SeenStore is the subset of cache.Cache the idempotent sink needs; it is
+// declared here because the cache package depends on posts, which emit events.
+type SeenStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// IdempotentSink skips events it has already delivered, for sinks that can't
+// dedupe themselves. It remembers only as long as Seen does: with the
+// in-process LRU it dedupes within one process, a redelivery after a relay
+// restart needs a shared store such as Redis.
+type IdempotentSink struct {
+	Sink Sink
+	Seen SeenStore
+	TTL  time.Duration
+}
+
+func NewIdempotentSink(sink Sink, seen SeenStore, ttl time.Duration) *IdempotentSink {
+	return &IdempotentSink{
+		Sink: sink,
+		Seen: seen,
+		TTL:  ttl,
+	}
+}
+
+func (s *IdempotentSink) Publish(ctx context.Context, event Event) error {
+	if _, ok, err := s.Seen.Get(ctx, "outbox:"+event.ID); err == nil && ok {
+		return nil
+	}
+	if err := s.Sink.Publish(ctx, event); err != nil {
+		return err
+	}
+	_ = s.Seen.Set(ctx, "outbox:"+event.ID, []byte{1}, s.TTL)
+	return nil
+}
@@ -0,0 +1,293 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"redditclone/pkg/author"
+	"redditclone/pkg/comments"
+	"redditclone/pkg/vote"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// postComments is the page attached to a post returned by GetPostByID, the
+// rest of the thread is read through GetComments.
+var postComments = comments.Page{Sort: comments.SortBest, Limit: comments.DefaultPageSize}
+
+type commentDoc struct {
+	ID      string        `bson:"_id"`
+	PostID  string        `bson:"postId"`
+	Created time.Time     `bson:"created"`
+	Author  author.Author `bson:"author"`
+	Body    string        `bson:"body"`
+	Score   int           `bson:"score"`
+	Votes   []vote.Vote   `bson:"votes,omitempty"`
+	Hidden  bool          `bson:"hidden"`
+}
+
+func newCommentDoc(postID string, comment comments.Comment) commentDoc {
+	return commentDoc{
+		ID:      comment.ID,
+		PostID:  postID,
+		Created: comment.Created,
+		Author:  comment.Author,
+		Body:    comment.Body,
+		Score:   comment.Score,
+		Hidden:  comment.Hidden,
+	}
+}
+
+func (d commentDoc) comment() comments.Comment {
+	return comments.Comment{
+		Created: d.Created,
+		Author:  d.Author,
+		Body:    d.Body,
+		ID:      d.ID,
+		Score:   d.Score,
+		Hidden:  d.Hidden,
+	}
+}
+
+func pageQuery(postID string, page comments.Page) (bson.M, bson.D) {
+	filter := visible(bson.M{"postId": postID})
+	var sort bson.D
+	switch page.Sort {
+	case comments.SortNew:
+		sort = bson.D{{Key: "_id", Value: -1}}
+		if page.After != nil {
+			filter["_id"] = bson.M{"$lt": page.After.ID}
+		}
+	case comments.SortOld:
+		sort = bson.D{{Key: "_id", Value: 1}}
+		if page.After != nil {
+			filter["_id"] = bson.M{"$gt": page.After.ID}
+		}
+	default:
+		sort = bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: -1}}
+		if page.After != nil {
+			filter["$or"] = bson.A{
+				bson.M{"score": bson.M{"$lt": page.After.Score}},
+				bson.M{"score": page.After.Score, "_id": bson.M{"$lt": page.After.ID}},
+			}
+		}
+	}
+	return filter, sort
+}
+
+func (p *PostsMongoRepo) findComments(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]comments.Comment, error) {
+	c, err := p.Comments.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close(ctx)
+	docs := make([]commentDoc, 0)
+	if err = c.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	found := make([]comments.Comment, 0, len(docs))
+	for _, doc := range docs {
+		found = append(found, doc.comment())
+	}
+	return found, nil
+}
+
+func (p *PostsMongoRepo) GetComments(ctx context.Context, postID string, page comments.Page) (comments.CommentsPage, error) {
+	filter, sort := pageQuery(postID, page)
+	opts := options.Find().SetSort(sort).SetLimit(int64(page.Limit) + 1)
+	found, err := p.findComments(ctx, filter, opts)
+	if err != nil {
+		return comments.CommentsPage{}, fmt.Errorf("error in getcomments: %s", err.Error())
+	}
+	result := comments.CommentsPage{Comments: found}
+	if len(found) > page.Limit {
+		result.Comments = found[:page.Limit]
+		result.Next = page.CursorFor(result.Comments[page.Limit-1])
+	}
+	return result, nil
+}
+
+func (p *PostsMongoRepo) GetComment(ctx context.Context, postID string, commentID string) (comments.Comment, error) {
+	doc := commentDoc{}
+	err := p.Comments.FindOne(ctx, bson.M{"_id": commentID, "postId": postID}).Decode(&doc)
+	if err != nil {
+		return comments.Comment{}, fmt.Errorf("error in getcomment: %w", err)
+	}
+	return doc.comment(), nil
+}
+
+// commentVoteUpdate replaces the user's vote and sums the score again from
+// the votes left.
+func commentVoteUpdate(vote vote.Vote) mongo.Pipeline {
+	var votes interface{} = bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$votes", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.user", vote.User}},
+	}}
+	if vote.Vote != 0 {
+		votes = bson.M{"$concatArrays": bson.A{votes, bson.A{vote}}}
+	}
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"votes": votes}}},
+		{{Key: "$set", Value: bson.M{"score": bson.M{"$sum": "$votes.vote"}}}},
+	}
+}
+
+// VoteComment replaces the user's vote on a visible comment, a zero vote
+// removes it. The score is summed from the votes in the same update, so it
+// stays right under concurrent votes and the best order can rely on it.
+func (p *PostsMongoRepo) VoteComment(ctx context.Context, postID string, commentID string, vote vote.Vote) (comments.Comment, error) {
+	doc := commentDoc{}
+	err := p.Comments.FindOneAndUpdate(ctx, visible(bson.M{"_id": commentID, "postId": postID}), commentVoteUpdate(vote),
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&doc)
+	if err != nil {
+		return comments.Comment{}, fmt.Errorf("error in votecomment: %w", err)
+	}
+	return doc.comment(), nil
+}
+
+// GetUserCommentVotes lists the user's votes on comments, hidden ones
+// included.
+func (p *PostsMongoRepo) GetUserCommentVotes(ctx context.Context, userID string) ([]CommentVote, error) {
+	votes := make([]CommentVote, 0)
+	c, err := p.Comments.Find(ctx, bson.M{"votes.user": userID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return votes, fmt.Errorf("error in getusercommentvotes: %s", err.Error())
+	}
+	defer c.Close(ctx)
+	docs := make([]commentDoc, 0)
+	if err = c.All(ctx, &docs); err != nil {
+		return votes, fmt.Errorf("error in getusercommentvotes: %s", err.Error())
+	}
+	for _, doc := range docs {
+		for _, v := range doc.Votes {
+			if v.User == userID {
+				votes = append(votes, CommentVote{PostID: doc.PostID, CommentID: doc.ID, Vote: v.Vote})
+			}
+		}
+	}
+	return votes, nil
+}
+
+// UnVoteComments removes the user's votes from the comments of a post and
+// sums their scores again. Like AnonymizeComments it is idempotent.
+func (p *PostsMongoRepo) UnVoteComments(ctx context.Context, postID string, userID string) error {
+	_, err := p.Comments.UpdateMany(ctx, bson.M{"postId": postID, "votes.user": userID}, commentVoteUpdate(vote.Vote{User: userID}))
+	if err != nil {
+		return fmt.Errorf("error in unvotecomments: %s", err.Error())
+	}
+	return nil
+}
+
+// attachComments sets the first page of the thread and the number of
+// visible comments on the post.
+func (p *PostsMongoRepo) attachComments(ctx context.Context, post *Post) error {
+	page, err := p.GetComments(ctx, post.ID, postComments)
+	if err != nil {
+		return err
+	}
+	post.Comments = page.Comments
+	post.CommentsCount = len(page.Comments)
+	if page.Next == "" {
+		return nil
+	}
+	count, err := p.Comments.CountDocuments(ctx, visible(bson.M{"postId": post.ID}))
+	if err != nil {
+		return fmt.Errorf("error in countcomments: %s", err.Error())
+	}
+	post.CommentsCount = int(count)
+	return nil
+}
+
+// countComments sets the number of visible comments on every post of a
+// listing with a single aggregation. Listings carry no comment bodies.
+func (p *PostsMongoRepo) countComments(ctx context.Context, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	c, err := p.Comments.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: visible(bson.M{"postId": bson.M{"$in": ids}})}},
+		{{Key: "$group", Value: bson.M{"_id": "$postId", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error in countcomments: %s", err.Error())
+	}
+	defer c.Close(ctx)
+	counts := make([]struct {
+		PostID string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}, 0)
+	if err = c.All(ctx, &counts); err != nil {
+		return fmt.Errorf("error in countcomments: %s", err.Error())
+	}
+	byPost := make(map[string]int, len(counts))
+	for _, count := range counts {
+		byPost[count.PostID] = count.Count
+	}
+	for i := range posts {
+		posts[i].Comments = make([]comments.Comment, 0)
+		posts[i].CommentsCount = byPost[posts[i].ID]
+	}
+	return nil
+}
+
+// commentIndexes serve the thread pages in every order, the comment counts
+// and the lookup of a user's comments. The best index follows the sort of
+// pageQuery, so a page reads only its own entries.
+var commentIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "score", Value: -1}, {Key: "_id", Value: -1}}},
+	{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "author.id", Value: 1}}},
+}
+
+// EnsureIndexes creates the comments indexes. Creating an index that exists
+// is a no-op, so it runs on every start.
+func (p *PostsMongoRepo) EnsureIndexes(ctx context.Context) error {
+	if _, err := p.Comments.Indexes().CreateMany(ctx, commentIndexes); err != nil {
+		return fmt.Errorf("error in ensureindexes: %s", err.Error())
+	}
+	return nil
+}
+
+// MigrateComments moves comments embedded in post documents into the
+// comments collection. Comments are upserted by id before they are removed
+// from the post, so an interrupted migration can simply run again.
+func (p *PostsMongoRepo) MigrateComments(ctx context.Context) (int, error) {
+	c, err := p.Posts.Find(ctx, bson.M{"comments.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"comments": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("error in migratecomments: %s", err.Error())
+	}
+	defer c.Close(ctx)
+
+	migrated := 0
+	for c.Next(ctx) {
+		post := Post{}
+		if err = c.Decode(&post); err != nil {
+			return migrated, fmt.Errorf("error in migratecomments: %s", err.Error())
+		}
+		writes := make([]mongo.WriteModel, 0, len(post.Comments))
+		for _, comment := range post.Comments {
+			doc := newCommentDoc(post.ID, comment)
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": doc.ID}).SetReplacement(doc).SetUpsert(true))
+		}
+		if _, err = p.Comments.BulkWrite(ctx, writes); err != nil {
+			return migrated, fmt.Errorf("error in migratecomments: %s", err.Error())
+		}
+		if _, err = p.Posts.UpdateByID(ctx, post.ID, bson.M{"$unset": bson.M{"comments": ""}}); err != nil {
+			return migrated, fmt.Errorf("error in migratecomments: %s", err.Error())
+		}
+		migrated += len(writes)
+	}
+	if err = c.Err(); err != nil {
+		return migrated, fmt.Errorf("error in migratecomments: %s", err.Error())
+	}
+	return migrated, nil
+}
@@ -6,9 +6,20 @@ import (
 
 	"redditclone/pkg/author"
 	"redditclone/pkg/comments"
+	"redditclone/pkg/media"
+	"redditclone/pkg/preview"
 	"redditclone/pkg/vote"
 )
 
+const (
+	TypeText  = "text"
+	TypeLink  = "link"
+	TypeImage = "image"
+)
+
+// DeletedUsername replaces the author of comments left by deleted accounts.
+const DeletedUsername = "[deleted]"
+
 type Post struct {
 	Score            int                `json:"score" bson:"score"`
 	Views            int                `json:"views" bson:"views"`
@@ -18,12 +29,36 @@ type Post struct {
 	Category         string             `json:"category" bson:"category"`
 	URL              string             `json:"url,omitempty" bson:"url,omitempty"`
 	Text             string             `json:"text,omitempty" bson:"text,omitempty"`
+	Preview          *preview.Preview   `json:"preview,omitempty" bson:"preview,omitempty"`
+	Image            *media.Image       `json:"image,omitempty" bson:"image,omitempty"`
 	Votes            []vote.Vote        `json:"votes" bson:"votes"`
-	Comments         []comments.Comment `json:"comments" bson:"comments"`
+	Comments         []comments.Comment `json:"comments" bson:"comments,omitempty"`
+	CommentsCount    int                `json:"commentsCount" bson:"commentsCount,omitempty"`
 	Created          time.Time          `json:"created" bson:"created"`
 	UpvotePercentage int                `json:"upvotePercentage" bson:"upvotePercentage"`
 	ID               string             `json:"id" bson:"_id"`
 	Upvotes          int                `json:"-" bson:"upvotes"`
+	Hidden           bool               `json:"-" bson:"hidden"`
+}
+
+func (p Post) Visible() Post {
+	visible := make([]comments.Comment, 0, len(p.Comments))
+	for _, comment := range p.Comments {
+		if !comment.Hidden {
+			visible = append(visible, comment)
+		}
+	}
+	if len(visible) != len(p.Comments) {
+		p.Comments = visible
+	}
+	return p
+}
+
+// CommentVote is a user's vote on a comment, as kept for the account export.
+type CommentVote struct {
+	PostID    string
+	CommentID string
+	Vote      int
 }
 
 //go:generate mockgen -source posts.go -destination posts_mock.go -package posts PostsRepository
@@ -39,4 +74,13 @@ type PostsRepository interface {
 	GetByUserLogin(ctx context.Context, login string) ([]Post, error)
 	Vote(ctx context.Context, postID string, vote vote.Vote) (Post, error)
 	UnVote(ctx context.Context, username string, postID string) (Post, error)
+	SetHidden(ctx context.Context, postID string, commentID string, hidden bool) error
+	SetPreview(ctx context.Context, postID string, preview preview.Preview) error
+	GetUserActivity(ctx context.Context, userID string) ([]Post, error)
+	AnonymizeComments(ctx context.Context, postID string, userID string) error
+	GetComments(ctx context.Context, postID string, page comments.Page) (comments.CommentsPage, error)
+	GetComment(ctx context.Context, postID string, commentID string) (comments.Comment, error)
+	VoteComment(ctx context.Context, postID string, commentID string, vote vote.Vote) (comments.Comment, error)
+	GetUserCommentVotes(ctx context.Context, userID string) ([]CommentVote, error)
+	UnVoteComments(ctx context.Context, postID string, userID string) error
 }
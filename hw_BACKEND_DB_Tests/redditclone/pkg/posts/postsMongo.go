@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"slices"
 
+	"redditclone/pkg/author"
 	"redditclone/pkg/comments"
+	"redditclone/pkg/outbox"
+	"redditclone/pkg/preview"
 	"redditclone/pkg/vote"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,20 +18,79 @@ import (
 )
 
 type PostsMongoRepo struct {
-	Posts *mongo.Collection
+	Posts    *mongo.Collection
+	Comments *mongo.Collection
+	Outbox   *mongo.Collection
 }
 
 func NewPostsMongoRepo(collection *mongo.Collection) *PostsMongoRepo {
 	repo := &PostsMongoRepo{
-		Posts: collection,
+		Posts:    collection,
+		Comments: collection.Database().Collection("comments"),
 	}
 	return repo
 }
 
+func NewPostsMongoRepoWithOutbox(collection *mongo.Collection, outboxCollection *mongo.Collection) *PostsMongoRepo {
+	repo := NewPostsMongoRepo(collection)
+	repo.Outbox = outboxCollection
+	return repo
+}
+
+// withEvents runs write and stores the events it returns in the outbox
+// within one transaction. Without an outbox collection only write runs.
+func (p *PostsMongoRepo) withEvents(ctx context.Context, write func(ctx context.Context) ([]outbox.Event, error)) error {
+	if p.Outbox == nil {
+		_, err := write(ctx)
+		return err
+	}
+	sess, err := p.Posts.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("error in outbox session: %s", err.Error())
+	}
+	defer sess.EndSession(ctx)
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		events, err := write(sc)
+		if err != nil || len(events) == 0 {
+			return nil, err
+		}
+		docs := make([]interface{}, len(events))
+		for i := range events {
+			docs[i] = events[i]
+		}
+		if _, err = p.Outbox.InsertMany(sc, docs); err != nil {
+			return nil, fmt.Errorf("error in outbox: %s", err.Error())
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func voteEvent(eventType string, post Post, user string, value int) outbox.Event {
+	return outbox.NewEvent(eventType, post.ID, map[string]interface{}{
+		"user":  user,
+		"vote":  value,
+		"score": post.Score,
+	})
+}
+
+// stored strips the comments, which live in their own collection, from a
+// post before it is written.
+func stored(post Post) Post {
+	post.Comments = nil
+	post.CommentsCount = 0
+	return post
+}
+
+func visible(filter bson.M) bson.M {
+	filter["hidden"] = bson.M{"$ne": true}
+	return filter
+}
+
 func (p *PostsMongoRepo) GetAllPosts(ctx context.Context) ([]Post, error) {
 	posts := make([]Post, 0)
 
-	c, err := p.Posts.Find(ctx, bson.M{})
+	c, err := p.Posts.Find(ctx, visible(bson.M{}))
 	if err != nil {
 		return posts, fmt.Errorf("error in getallposts:%s", err.Error())
 	}
@@ -37,28 +99,39 @@ func (p *PostsMongoRepo) GetAllPosts(ctx context.Context) ([]Post, error) {
 	if err != nil {
 		return posts, fmt.Errorf("error in getallposts:%s", err.Error())
 	}
-	return posts, nil
+	return posts, p.countComments(ctx, posts)
 }
 
 func (p *PostsMongoRepo) AddPost(ctx context.Context, post Post) (Post, error) {
 	post.ID = primitive.NewObjectID().Hex()
 	post.Upvotes++
-	newPost, err := bson.Marshal(post)
+	newPost, err := bson.Marshal(stored(post))
 	if err != nil {
 		return Post{}, fmt.Errorf("error in adpost: %s", err.Error())
 	}
 
-	res, err := p.Posts.InsertOne(ctx, newPost)
+	err = p.withEvents(ctx, func(ctx context.Context) ([]outbox.Event, error) {
+		res, err := p.Posts.InsertOne(ctx, newPost)
+		if err != nil {
+			return nil, fmt.Errorf("error in adpost: %s", err.Error())
+		}
+		post.ID = res.InsertedID.(string)
+		return []outbox.Event{outbox.NewEvent(outbox.EventPostCreated, post.ID, map[string]interface{}{
+			"type":     post.Type,
+			"title":    post.Title,
+			"category": post.Category,
+			"author":   post.Author.ID,
+		})}, nil
+	})
 	if err != nil {
-		return Post{}, fmt.Errorf("error in adpost: %s", err.Error())
+		return Post{}, err
 	}
-	post.ID = res.InsertedID.(string)
 	return post, nil
 }
 
 func (p *PostsMongoRepo) UpdatePost(ctx context.Context, post Post) error {
 	updatePost := bson.M{
-		"$set": post,
+		"$set": stored(post),
 	}
 	res, err := p.Posts.UpdateByID(ctx, post.ID, updatePost)
 	if err != nil {
@@ -71,10 +144,21 @@ func (p *PostsMongoRepo) UpdatePost(ctx context.Context, post Post) error {
 }
 
 func (p *PostsMongoRepo) GetPostByID(ctx context.Context, id string) (Post, error) {
+	post, err := p.getPost(ctx, id)
+	if err != nil {
+		return Post{}, err
+	}
+	if err = p.attachComments(ctx, &post); err != nil {
+		return Post{}, err
+	}
+	return post, nil
+}
+
+func (p *PostsMongoRepo) getPost(ctx context.Context, id string) (Post, error) {
 	post := Post{}
 	res := p.Posts.FindOne(ctx, bson.M{"_id": id})
 	if res.Err() != nil {
-		return Post{}, fmt.Errorf("error in getpost: %s", res.Err().Error())
+		return Post{}, fmt.Errorf("error in getpost: %w", res.Err())
 	}
 	err := res.Decode(&post)
 	if err != nil {
@@ -86,7 +170,7 @@ func (p *PostsMongoRepo) GetPostByID(ctx context.Context, id string) (Post, erro
 func (p *PostsMongoRepo) GetCategory(ctx context.Context, category string) ([]Post, error) {
 	posts := make([]Post, 0)
 
-	c, err := p.Posts.Find(ctx, bson.M{"category": category})
+	c, err := p.Posts.Find(ctx, visible(bson.M{"category": category}))
 	if err != nil {
 		return posts, fmt.Errorf("error in getallposts:%s", err.Error())
 	}
@@ -96,68 +180,56 @@ func (p *PostsMongoRepo) GetCategory(ctx context.Context, category string) ([]Po
 	if err != nil {
 		return posts, fmt.Errorf("error in getallposts:%s", err.Error())
 	}
-	return posts, nil
+	return posts, p.countComments(ctx, posts)
 }
 
 func (p *PostsMongoRepo) DeletePost(ctx context.Context, postID string) error {
-	res, err := p.Posts.DeleteOne(ctx, bson.M{"_id": postID})
-	if err != nil {
-		return fmt.Errorf("error in deletePost %s", err.Error())
-	}
-	if res.DeletedCount == 0 {
-		return mongo.ErrNoDocuments
-	}
-	return nil
+	return p.withEvents(ctx, func(ctx context.Context) ([]outbox.Event, error) {
+		res, err := p.Posts.DeleteOne(ctx, bson.M{"_id": postID})
+		if err != nil {
+			return nil, fmt.Errorf("error in deletePost %s", err.Error())
+		}
+		if res.DeletedCount == 0 {
+			return nil, mongo.ErrNoDocuments
+		}
+		if _, err = p.Comments.DeleteMany(ctx, bson.M{"postId": postID}); err != nil {
+			return nil, fmt.Errorf("error in deletePost %s", err.Error())
+		}
+		return []outbox.Event{outbox.NewEvent(outbox.EventPostDeleted, postID, nil)}, nil
+	})
 }
 
 func (p *PostsMongoRepo) AddComment(ctx context.Context, postID string, comment comments.Comment) (Post, error) {
-	comment.ID = primitive.NewObjectID().Hex()
-	update := bson.M{
-		"$push": bson.M{
-			"comments": comment,
-		},
-	}
-	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	res := p.Posts.FindOneAndUpdate(ctx, bson.M{"_id": postID}, update, options)
-	if res.Err() != nil {
-		return Post{}, fmt.Errorf("error in addcomment: %s", res.Err().Error())
+	post, err := p.getPost(ctx, postID)
+	if err != nil {
+		return Post{}, fmt.Errorf("error in addcomment: %w", err)
 	}
-	post := Post{}
-	err := res.Decode(&post)
+	comment.ID = primitive.NewObjectID().Hex()
+	_, err = p.Comments.InsertOne(ctx, newCommentDoc(postID, comment))
 	if err != nil {
 		return Post{}, fmt.Errorf("error in addcomment: %s", err.Error())
 	}
-
+	if err = p.attachComments(ctx, &post); err != nil {
+		return Post{}, err
+	}
 	return post, nil
 }
 
 func (p *PostsMongoRepo) DeleteComment(ctx context.Context, postID string, commentID string) (Post, error) {
-
-	filter := bson.M{
-		"_id": postID,
-	}
-	update := bson.M{
-		"$pull": bson.M{"comments": bson.M{"id": commentID}},
-	}
-	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	res := p.Posts.FindOneAndUpdate(ctx, filter, update, options)
-	if res.Err() != nil {
-		return Post{}, fmt.Errorf("error in DeleteComment: %s", res.Err().Error())
-	}
-	post := Post{}
-	err := res.Decode(&post)
+	res, err := p.Comments.DeleteOne(ctx, bson.M{"_id": commentID, "postId": postID})
 	if err != nil {
 		return Post{}, fmt.Errorf("error in DeleteComment: %s", err.Error())
 	}
-
-	return post, nil
-
+	if res.DeletedCount == 0 {
+		return Post{}, fmt.Errorf("error in DeleteComment: %w", mongo.ErrNoDocuments)
+	}
+	return p.GetPostByID(ctx, postID)
 }
 
 func (p *PostsMongoRepo) GetByUserLogin(ctx context.Context, login string) ([]Post, error) {
 	posts := make([]Post, 0)
 
-	c, err := p.Posts.Find(ctx, bson.M{"author.username": login})
+	c, err := p.Posts.Find(ctx, visible(bson.M{"author.username": login}))
 	if err != nil {
 		return posts, fmt.Errorf("error in geByUserLoginPosts:%s", err.Error())
 	}
@@ -167,7 +239,7 @@ func (p *PostsMongoRepo) GetByUserLogin(ctx context.Context, login string) ([]Po
 	if err != nil {
 		return posts, fmt.Errorf("error in getallposts:%s", err.Error())
 	}
-	return posts, nil
+	return posts, p.countComments(ctx, posts)
 }
 
 func (p *PostsMongoRepo) findVote(votes []vote.Vote, creator string) (int, bool) {
@@ -209,50 +281,155 @@ func (p *PostsMongoRepo) deleteVote(post *Post, index int) {
 }
 
 func (p *PostsMongoRepo) Vote(ctx context.Context, postID string, vote vote.Vote) (Post, error) {
-	post, err := p.GetPostByID(ctx, postID)
+	var post Post
+	err := p.withEvents(ctx, func(ctx context.Context) ([]outbox.Event, error) {
+		var err error
+		post, err = p.getPost(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+
+		if index, voteExist := p.findVote(post.Votes, vote.User); !voteExist {
+			p.addVote(&post, vote)
+		} else {
+			p.updateVote(&post, index, vote)
+		}
+
+		p.updateVoteStats(&post)
+
+		err = p.UpdatePost(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+		return []outbox.Event{voteEvent(outbox.EventPostVoted, post, vote.User, vote.Vote)}, nil
+	})
 	if err != nil {
 		return Post{}, err
 	}
-
-	if index, voteExist := p.findVote(post.Votes, vote.User); !voteExist {
-		p.addVote(&post, vote)
-	} else {
-		p.updateVote(&post, index, vote)
+	if err = p.attachComments(ctx, &post); err != nil {
+		return Post{}, err
 	}
 
-	p.updateVoteStats(&post)
+	return post, nil
+}
+
+func (p *PostsMongoRepo) UnVote(ctx context.Context, username string, postID string) (Post, error) {
+	var post Post
+	err := p.withEvents(ctx, func(ctx context.Context) ([]outbox.Event, error) {
+		var err error
+		post, err = p.getPost(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+
+		var index int
+		var voteExist bool
+		if index, voteExist = p.findVote(post.Votes, username); !voteExist {
+			return nil, fmt.Errorf("no such vote")
+		}
 
-	err = p.UpdatePost(ctx, post)
+		removed := post.Votes[index].Vote
+		post.Score -= removed
+		if removed > 0 && post.Upvotes > 0 {
+			post.Upvotes--
+		}
+		p.deleteVote(&post, index)
+		p.updateVoteStats(&post)
+
+		err = p.UpdatePost(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+		return []outbox.Event{voteEvent(outbox.EventPostUnvoted, post, username, removed)}, nil
+	})
 	if err != nil {
 		return Post{}, err
 	}
+	if err = p.attachComments(ctx, &post); err != nil {
+		return Post{}, err
+	}
 
 	return post, nil
 }
 
-func (p *PostsMongoRepo) UnVote(ctx context.Context, username string, postID string) (Post, error) {
-	post, err := p.GetPostByID(ctx, postID)
+func (p *PostsMongoRepo) SetHidden(ctx context.Context, postID string, commentID string, hidden bool) error {
+	collection := p.Posts
+	filter := bson.M{"_id": postID}
+	if commentID != "" {
+		collection = p.Comments
+		filter = bson.M{"_id": commentID, "postId": postID}
+	}
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"hidden": hidden}})
 	if err != nil {
-		return Post{}, err
+		return fmt.Errorf("error in sethidden: %s", err.Error())
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
+	return nil
+}
 
-	var index int
-	var voteExist bool
-	if index, voteExist = p.findVote(post.Votes, username); !voteExist {
-		return Post{}, fmt.Errorf("no such vote")
+func (p *PostsMongoRepo) SetPreview(ctx context.Context, postID string, preview preview.Preview) error {
+	res, err := p.Posts.UpdateByID(ctx, postID, bson.M{"$set": bson.M{"preview": preview}})
+	if err != nil {
+		return fmt.Errorf("error in setpreview: %s", err.Error())
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
+	return nil
+}
 
-	post.Score -= post.Votes[index].Vote
-	if post.Votes[index].Vote > 0 && post.Upvotes > 0 {
-		post.Upvotes--
+// GetUserActivity returns every post the user wrote, commented on or voted
+// for, hidden ones included. Only the user's own comments are attached.
+func (p *PostsMongoRepo) GetUserActivity(ctx context.Context, userID string) ([]Post, error) {
+	posts := make([]Post, 0)
+
+	own, err := p.Comments.Find(ctx, bson.M{"author.id": userID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return posts, fmt.Errorf("error in getuseractivity:%s", err.Error())
+	}
+	defer own.Close(ctx)
+	docs := make([]commentDoc, 0)
+	if err = own.All(ctx, &docs); err != nil {
+		return posts, fmt.Errorf("error in getuseractivity:%s", err.Error())
+	}
+	byPost := make(map[string][]comments.Comment)
+	commented := make([]string, 0)
+	for _, doc := range docs {
+		if _, ok := byPost[doc.PostID]; !ok {
+			commented = append(commented, doc.PostID)
+		}
+		byPost[doc.PostID] = append(byPost[doc.PostID], doc.comment())
 	}
-	p.deleteVote(&post, index)
-	p.updateVoteStats(&post)
 
-	err = p.UpdatePost(ctx, post)
+	c, err := p.Posts.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"author.id": userID},
+		bson.M{"_id": bson.M{"$in": commented}},
+		bson.M{"votes.user": userID},
+	}})
 	if err != nil {
-		return Post{}, err
+		return posts, fmt.Errorf("error in getuseractivity:%s", err.Error())
 	}
+	defer c.Close(ctx)
 
-	return post, nil
+	err = c.All(ctx, &posts)
+	if err != nil {
+		return posts, fmt.Errorf("error in getuseractivity:%s", err.Error())
+	}
+	for i := range posts {
+		posts[i].Comments = byPost[posts[i].ID]
+	}
+	return posts, nil
+}
+
+// AnonymizeComments is idempotent: a post with no comments left by the user
+// is not an error.
+func (p *PostsMongoRepo) AnonymizeComments(ctx context.Context, postID string, userID string) error {
+	update := bson.M{"$set": bson.M{"author": author.Author{Username: DeletedUsername}}}
+	_, err := p.Comments.UpdateMany(ctx, bson.M{"postId": postID, "author.id": userID}, update)
+	if err != nil {
+		return fmt.Errorf("error in anonymizecomments: %s", err.Error())
+	}
+	return nil
 }
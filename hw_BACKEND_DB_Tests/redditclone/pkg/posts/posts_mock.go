@@ -7,6 +7,7 @@ package posts
 import (
 	context "context"
 	comments "redditclone/pkg/comments"
+	preview "redditclone/pkg/preview"
 	vote "redditclone/pkg/vote"
 	reflect "reflect"
 
@@ -66,6 +67,20 @@ func (mr *MockPostsRepositoryMockRecorder) AddPost(ctx, post interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPost", reflect.TypeOf((*MockPostsRepository)(nil).AddPost), ctx, post)
 }
 
+// AnonymizeComments mocks base method.
+func (m *MockPostsRepository) AnonymizeComments(ctx context.Context, postID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeComments", ctx, postID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnonymizeComments indicates an expected call of AnonymizeComments.
+func (mr *MockPostsRepositoryMockRecorder) AnonymizeComments(ctx, postID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeComments", reflect.TypeOf((*MockPostsRepository)(nil).AnonymizeComments), ctx, postID, userID)
+}
+
 // DeleteComment mocks base method.
 func (m *MockPostsRepository) DeleteComment(ctx context.Context, postID, commentID string) (Post, error) {
 	m.ctrl.T.Helper()
@@ -140,6 +155,36 @@ func (mr *MockPostsRepositoryMockRecorder) GetCategory(ctx, category interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategory", reflect.TypeOf((*MockPostsRepository)(nil).GetCategory), ctx, category)
 }
 
+// GetComment mocks base method.
+func (m *MockPostsRepository) GetComment(ctx context.Context, postID, commentID string) (comments.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetComment", ctx, postID, commentID)
+	ret0, _ := ret[0].(comments.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComment indicates an expected call of GetComment.
+func (mr *MockPostsRepositoryMockRecorder) GetComment(ctx, postID, commentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComment", reflect.TypeOf((*MockPostsRepository)(nil).GetComment), ctx, postID, commentID)
+}
+
+// GetComments mocks base method.
+func (m *MockPostsRepository) GetComments(ctx context.Context, postID string, page comments.Page) (comments.CommentsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetComments", ctx, postID, page)
+	ret0, _ := ret[0].(comments.CommentsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComments indicates an expected call of GetComments.
+func (mr *MockPostsRepositoryMockRecorder) GetComments(ctx, postID, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComments", reflect.TypeOf((*MockPostsRepository)(nil).GetComments), ctx, postID, page)
+}
+
 // GetPostByID mocks base method.
 func (m *MockPostsRepository) GetPostByID(ctx context.Context, id string) (Post, error) {
 	m.ctrl.T.Helper()
@@ -155,6 +200,64 @@ func (mr *MockPostsRepositoryMockRecorder) GetPostByID(ctx, id interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostByID", reflect.TypeOf((*MockPostsRepository)(nil).GetPostByID), ctx, id)
 }
 
+// GetUserActivity mocks base method.
+func (m *MockPostsRepository) GetUserActivity(ctx context.Context, userID string) ([]Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserActivity", ctx, userID)
+	ret0, _ := ret[0].([]Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserActivity indicates an expected call of GetUserActivity.
+func (mr *MockPostsRepositoryMockRecorder) GetUserActivity(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserActivity", reflect.TypeOf((*MockPostsRepository)(nil).GetUserActivity), ctx, userID)
+}
+
+// GetUserCommentVotes mocks base method.
+func (m *MockPostsRepository) GetUserCommentVotes(ctx context.Context, userID string) ([]CommentVote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserCommentVotes", ctx, userID)
+	ret0, _ := ret[0].([]CommentVote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserCommentVotes indicates an expected call of GetUserCommentVotes.
+func (mr *MockPostsRepositoryMockRecorder) GetUserCommentVotes(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserCommentVotes", reflect.TypeOf((*MockPostsRepository)(nil).GetUserCommentVotes), ctx, userID)
+}
+
+// SetHidden mocks base method.
+func (m *MockPostsRepository) SetHidden(ctx context.Context, postID, commentID string, hidden bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHidden", ctx, postID, commentID, hidden)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHidden indicates an expected call of SetHidden.
+func (mr *MockPostsRepositoryMockRecorder) SetHidden(ctx, postID, commentID, hidden interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHidden", reflect.TypeOf((*MockPostsRepository)(nil).SetHidden), ctx, postID, commentID, hidden)
+}
+
+// SetPreview mocks base method.
+func (m *MockPostsRepository) SetPreview(ctx context.Context, postID string, preview preview.Preview) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPreview", ctx, postID, preview)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPreview indicates an expected call of SetPreview.
+func (mr *MockPostsRepositoryMockRecorder) SetPreview(ctx, postID, preview interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPreview", reflect.TypeOf((*MockPostsRepository)(nil).SetPreview), ctx, postID, preview)
+}
+
 // UnVote mocks base method.
 func (m *MockPostsRepository) UnVote(ctx context.Context, username, postID string) (Post, error) {
 	m.ctrl.T.Helper()
@@ -170,6 +273,20 @@ func (mr *MockPostsRepositoryMockRecorder) UnVote(ctx, username, postID interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnVote", reflect.TypeOf((*MockPostsRepository)(nil).UnVote), ctx, username, postID)
 }
 
+// UnVoteComments mocks base method.
+func (m *MockPostsRepository) UnVoteComments(ctx context.Context, postID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnVoteComments", ctx, postID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnVoteComments indicates an expected call of UnVoteComments.
+func (mr *MockPostsRepositoryMockRecorder) UnVoteComments(ctx, postID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnVoteComments", reflect.TypeOf((*MockPostsRepository)(nil).UnVoteComments), ctx, postID, userID)
+}
+
 // UpdatePost mocks base method.
 func (m *MockPostsRepository) UpdatePost(ctx context.Context, post Post) error {
 	m.ctrl.T.Helper()
@@ -198,3 +315,18 @@ func (mr *MockPostsRepositoryMockRecorder) Vote(ctx, postID, vote interface{}) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Vote", reflect.TypeOf((*MockPostsRepository)(nil).Vote), ctx, postID, vote)
 }
+
+// VoteComment mocks base method.
+func (m *MockPostsRepository) VoteComment(ctx context.Context, postID, commentID string, vote vote.Vote) (comments.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VoteComment", ctx, postID, commentID, vote)
+	ret0, _ := ret[0].(comments.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VoteComment indicates an expected call of VoteComment.
+func (mr *MockPostsRepositoryMockRecorder) VoteComment(ctx, postID, commentID, vote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoteComment", reflect.TypeOf((*MockPostsRepository)(nil).VoteComment), ctx, postID, commentID, vote)
+}
@@ -2,14 +2,18 @@ package posts
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"testing"
 
 	"redditclone/pkg/comments"
+	"redditclone/pkg/preview"
 	"redditclone/pkg/vote"
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
 
@@ -17,6 +21,15 @@ var CursorError string = "cursor error"
 var SomeError string = "some error"
 var CALLError string = "c.All error"
 
+func noComments() primitive.D {
+	return mtest.CreateCursorResponse(0, "foo.comments", mtest.FirstBatch)
+}
+
+func listed(post Post) Post {
+	post.Comments = make([]comments.Comment, 0)
+	return post
+}
+
 func ConvertToBSON(t *testing.T, data interface{}) []byte {
 	bytes, err := bson.Marshal(data)
 	if err != nil {
@@ -84,6 +97,12 @@ func funcSwitcher(repo *PostsMongoRepo, funcName string, args ...interface{}) (i
 	case "UnVote":
 		ans, err = repo.UnVote(context.Background(), args[0].(string), args[1].(string))
 		return ans, err
+	case "SetPreview":
+		err = repo.SetPreview(context.Background(), args[0].(string), args[1].(preview.Preview))
+		return ans, err
+	case "SetHidden":
+		err = repo.SetHidden(context.Background(), args[0].(string), args[1].(string), args[2].(bool))
+		return ans, err
 	}
 
 	return ans, err
@@ -142,8 +161,8 @@ func TestGetAllPosts(t *testing.T) {
 		mt:            mt,
 		funcName:      "GetAllPosts",
 		testName:      "cursorTestOK",
-		expected:      []Post{Post1, Post2},
-		mockResponses: []primitive.D{first, second, killCursors},
+		expected:      []Post{listed(Post1), listed(Post2)},
+		mockResponses: []primitive.D{first, second, killCursors, noComments()},
 	}
 	EqualityTesting(test)
 
@@ -210,6 +229,11 @@ func TestDeletePost(t *testing.T) {
 				{Key: "acknowledged", Value: true},
 				{Key: "n", Value: 1},
 			},
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "acknowledged", Value: true},
+				{Key: "n", Value: 3},
+			},
 		},
 		args: []interface{}{"1"},
 	}
@@ -263,9 +287,9 @@ func TestGetPostByID(t *testing.T) {
 		mt:            mt,
 		funcName:      "GetPostByID",
 		testName:      "OK",
-		expected:      Post{ID: "1"},
+		expected:      listed(Post{ID: "1"}),
 		args:          []interface{}{"1"},
-		mockResponses: []primitive.D{mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch, *primitivePost)},
+		mockResponses: []primitive.D{mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch, *primitivePost), mtest.CreateSuccessResponse(), noComments()},
 	}
 	EqualityTesting(test)
 
@@ -302,8 +326,8 @@ func TestGetByCategory(t *testing.T) {
 		mt:            mt,
 		funcName:      "GetCategory",
 		testName:      "cursorTestOK",
-		expected:      []Post{Post1, Post2},
-		mockResponses: []primitive.D{first, second, killCursors},
+		expected:      []Post{listed(Post1), listed(Post2)},
+		mockResponses: []primitive.D{first, second, killCursors, noComments()},
 		args:          []interface{}{"news"},
 	}
 	EqualityTesting(test)
@@ -321,80 +345,309 @@ func TestGetByCategory(t *testing.T) {
 
 func TestAddComment(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
-	// defer mt.Close()
-
-	test := Testing{
-		t:        t,
-		mt:       mt,
-		funcName: "AddComment",
-		testName: "OK",
-		expected: Post{ID: "1"},
-		args:     []interface{}{"1", comments.Comment{}},
-		mockResponses: []primitive.D{bson.D{
-			{Key: "ok", Value: 1},
-			{Key: "value", Value: bson.D{
-				{Key: "_id", Value: "1"},
-			},
-			},
-		}},
-	}
-	EqualityTesting(test)
 
-	test.mockResponses = []primitive.D{bson.D{{Key: "ok", Value: 0}, {Key: "value", Value: bson.D{
-		{Key: "_id", Value: "1"},
-	}}}}
-	test.testName = SomeError
-	ErrorTesting(test)
+	primitivePost := ConvertToPrimtive(t, ConvertToBSON(t, Post{ID: "1"}))
+	added := ConvertToPrimtive(t, ConvertToBSON(t, commentDoc{ID: "5", PostID: "1", Body: "hi"}))
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch, *primitivePost),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, "foo.comments", mtest.FirstBatch, *added),
+		)
+		post, err := repo.AddComment(context.Background(), "1", comments.Comment{Body: "hi"})
+		assert.Nil(t, err)
+		assert.Equal(t, []comments.Comment{{ID: "5", Body: "hi"}}, post.Comments)
+		assert.Equal(t, 1, post.CommentsCount)
+	})
 
-	test.mockResponses = []primitive.D{bson.D{
-		{Key: "ok", Value: 1},
-		{Key: "value", Value: bson.D{
-			{Key: "author", Value: "1"},
-		},
-		},
-	}}
-	test.testName = "decode error"
-	ErrorTesting(test)
+	mt.Run("no post", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch))
+		_, err := repo.AddComment(context.Background(), "1", comments.Comment{})
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
 
+	mt.Run("insert error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch, *primitivePost),
+			mtest.CreateSuccessResponse(),
+			bson.D{{Key: "ok", Value: 0}},
+		)
+		_, err := repo.AddComment(context.Background(), "1", comments.Comment{})
+		assert.NotNil(t, err)
+	})
 }
 
 func TestDeleteComment(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	// defer mt.Close()
 
+	primitivePost := ConvertToPrimtive(t, ConvertToBSON(t, Post{ID: "1"}))
 	test := Testing{
 		t:        t,
 		mt:       mt,
 		funcName: "DeleteComment",
 		testName: "OK",
-		expected: Post{ID: "1"},
+		expected: listed(Post{ID: "1"}),
 		args:     []interface{}{"1", "2"},
-		mockResponses: []primitive.D{bson.D{
-			{Key: "ok", Value: 1},
-			{Key: "value", Value: bson.D{
-				{Key: "_id", Value: "1"},
-			},
-			},
-		}},
+		mockResponses: []primitive.D{
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch, *primitivePost),
+			mtest.CreateSuccessResponse(),
+			noComments(),
+		},
 	}
 	EqualityTesting(test)
 
-	test.mockResponses = []primitive.D{bson.D{{Key: "ok", Value: 0}, {Key: "value", Value: bson.D{
-		{Key: "_id", Value: "1"},
-	}}}}
-	test.testName = "error"
+	test.mockResponses = []primitive.D{bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}}}
+	test.testName = "not found"
 	ErrorTesting(test)
 
-	test.mockResponses = []primitive.D{bson.D{
-		{Key: "ok", Value: 1},
-		{Key: "value", Value: bson.D{
-			{Key: "author", Value: "1"},
-		},
-		},
-	}}
-	test.testName = "decode error"
+	test.mockResponses = []primitive.D{bson.D{{Key: "ok", Value: 0}}}
+	test.testName = "error"
 	ErrorTesting(test)
+}
+
+func TestGetComments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	docs := make([]primitive.D, 0, 3)
+	for _, id := range []string{"c", "b", "a"} {
+		docs = append(docs, *ConvertToPrimtive(t, ConvertToBSON(t, commentDoc{ID: id, PostID: "1", Score: 1})))
+	}
+
+	mt.Run("first page", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.comments", mtest.FirstBatch, docs...))
+		page, err := repo.GetComments(context.Background(), "1", comments.Page{Sort: comments.SortNew, Limit: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, []comments.Comment{{ID: "c", Score: 1}, {ID: "b", Score: 1}}, page.Comments)
+		assert.Equal(t, comments.Cursor{ID: "b"}.Encode(), page.Next)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, `{"_id": {"$numberInt":"-1"}}`, cmd.Lookup("sort").String())
+		assert.Equal(t, int64(3), cmd.Lookup("limit").Int64())
+	})
+
+	mt.Run("last page", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.comments", mtest.FirstBatch, docs[2]))
+		after := &comments.Cursor{Score: 1, ID: "b"}
+		page, err := repo.GetComments(context.Background(), "1", comments.Page{Sort: comments.SortBest, After: after, Limit: 2})
+		assert.Nil(t, err)
+		assert.Len(t, page.Comments, 1)
+		assert.Empty(t, page.Next)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").String()
+		assert.Contains(t, filter, `"$or"`)
+		assert.Contains(t, filter, `"_id": {"$lt": "b"}`)
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := repo.GetComments(context.Background(), "1", comments.Page{Sort: comments.SortOld, Limit: 2})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestVoteComment(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("upvote", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		voted := commentDoc{ID: "a", PostID: "1", Score: 2, Votes: []vote.Vote{{User: "u1", Vote: 1}, {User: "u2", Vote: 1}}}
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: *ConvertToPrimtive(t, ConvertToBSON(t, voted))}})
+		comment, err := repo.VoteComment(context.Background(), "1", "a", vote.Vote{User: "u2", Vote: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, comments.Comment{ID: "a", Score: 2}, comment)
+
+		// the score is summed from the votes in the same update
+		update := mt.GetStartedEvent().Command.Lookup("update").String()
+		assert.Contains(t, update, `"$concatArrays"`)
+		assert.Contains(t, update, `"score": {"$sum": "$votes.vote"}`)
+	})
+
+	mt.Run("unvote", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: *ConvertToPrimtive(t, ConvertToBSON(t, commentDoc{ID: "a"}))}})
+		_, err := repo.VoteComment(context.Background(), "1", "a", vote.Vote{User: "u2"})
+		assert.Nil(t, err)
+		assert.NotContains(t, mt.GetStartedEvent().Command.Lookup("update").String(), `"$concatArrays"`)
+	})
+
+	mt.Run("no comment", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+		_, err := repo.VoteComment(context.Background(), "1", "a", vote.Vote{User: "u2", Vote: 1})
+		assert.True(t, errors.Is(err, mongo.ErrNoDocuments))
+	})
+}
+
+func TestUserCommentVotes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("get", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		voted := commentDoc{ID: "a", PostID: "1", Hidden: true, Votes: []vote.Vote{{User: "u1", Vote: 1}, {User: "u2", Vote: -1}}}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.comments", mtest.FirstBatch, *ConvertToPrimtive(t, ConvertToBSON(t, voted))))
+		votes, err := repo.GetUserCommentVotes(context.Background(), "u2")
+		assert.Nil(t, err)
+		assert.Equal(t, []CommentVote{{PostID: "1", CommentID: "a", Vote: -1}}, votes)
+
+		// hidden comments are not filtered out
+		assert.Equal(t, `{"votes.user": "u2"}`, mt.GetStartedEvent().Command.Lookup("filter").String())
+	})
+
+	mt.Run("unvote", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}, {Key: "nModified", Value: 2}})
+		err := repo.UnVoteComments(context.Background(), "1", "u2")
+		assert.Nil(t, err)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "comments", cmd.Lookup("update").StringValue())
+		update := cmd.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "1", update.Lookup("q", "postId").StringValue())
+		assert.Equal(t, "u2", update.Lookup("q", "votes.user").StringValue())
+		assert.True(t, update.Lookup("multi").Boolean())
+		// the same pipeline as VoteComment, without a new vote
+		pipeline := update.Lookup("u").String()
+		assert.NotContains(t, pipeline, `"$concatArrays"`)
+		assert.Contains(t, pipeline, `"score": {"$sum": "$votes.vote"}`)
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		assert.NotNil(t, repo.UnVoteComments(context.Background(), "1", "u2"))
+	})
+}
+
+// sortDocs orders docs the way the server would for a sort on score and _id.
+func sortDocs(docs []commentDoc, spec bson.Raw) []string {
+	keys, _ := spec.Elements()
+	sorted := append([]commentDoc(nil), docs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for _, key := range keys {
+			a, b := sorted[i], sorted[j]
+			var less, equal bool
+			switch key.Key() {
+			case "score":
+				less, equal = a.Score < b.Score, a.Score == b.Score
+			case "_id":
+				less, equal = a.ID < b.ID, a.ID == b.ID
+			}
+			if equal {
+				continue
+			}
+			return less == (key.Value().Int32() > 0)
+		}
+		return false
+	})
+	ids := make([]string, 0, len(sorted))
+	for _, doc := range sorted {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func TestBestOrder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("best differs from new", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		// a is the oldest comment and the only one voted up
+		thread := []commentDoc{{ID: "a", PostID: "1"}, {ID: "b", PostID: "1"}, {ID: "c", PostID: "1"}}
+		voted := thread[0]
+		voted.Votes = []vote.Vote{{User: "u1", Vote: 1}, {User: "u2", Vote: 1}}
+		voted.Score = 2
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: *ConvertToPrimtive(t, ConvertToBSON(t, voted))}})
+		comment, err := repo.VoteComment(context.Background(), "1", "a", vote.Vote{User: "u2", Vote: 1})
+		assert.Nil(t, err)
+		thread[0].Score = comment.Score
+		mt.ClearEvents()
+
+		orders := make(map[string][]string)
+		for _, order := range []string{comments.SortBest, comments.SortNew} {
+			mt.AddMockResponses(noComments())
+			_, err = repo.GetComments(context.Background(), "1", comments.Page{Sort: order, Limit: 10})
+			assert.Nil(t, err)
+			orders[order] = sortDocs(thread, mt.GetStartedEvent().Command.Lookup("sort").Document())
+		}
+		assert.Equal(t, []string{"a", "c", "b"}, orders[comments.SortBest])
+		assert.Equal(t, []string{"c", "b", "a"}, orders[comments.SortNew])
+	})
+}
+
+func TestMigrateComments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		legacy := ConvertToPrimtive(t, ConvertToBSON(t, Post{ID: "1", Comments: []comments.Comment{{ID: "a"}, {ID: "b"}}}))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, *legacy),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+		migrated, err := repo.MigrateComments(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 2, migrated)
+
+		started := mt.GetAllStartedEvents()
+		collections := make([]string, 0, len(started))
+		for _, event := range started {
+			collections = append(collections, event.CommandName+" "+event.Command.Lookup(event.CommandName).StringValue())
+		}
+		assert.Equal(t, []string{"find " + mt.Coll.Name(), "update comments", "update " + mt.Coll.Name()}, collections)
+	})
 
+	mt.Run("bulk write error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		legacy := ConvertToPrimtive(t, ConvertToBSON(t, Post{ID: "1", Comments: []comments.Comment{{ID: "a"}}}))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, *legacy),
+			bson.D{{Key: "ok", Value: 0}},
+		)
+		migrated, err := repo.MigrateComments(context.Background())
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, migrated)
+	})
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		assert.Nil(t, repo.EnsureIndexes(context.Background()))
+
+		command := mt.GetStartedEvent().Command
+		assert.Equal(t, "comments", command.Lookup("createIndexes").StringValue())
+		values, err := command.Lookup("indexes").Array().Values()
+		assert.Nil(t, err)
+		keys := make([]string, 0, len(values))
+		for _, value := range values {
+			keys = append(keys, value.Document().Lookup("key").Document().String())
+		}
+		assert.Equal(t, []string{
+			`{"postId": {"$numberInt":"1"},"score": {"$numberInt":"-1"},"_id": {"$numberInt":"-1"}}`,
+			`{"postId": {"$numberInt":"1"},"_id": {"$numberInt":"1"}}`,
+			`{"author.id": {"$numberInt":"1"}}`,
+		}, keys)
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		assert.NotNil(t, repo.EnsureIndexes(context.Background()))
+	})
 }
 
 func TestGetByUserLogin(t *testing.T) {
@@ -419,8 +672,8 @@ func TestGetByUserLogin(t *testing.T) {
 		mt:            mt,
 		funcName:      "GetByUserLogin",
 		testName:      "cursorTestOK",
-		expected:      []Post{Post1, Post2},
-		mockResponses: []primitive.D{first, second, killCursors},
+		expected:      []Post{listed(Post1), listed(Post2)},
+		mockResponses: []primitive.D{first, second, killCursors, noComments()},
 		args:          []interface{}{"asd"},
 	}
 	EqualityTesting(test)
@@ -477,11 +730,14 @@ func TestVote(t *testing.T) {
 		},
 	}
 
+	test.mockResponses = append(test.mockResponses, noComments())
+
 	voteTmp.Vote = -1
 	Post1.UpvotePercentage = 0
 	Post1.Upvotes--
 	Post1.Votes[0].Vote = -1
 	Post1.Score = -1
+	Post1.Comments = make([]comments.Comment, 0)
 
 	test.args[1] = voteTmp
 	test.expected = Post1
@@ -533,12 +789,14 @@ func TestUnVote(t *testing.T) {
 			{Key: "ok", Value: 1},
 			{Key: "nModified", Value: 1},
 		},
+		noComments(),
 	}
 
 	Post1.UpvotePercentage = 0
 	Post1.Upvotes--
 	Post1.Votes = make([]vote.Vote, 0)
 	Post1.Score = 0
+	Post1.Comments = make([]comments.Comment, 0)
 
 	test.expected = Post1
 	test.testName = "ok"
@@ -554,3 +812,126 @@ func TestUnVote(t *testing.T) {
 	}
 	ErrorTesting(test)
 }
+
+func TestSetHidden(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	// defer mt.Close()
+
+	test := Testing{
+		t:        t,
+		mt:       mt,
+		funcName: "SetHidden",
+		testName: "post OK",
+		mockResponses: []primitive.D{
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "n", Value: 1},
+				{Key: "nModified", Value: 1},
+			},
+		},
+		args: []interface{}{"1", "", true},
+	}
+	EqualityTesting(test)
+
+	test.testName = "comment OK"
+	test.args = []interface{}{"1", "2", true}
+	EqualityTesting(test)
+
+	test.mockResponses = []primitive.D{bson.D{
+		{Key: "ok", Value: 1},
+		{Key: "n", Value: 0},
+	}}
+	test.testName = "not found"
+	ErrorTesting(test)
+
+	test.mockResponses = []primitive.D{bson.D{
+		{Key: "ok", Value: 0},
+	}}
+	test.testName = SomeError
+	ErrorTesting(test)
+}
+
+func TestSetPreview(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	// defer mt.Close()
+
+	test := Testing{
+		t:        t,
+		mt:       mt,
+		funcName: "SetPreview",
+		testName: "OK",
+		mockResponses: []primitive.D{
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "n", Value: 1},
+				{Key: "nModified", Value: 1},
+			},
+		},
+		args: []interface{}{"1", preview.Preview{Title: "title"}},
+	}
+	EqualityTesting(test)
+
+	test.mockResponses = []primitive.D{bson.D{
+		{Key: "ok", Value: 1},
+		{Key: "n", Value: 0},
+	}}
+	test.testName = "not found"
+	ErrorTesting(test)
+
+	test.mockResponses = []primitive.D{bson.D{
+		{Key: "ok", Value: 0},
+	}}
+	test.testName = SomeError
+	ErrorTesting(test)
+}
+
+func TestAddPostWithOutbox(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewPostsMongoRepoWithOutbox(mt.Coll, mt.DB.Collection("outbox"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		post, err := repo.AddPost(context.Background(), Post{Title: "title"})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, post.ID)
+
+		started := mt.GetAllStartedEvents()
+		commands := make([]string, 0, len(started))
+		for _, event := range started {
+			commands = append(commands, event.CommandName)
+		}
+		assert.Equal(t, []string{"insert", "insert", "commitTransaction"}, commands)
+	})
+
+	mt.Run("outbox error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepoWithOutbox(mt.Coll, mt.DB.Collection("outbox"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), bson.D{{Key: "ok", Value: 0}}, mtest.CreateSuccessResponse())
+		_, err := repo.AddPost(context.Background(), Post{Title: "title"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAnonymizeComments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+		err := repo.AnonymizeComments(context.Background(), "1", "2")
+		assert.Nil(t, err)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "comments", cmd.Lookup("update").StringValue())
+		update := cmd.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "1", update.Lookup("q", "postId").StringValue())
+		assert.Equal(t, "2", update.Lookup("q", "author.id").StringValue())
+		assert.True(t, update.Lookup("multi").Boolean())
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		repo := NewPostsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		err := repo.AnonymizeComments(context.Background(), "1", "2")
+		assert.NotNil(t, err)
+	})
+}
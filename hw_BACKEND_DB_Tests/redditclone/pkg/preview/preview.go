@@ -0,0 +1,7 @@
+package preview
+
+type Preview struct {
+	Title       string `json:"title,omitempty" bson:"title,omitempty"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	Image       string `json:"image,omitempty" bson:"image,omitempty"`
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/author"
+)
+
+const (
+	TypePost    = "post"
+	TypeComment = "comment"
+)
+
+type Report struct {
+	ID        string        `json:"id" bson:"_id"`
+	Type      string        `json:"type" bson:"type"`
+	PostID    string        `json:"postId" bson:"postId"`
+	CommentID string        `json:"commentId,omitempty" bson:"commentId"`
+	Reporter  author.Author `json:"reporter" bson:"reporter"`
+	Reason    string        `json:"reason" bson:"reason"`
+	Created   time.Time     `json:"created" bson:"created"`
+}
+
+type SimpleReport struct {
+	Reason string `json:"reason"`
+}
+
+type QueueItem struct {
+	Type      string   `json:"type"`
+	PostID    string   `json:"postId"`
+	CommentID string   `json:"commentId,omitempty"`
+	Reports   []Report `json:"reports"`
+}
+
+//go:generate mockgen -source report.go -destination report_mock.go -package report ReportRepository
+type ReportRepository interface {
+	AddReport(ctx context.Context, report Report) (int64, error)
+	GetOpen(ctx context.Context) ([]Report, error)
+	Resolve(ctx context.Context, postID string, commentID string) error
+	ResolvePost(ctx context.Context, postID string) error
+}
+
+func Queue(reports []Report) []QueueItem {
+	queue := make([]QueueItem, 0)
+	index := make(map[[2]string]int)
+	for _, report := range reports {
+		itemKey := [2]string{report.PostID, report.CommentID}
+		i, ok := index[itemKey]
+		if !ok {
+			i = len(queue)
+			index[itemKey] = i
+			queue = append(queue, QueueItem{
+				Type:      report.Type,
+				PostID:    report.PostID,
+				CommentID: report.CommentID,
+				Reports:   make([]Report, 0, 1),
+			})
+		}
+		queue[i].Reports = append(queue[i].Reports, report)
+	}
+	return queue
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReportsMongoRepo struct {
+	Reports *mongo.Collection
+}
+
+func NewReportsMongoRepo(collection *mongo.Collection) *ReportsMongoRepo {
+	return &ReportsMongoRepo{
+		Reports: collection,
+	}
+}
+
+// reportIndexes keep one report per reporter and content, so concurrent
+// reports from the same user cannot both be counted.
+var reportIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "commentId", Value: 1}, {Key: "reporter.id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+// EnsureIndexes creates the reports indexes. Creating an index that exists
+// is a no-op, so it runs on every start.
+func (r *ReportsMongoRepo) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.Reports.Indexes().CreateMany(ctx, reportIndexes); err != nil {
+		return fmt.Errorf("error in ensureindexes: %s", err.Error())
+	}
+	return nil
+}
+
+// AddReport returns the number of users who reported the content. A user
+// who reported it already, even in a concurrent call, is counted once.
+func (r *ReportsMongoRepo) AddReport(ctx context.Context, report Report) (int64, error) {
+	report.ID = primitive.NewObjectID().Hex()
+	filter := bson.M{
+		"postId":      report.PostID,
+		"commentId":   report.CommentID,
+		"reporter.id": report.Reporter.ID,
+	}
+	update := bson.M{
+		"$setOnInsert": report,
+	}
+	_, err := r.Reports.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return 0, fmt.Errorf("error in addreport: %s", err.Error())
+	}
+	count, err := r.Reports.CountDocuments(ctx, bson.M{
+		"postId":    report.PostID,
+		"commentId": report.CommentID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error in addreport: %s", err.Error())
+	}
+	return count, nil
+}
+
+func (r *ReportsMongoRepo) GetOpen(ctx context.Context) ([]Report, error) {
+	reports := make([]Report, 0)
+	c, err := r.Reports.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created": 1}))
+	if err != nil {
+		return reports, fmt.Errorf("error in getopen: %s", err.Error())
+	}
+	defer c.Close(ctx)
+	err = c.All(ctx, &reports)
+	if err != nil {
+		return reports, fmt.Errorf("error in getopen: %s", err.Error())
+	}
+	return reports, nil
+}
+
+func (r *ReportsMongoRepo) Resolve(ctx context.Context, postID string, commentID string) error {
+	_, err := r.Reports.DeleteMany(ctx, bson.M{"postId": postID, "commentId": commentID})
+	if err != nil {
+		return fmt.Errorf("error in resolve: %s", err.Error())
+	}
+	return nil
+}
+
+func (r *ReportsMongoRepo) ResolvePost(ctx context.Context, postID string) error {
+	_, err := r.Reports.DeleteMany(ctx, bson.M{"postId": postID})
+	if err != nil {
+		return fmt.Errorf("error in resolvepost: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: report.go
+
+// Package report is a generated GoMock package.
+package report
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockReportRepository is a mock of ReportRepository interface.
+type MockReportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportRepositoryMockRecorder
+}
+
+// MockReportRepositoryMockRecorder is the mock recorder for MockReportRepository.
+type MockReportRepositoryMockRecorder struct {
+	mock *MockReportRepository
+}
+
+// NewMockReportRepository creates a new mock instance.
+func NewMockReportRepository(ctrl *gomock.Controller) *MockReportRepository {
+	mock := &MockReportRepository{ctrl: ctrl}
+	mock.recorder = &MockReportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportRepository) EXPECT() *MockReportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddReport mocks base method.
+func (m *MockReportRepository) AddReport(ctx context.Context, report Report) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReport", ctx, report)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddReport indicates an expected call of AddReport.
+func (mr *MockReportRepositoryMockRecorder) AddReport(ctx, report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReport", reflect.TypeOf((*MockReportRepository)(nil).AddReport), ctx, report)
+}
+
+// GetOpen mocks base method.
+func (m *MockReportRepository) GetOpen(ctx context.Context) ([]Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOpen", ctx)
+	ret0, _ := ret[0].([]Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOpen indicates an expected call of GetOpen.
+func (mr *MockReportRepositoryMockRecorder) GetOpen(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpen", reflect.TypeOf((*MockReportRepository)(nil).GetOpen), ctx)
+}
+
+// Resolve mocks base method.
+func (m *MockReportRepository) Resolve(ctx context.Context, postID, commentID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", ctx, postID, commentID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockReportRepositoryMockRecorder) Resolve(ctx, postID, commentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockReportRepository)(nil).Resolve), ctx, postID, commentID)
+}
+
+// ResolvePost mocks base method.
+func (m *MockReportRepository) ResolvePost(ctx context.Context, postID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolvePost", ctx, postID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolvePost indicates an expected call of ResolvePost.
+func (mr *MockReportRepositoryMockRecorder) ResolvePost(ctx, postID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolvePost", reflect.TypeOf((*MockReportRepository)(nil).ResolvePost), ctx, postID)
+}
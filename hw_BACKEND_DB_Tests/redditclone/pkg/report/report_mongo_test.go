@@ -0,0 +1,65 @@
+package report
+
+import (
+	"context"
+	"testing"
+
+	"redditclone/pkg/author"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func counted(n int32) bson.D {
+	return mtest.CreateCursorResponse(0, "foo.reports", mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+func TestAddReport(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	report := Report{Type: TypePost, PostID: "1", Reporter: author.Author{ID: "2"}}
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewReportsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 0}}, counted(3))
+		count, err := repo.AddReport(context.Background(), report)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	// a concurrent report from the same user lost the race to the unique index
+	mt.Run("already reported", func(mt *mtest.T) {
+		repo := NewReportsMongoRepo(mt.Coll)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}), counted(1))
+		count, err := repo.AddReport(context.Background(), report)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		repo := NewReportsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := repo.AddReport(context.Background(), report)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("OK", func(mt *mtest.T) {
+		repo := NewReportsMongoRepo(mt.Coll)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		assert.Nil(t, repo.EnsureIndexes(context.Background()))
+
+		index := mt.GetStartedEvent().Command.Lookup("indexes").Array().Index(0).Value().Document()
+		assert.Equal(t, `{"postId": {"$numberInt":"1"},"commentId": {"$numberInt":"1"},"reporter.id": {"$numberInt":"1"}}`, index.Lookup("key").Document().String())
+		assert.True(t, index.Lookup("unique").Boolean())
+	})
+
+	mt.Run("error", func(mt *mtest.T) {
+		repo := NewReportsMongoRepo(mt.Coll)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		assert.NotNil(t, repo.EnsureIndexes(context.Background()))
+	})
+}
@@ -14,6 +14,7 @@ func ServerResponseWriter(w http.ResponseWriter, header int, payload interface{}
 		w.WriteHeader(500)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(header)
 	_, err = w.Write(JSONbytes)
 	if err != nil {
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"net/http"
+
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/middleware"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+
+	"github.com/gorilla/mux"
+)
+
+type Access int
+
+const (
+	Public Access = iota
+	Authenticated
+	Owner
+	Moderator
+	Admin
+)
+
+func (a Access) String() string {
+	switch a {
+	case Public:
+		return "public"
+	case Authenticated:
+		return "authenticated"
+	case Owner:
+		return "owner"
+	case Moderator:
+		return "moderator"
+	case Admin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+type Route struct {
+	Path    string
+	Methods []string
+	Access  Access
+	Handler http.Handler
+}
+
+// Builder collects routes with their access level and wraps each handler in
+// the matching middleware chain when the routes are mounted.
+type Builder struct {
+	Key      key.Key
+	Logger   logger.Logger
+	Sessions session.SessionManager
+	Users    user.UserRepo
+	Posts    posts.PostsRepository
+	routes   []Route
+}
+
+func NewBuilder(contextKey key.Key, logger logger.Logger, sessions session.SessionManager, users user.UserRepo, postsRepo posts.PostsRepository) *Builder {
+	return &Builder{
+		Key:      contextKey,
+		Logger:   logger,
+		Sessions: sessions,
+		Users:    users,
+		Posts:    postsRepo,
+	}
+}
+
+func (b *Builder) Handle(access Access, path string, handler http.Handler, methods ...string) *Builder {
+	b.routes = append(b.routes, Route{
+		Path:    path,
+		Methods: methods,
+		Access:  access,
+		Handler: handler,
+	})
+	return b
+}
+
+func (b *Builder) HandleFunc(access Access, path string, handler http.HandlerFunc, methods ...string) *Builder {
+	return b.Handle(access, path, handler, methods...)
+}
+
+func (b *Builder) Routes() []Route {
+	return append([]Route(nil), b.routes...)
+}
+
+func (b *Builder) Chain(access Access, handler http.Handler) http.Handler {
+	switch access {
+	case Public:
+		return handler
+	case Owner:
+		handler = middleware.Authorize(b.Key, b.Logger, b.Posts, handler)
+	case Moderator:
+		handler = middleware.Moderator(b.Key, b.Logger, b.Users, handler)
+	case Admin:
+		handler = middleware.Admin(b.Key, b.Logger, b.Users, handler)
+	}
+	handler = middleware.Authenticate(b.Key, b.Logger, b.Users, handler)
+	return middleware.JWT(b.Key, b.Logger, b.Sessions, handler)
+}
+
+func (b *Builder) Mount(r *mux.Router) {
+	for _, route := range b.routes {
+		registered := r.Handle(route.Path, b.Chain(route.Access, route.Handler))
+		if len(route.Methods) > 0 {
+			registered.Methods(route.Methods...)
+		}
+	}
+}
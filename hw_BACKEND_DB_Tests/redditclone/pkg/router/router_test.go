@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"redditclone/pkg/key"
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/session"
+	"redditclone/pkg/user"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var contextKey key.Key = "author"
+	b := NewBuilder(contextKey, logger.NopLogger{}, session.NewMockSessionManager(ctrl), user.NewMockUserRepo(ctrl), posts.NewMockPostsRepository(ctrl))
+	called := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called++
+	})
+	b.Handle(Public, "/open", handler, "GET")
+	b.Handle(Authenticated, "/closed", handler, "POST")
+	b.Handle(Moderator, "/moderate", handler, "POST")
+
+	r := mux.NewRouter()
+	b.Mount(r)
+
+	// public route reaches the handler
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/open", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 1, called)
+
+	// method is restricted
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/open", nil))
+	assert.Equal(t, 405, w.Code)
+
+	// protected routes stop at the jwt middleware without a token
+	for _, path := range []string{"/closed", "/moderate"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		assert.Equal(t, 400, w.Code)
+	}
+	assert.Equal(t, 1, called)
+
+	assert.Equal(t, []Access{Public, Authenticated, Moderator}, []Access{b.Routes()[0].Access, b.Routes()[1].Access, b.Routes()[2].Access})
+}
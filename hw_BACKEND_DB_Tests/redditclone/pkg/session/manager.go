@@ -9,4 +9,6 @@ type SessionManager interface {
 	AddNewSess(ctx context.Context, id string, exp int64, iat int64) error
 	GetExp(ctx context.Context, id string, iat int64) int64
 	DeleteSess(ctx context.Context, userID string, iat int64) error
+	GetSessions(ctx context.Context, userID string) ([]SessionDB, error)
+	DeleteUserSessions(ctx context.Context, userID string) error
 }
@@ -62,6 +62,20 @@ func (mr *MockSessionManagerMockRecorder) DeleteSess(ctx, userID, iat interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSess", reflect.TypeOf((*MockSessionManager)(nil).DeleteSess), ctx, userID, iat)
 }
 
+// DeleteUserSessions mocks base method.
+func (m *MockSessionManager) DeleteUserSessions(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserSessions", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserSessions indicates an expected call of DeleteUserSessions.
+func (mr *MockSessionManagerMockRecorder) DeleteUserSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserSessions", reflect.TypeOf((*MockSessionManager)(nil).DeleteUserSessions), ctx, userID)
+}
+
 // GetExp mocks base method.
 func (m *MockSessionManager) GetExp(ctx context.Context, id string, iat int64) int64 {
 	m.ctrl.T.Helper()
@@ -90,6 +104,21 @@ func (mr *MockSessionManagerMockRecorder) GetKey() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKey", reflect.TypeOf((*MockSessionManager)(nil).GetKey))
 }
 
+// GetSessions mocks base method.
+func (m *MockSessionManager) GetSessions(ctx context.Context, userID string) ([]SessionDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userID)
+	ret0, _ := ret[0].([]SessionDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockSessionManagerMockRecorder) GetSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockSessionManager)(nil).GetSessions), ctx, userID)
+}
+
 // SetKey mocks base method.
 func (m *MockSessionManager) SetKey(arg0 interface{}) {
 	m.ctrl.T.Helper()
@@ -8,11 +8,11 @@ import (
 )
 
 type SessionDB struct {
-	ID         int
-	IAT        int64
-	Expiration int64
-	UserID     string
-	UserAgent  string
+	ID         int    `json:"id"`
+	IAT        int64  `json:"iat"`
+	Expiration int64  `json:"expiration"`
+	UserID     string `json:"userId"`
+	UserAgent  string `json:"userAgent,omitempty"`
 }
 
 type SessionSQL struct {
@@ -80,3 +80,34 @@ func (s *SessionSQL) DeleteSess(ctx context.Context, userID string, iat int64) e
 	}
 	return nil
 }
+
+func (s *SessionSQL) GetSessions(ctx context.Context, userID string) ([]SessionDB, error) {
+	sessions := make([]SessionDB, 0)
+	rows, err := s.Sessions.QueryContext(ctx, "SELECT id, iat, expiration, userid FROM sessions WHERE userid = ?",
+		userID,
+	)
+	if err != nil {
+		return sessions, fmt.Errorf("error in session getsessions %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		sess := SessionDB{}
+		err = rows.Scan(&sess.ID, &sess.IAT, &sess.Expiration, &sess.UserID)
+		if err != nil {
+			return sessions, fmt.Errorf("error in session getsessions %w", err)
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SessionSQL) DeleteUserSessions(ctx context.Context, userID string) error {
+	_, err := s.Sessions.ExecContext(ctx,
+		"DELETE FROM sessions WHERE userid = ?",
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("error in session deleteusersessions %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := NewSigner([]byte("secret"))
+	signer.Now = func() time.Time { return now }
+
+	tok := signer.Sign(PurposeReset, "12", "state", time.Hour)
+	userID, err := signer.Parse(tok, PurposeReset)
+	assert.Nil(t, err)
+	assert.Equal(t, "12", userID)
+	assert.Nil(t, signer.Verify(tok, PurposeReset, "state"))
+
+	// the state moved on, e.g. the password was already reset with it
+	assert.ErrorIs(t, signer.Verify(tok, PurposeReset, "changed"), ErrInvalid)
+	// issued for another flow
+	assert.ErrorIs(t, signer.Verify(tok, PurposeVerify, "state"), ErrInvalid)
+	// signed with another key
+	other := NewSigner([]byte("other"))
+	other.Now = signer.Now
+	assert.ErrorIs(t, other.Verify(tok, PurposeReset, "state"), ErrInvalid)
+	// garbage
+	for _, bad := range []string{"", "abc", "abc.def", "!!.!!"} {
+		_, err = signer.Parse(bad, PurposeReset)
+		assert.ErrorIs(t, err, ErrInvalid, bad)
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	assert.ErrorIs(t, signer.Verify(tok, PurposeReset, "state"), ErrExpired)
+}
@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 appendix B, SHA1 key "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+
+	_, err := Code("not base32!", 1)
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := Code(rfcSecret, Step(now))
+
+	step, ok := Validate(rfcSecret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// one period of drift is fine, two are not
+	_, ok = Validate(rfcSecret, code, now.Add(Period*time.Second))
+	assert.True(t, ok)
+	_, ok = Validate(rfcSecret, code, now.Add(2*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, "12345", now)
+	assert.False(t, ok)
+	_, ok = Validate(rfcSecret, "", now)
+	assert.False(t, ok)
+}
+
+func TestSecretAndURI(t *testing.T) {
+	secret, err := NewSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+	_, err = Code(secret, 1)
+	assert.Nil(t, err)
+
+	uri := ProvisioningURI("redditclone", "alice", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/redditclone:alice?"), uri)
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=redditclone")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes()
+	assert.Nil(t, err)
+	assert.Len(t, codes, 10)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, "^[a-z2-7]{5}-[a-z2-7]{5}$", code)
+		seen[code] = true
+	}
+	assert.Len(t, seen, 10)
+
+	assert.Equal(t, HashRecoveryCode("abcde-fghij"), HashRecoveryCode(" ABCDE FGHIJ"))
+	assert.NotEqual(t, HashRecoveryCode("abcde-fghij"), HashRecoveryCode("abcde-fghik"))
+}
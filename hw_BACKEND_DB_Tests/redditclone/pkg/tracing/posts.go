@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+
+	"redditclone/pkg/comments"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/preview"
+	"redditclone/pkg/vote"
+)
+
+type PostsRepository struct {
+	Repo    posts.PostsRepository
+	Tracing *Tracing
+}
+
+func NewPostsRepository(repo posts.PostsRepository, t *Tracing) *PostsRepository {
+	return &PostsRepository{
+		Repo:    repo,
+		Tracing: t,
+	}
+}
+
+func (p *PostsRepository) GetAllPosts(ctx context.Context) ([]posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetAllPosts")
+	res, err := p.Repo.GetAllPosts(ctx)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) AddPost(ctx context.Context, post posts.Post) (posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.AddPost")
+	res, err := p.Repo.AddPost(ctx, post)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) UpdatePost(ctx context.Context, post posts.Post) error {
+	ctx, span := p.Tracing.start(ctx, "posts.UpdatePost")
+	err := p.Repo.UpdatePost(ctx, post)
+	finish(span, err)
+	return err
+}
+
+func (p *PostsRepository) GetPostByID(ctx context.Context, id string) (posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetPostByID")
+	res, err := p.Repo.GetPostByID(ctx, id)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) GetCategory(ctx context.Context, category string) ([]posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetCategory")
+	res, err := p.Repo.GetCategory(ctx, category)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) AddComment(ctx context.Context, postID string, comment comments.Comment) (posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.AddComment")
+	res, err := p.Repo.AddComment(ctx, postID, comment)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) DeleteComment(ctx context.Context, postID string, commentID string) (posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.DeleteComment")
+	res, err := p.Repo.DeleteComment(ctx, postID, commentID)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) DeletePost(ctx context.Context, postID string) error {
+	ctx, span := p.Tracing.start(ctx, "posts.DeletePost")
+	err := p.Repo.DeletePost(ctx, postID)
+	finish(span, err)
+	return err
+}
+
+func (p *PostsRepository) GetByUserLogin(ctx context.Context, login string) ([]posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetByUserLogin")
+	res, err := p.Repo.GetByUserLogin(ctx, login)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) Vote(ctx context.Context, postID string, vote vote.Vote) (posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.Vote")
+	res, err := p.Repo.Vote(ctx, postID, vote)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) UnVote(ctx context.Context, username string, postID string) (posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.UnVote")
+	res, err := p.Repo.UnVote(ctx, username, postID)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) SetHidden(ctx context.Context, postID string, commentID string, hidden bool) error {
+	ctx, span := p.Tracing.start(ctx, "posts.SetHidden")
+	err := p.Repo.SetHidden(ctx, postID, commentID, hidden)
+	finish(span, err)
+	return err
+}
+
+func (p *PostsRepository) SetPreview(ctx context.Context, postID string, preview preview.Preview) error {
+	ctx, span := p.Tracing.start(ctx, "posts.SetPreview")
+	err := p.Repo.SetPreview(ctx, postID, preview)
+	finish(span, err)
+	return err
+}
+
+func (p *PostsRepository) GetUserActivity(ctx context.Context, userID string) ([]posts.Post, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetUserActivity")
+	res, err := p.Repo.GetUserActivity(ctx, userID)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) AnonymizeComments(ctx context.Context, postID string, userID string) error {
+	ctx, span := p.Tracing.start(ctx, "posts.AnonymizeComments")
+	err := p.Repo.AnonymizeComments(ctx, postID, userID)
+	finish(span, err)
+	return err
+}
+
+func (p *PostsRepository) GetComments(ctx context.Context, postID string, page comments.Page) (comments.CommentsPage, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetComments")
+	res, err := p.Repo.GetComments(ctx, postID, page)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) GetComment(ctx context.Context, postID string, commentID string) (comments.Comment, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetComment")
+	res, err := p.Repo.GetComment(ctx, postID, commentID)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) VoteComment(ctx context.Context, postID string, commentID string, vote vote.Vote) (comments.Comment, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.VoteComment")
+	res, err := p.Repo.VoteComment(ctx, postID, commentID, vote)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) GetUserCommentVotes(ctx context.Context, userID string) ([]posts.CommentVote, error) {
+	ctx, span := p.Tracing.start(ctx, "posts.GetUserCommentVotes")
+	res, err := p.Repo.GetUserCommentVotes(ctx, userID)
+	finish(span, err)
+	return res, err
+}
+
+func (p *PostsRepository) UnVoteComments(ctx context.Context, postID string, userID string) error {
+	ctx, span := p.Tracing.start(ctx, "posts.UnVoteComments")
+	err := p.Repo.UnVoteComments(ctx, postID, userID)
+	finish(span, err)
+	return err
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+
+	"redditclone/pkg/session"
+)
+
+type SessionManager struct {
+	Sessions session.SessionManager
+	Tracing  *Tracing
+}
+
+func NewSessionManager(sessions session.SessionManager, t *Tracing) *SessionManager {
+	return &SessionManager{
+		Sessions: sessions,
+		Tracing:  t,
+	}
+}
+
+func (s *SessionManager) GetKey() interface{} {
+	return s.Sessions.GetKey()
+}
+
+func (s *SessionManager) SetKey(key interface{}) {
+	s.Sessions.SetKey(key)
+}
+
+func (s *SessionManager) GetExp(ctx context.Context, id string, iat int64) int64 {
+	ctx, span := s.Tracing.start(ctx, "sessions.GetExp")
+	exp := s.Sessions.GetExp(ctx, id, iat)
+	finish(span, nil)
+	return exp
+}
+
+func (s *SessionManager) AddNewSess(ctx context.Context, id string, exp int64, iat int64) error {
+	ctx, span := s.Tracing.start(ctx, "sessions.AddNewSess")
+	err := s.Sessions.AddNewSess(ctx, id, exp, iat)
+	finish(span, err)
+	return err
+}
+
+func (s *SessionManager) DeleteSess(ctx context.Context, userID string, iat int64) error {
+	ctx, span := s.Tracing.start(ctx, "sessions.DeleteSess")
+	err := s.Sessions.DeleteSess(ctx, userID, iat)
+	finish(span, err)
+	return err
+}
+
+func (s *SessionManager) GetSessions(ctx context.Context, userID string) ([]session.SessionDB, error) {
+	ctx, span := s.Tracing.start(ctx, "sessions.GetSessions")
+	res, err := s.Sessions.GetSessions(ctx, userID)
+	finish(span, err)
+	return res, err
+}
+
+func (s *SessionManager) DeleteUserSessions(ctx context.Context, userID string) error {
+	ctx, span := s.Tracing.start(ctx, "sessions.DeleteUserSessions")
+	err := s.Sessions.DeleteUserSessions(ctx, userID)
+	finish(span, err)
+	return err
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"redditclone/pkg/logger"
+	"redditclone/pkg/requestid"
+	"redditclone/pkg/response"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "redditclone"
+
+// NewProvider writes finished spans to out, a nil out keeps spans in-process
+// so that trace context is still propagated without exporting anything.
+func NewProvider(out io.Writer) (*sdktrace.TracerProvider, error) {
+	if out == nil {
+		return sdktrace.NewTracerProvider(), nil
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter)), nil
+}
+
+type Tracing struct {
+	Tracer     trace.Tracer
+	Propagator propagation.TextMapPropagator
+}
+
+func NewTracing(provider trace.TracerProvider) *Tracing {
+	return &Tracing{
+		Tracer:     provider.Tracer(instrumentationName),
+		Propagator: propagation.TraceContext{},
+	}
+}
+
+// Middleware is meant for mux.Router.Use so spans are named after the
+// matched route. It continues an incoming W3C traceparent if there is one.
+func (t *Tracing) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		ctx := t.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := t.Tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("request.id", requestid.FromContext(ctx)),
+			),
+		)
+		defer span.End()
+
+		if log := logger.FromContext(ctx, nil); log != nil {
+			ctx = logger.NewContext(ctx, log.WithFields(map[string]interface{}{
+				"trace_id": span.SpanContext().TraceID().String(),
+			}))
+		}
+		t.Propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		recorder := response.NewStatusRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.status_code", recorder.Status))
+		if recorder.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.Status))
+		}
+	})
+}
+
+func (t *Tracing) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+func finish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"redditclone/pkg/posts"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceparent     = "00-" + incomingTraceID + "-00f067aa0ba902b7-01"
+)
+
+func newTestTracing() (*Tracing, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewTracing(provider), recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	tr, recorder := newTestTracing()
+	r := mux.NewRouter()
+	r.Use(tr.Middleware)
+	var handlerSpan trace.SpanContext
+	r.HandleFunc("/api/post/{POST_ID}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(500)
+	})
+
+	req := httptest.NewRequest("GET", "/api/post/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /api/post/{POST_ID}", spans[0].Name())
+		assert.Equal(t, incomingTraceID, spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, spans[0].SpanContext().SpanID(), handlerSpan.SpanID())
+	}
+	assert.Contains(t, w.Header().Get("traceparent"), incomingTraceID)
+
+	// without incoming context a new trace is started
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/post/2", nil))
+	spans = recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.NotEqual(t, incomingTraceID, spans[1].SpanContext().TraceID().String())
+		assert.False(t, spans[1].Parent().IsValid())
+	}
+}
+
+func TestRepositorySpans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tr, recorder := newTestTracing()
+	ctx, parent := tr.Tracer.Start(context.Background(), "request")
+
+	mock := posts.NewMockPostsRepository(ctrl)
+	repo := NewPostsRepository(mock, tr)
+	mock.EXPECT().GetPostByID(gomock.Any(), "1").Return(posts.Post{}, fmt.Errorf("not found"))
+	mock.EXPECT().DeletePost(gomock.Any(), "2").Return(nil)
+
+	_, err := repo.GetPostByID(ctx, "1")
+	assert.NotNil(t, err)
+	assert.Nil(t, repo.DeletePost(ctx, "2"))
+	parent.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "posts.GetPostByID", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Len(t, spans[0].Events(), 1)
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, "posts.DeletePost", spans[1].Name())
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	}
+}
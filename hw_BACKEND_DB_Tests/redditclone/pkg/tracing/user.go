@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"context"
+
+	"redditclone/pkg/user"
+)
+
+type UserRepo struct {
+	Repo    user.UserRepo
+	Tracing *Tracing
+}
+
+func NewUserRepo(repo user.UserRepo, t *Tracing) *UserRepo {
+	return &UserRepo{
+		Repo:    repo,
+		Tracing: t,
+	}
+}
+
+func (u *UserRepo) AddNewUser(ctx context.Context, user user.User) (string, error) {
+	ctx, span := u.Tracing.start(ctx, "users.AddNewUser")
+	res, err := u.Repo.AddNewUser(ctx, user)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) Authenticate(ctx context.Context, user user.User) (string, error) {
+	ctx, span := u.Tracing.start(ctx, "users.Authenticate")
+	res, err := u.Repo.Authenticate(ctx, user)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) IsUser(ctx context.Context, username string, id string) (bool, error) {
+	ctx, span := u.Tracing.start(ctx, "users.IsUser")
+	res, err := u.Repo.IsUser(ctx, username, id)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) GetRole(ctx context.Context, id string) (string, error) {
+	ctx, span := u.Tracing.start(ctx, "users.GetRole")
+	res, err := u.Repo.GetRole(ctx, id)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) GetUser(ctx context.Context, id string) (user.User, error) {
+	ctx, span := u.Tracing.start(ctx, "users.GetUser")
+	res, err := u.Repo.GetUser(ctx, id)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) DeleteUser(ctx context.Context, id string) error {
+	ctx, span := u.Tracing.start(ctx, "users.DeleteUser")
+	err := u.Repo.DeleteUser(ctx, id)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	ctx, span := u.Tracing.start(ctx, "users.GetUserByEmail")
+	res, err := u.Repo.GetUserByEmail(ctx, email)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) SetEmail(ctx context.Context, id string, email string) error {
+	ctx, span := u.Tracing.start(ctx, "users.SetEmail")
+	err := u.Repo.SetEmail(ctx, id, email)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) SetVerified(ctx context.Context, id string, email string) error {
+	ctx, span := u.Tracing.start(ctx, "users.SetVerified")
+	err := u.Repo.SetVerified(ctx, id, email)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) SetPassword(ctx context.Context, id string, password string) error {
+	ctx, span := u.Tracing.start(ctx, "users.SetPassword")
+	err := u.Repo.SetPassword(ctx, id, password)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) GetUserByIdentity(ctx context.Context, issuer string, subject string) (user.User, error) {
+	ctx, span := u.Tracing.start(ctx, "users.GetUserByIdentity")
+	res, err := u.Repo.GetUserByIdentity(ctx, issuer, subject)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) LinkIdentity(ctx context.Context, id string, issuer string, subject string) error {
+	ctx, span := u.Tracing.start(ctx, "users.LinkIdentity")
+	err := u.Repo.LinkIdentity(ctx, id, issuer, subject)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) GetTOTP(ctx context.Context, id string) (user.TOTP, error) {
+	ctx, span := u.Tracing.start(ctx, "users.GetTOTP")
+	res, err := u.Repo.GetTOTP(ctx, id)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	ctx, span := u.Tracing.start(ctx, "users.SetTOTPSecret")
+	err := u.Repo.SetTOTPSecret(ctx, id, secret)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) EnableTOTP(ctx context.Context, id string, step int64, recoveryHashes []string) error {
+	ctx, span := u.Tracing.start(ctx, "users.EnableTOTP")
+	err := u.Repo.EnableTOTP(ctx, id, step, recoveryHashes)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) DisableTOTP(ctx context.Context, id string) error {
+	ctx, span := u.Tracing.start(ctx, "users.DisableTOTP")
+	err := u.Repo.DisableTOTP(ctx, id)
+	finish(span, err)
+	return err
+}
+
+func (u *UserRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	ctx, span := u.Tracing.start(ctx, "users.UseTOTPStep")
+	res, err := u.Repo.UseTOTPStep(ctx, id, step)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) UseRecoveryCode(ctx context.Context, id string, hash string) (bool, error) {
+	ctx, span := u.Tracing.start(ctx, "users.UseRecoveryCode")
+	res, err := u.Repo.UseRecoveryCode(ctx, id, hash)
+	finish(span, err)
+	return res, err
+}
+
+func (u *UserRepo) TakeTOTPAttempt(ctx context.Context, id string) (bool, error) {
+	ctx, span := u.Tracing.start(ctx, "users.TakeTOTPAttempt")
+	res, err := u.Repo.TakeTOTPAttempt(ctx, id)
+	finish(span, err)
+	return res, err
+}
@@ -0,0 +1,207 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"redditclone/pkg/preview"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultTimeout   = 5 * time.Second
+	DefaultMaxBytes  = 512 * 1024
+	maxRedirects     = 3
+	maxPreviewLength = 500
+)
+
+var (
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	ErrBadScheme        = errors.New("only http and https links can be unfurled")
+	ErrNotHTML          = errors.New("response is not html")
+)
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+type Unfurler struct {
+	Client       *http.Client
+	MaxBytes     int64
+	AllowPrivate bool
+}
+
+func NewUnfurler(timeout time.Duration, maxBytes int64) *Unfurler {
+	u := &Unfurler{MaxBytes: maxBytes}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: u.control,
+	}
+	u.Client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkScheme(req.URL)
+		},
+	}
+	return u
+}
+
+func IsPrivate(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// control runs after DNS resolution for every connection, redirects
+// included, so a hostname cannot be rebound to an internal address.
+func (u *Unfurler) control(network, address string, _ syscall.RawConn) error {
+	if u.AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsPrivate(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+func checkScheme(link *url.URL) error {
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return ErrBadScheme
+	}
+	return nil
+}
+
+func (u *Unfurler) Fetch(ctx context.Context, rawURL string) (preview.Preview, error) {
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return preview.Preview{}, err
+	}
+	if err = checkScheme(link); err != nil {
+		return preview.Preview{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return preview.Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return preview.Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return preview.Preview{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/html" {
+		return preview.Preview{}, ErrNotHTML
+	}
+
+	return parse(io.LimitReader(resp.Body, u.MaxBytes), resp.Request.URL), nil
+}
+
+func parse(body io.Reader, base *url.URL) preview.Preview {
+	var result, fallback preview.Preview
+	tokenizer := html.NewTokenizer(body)
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return merge(result, fallback, base)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = true
+			case "meta":
+				readMeta(token, &result, &fallback)
+			case "body":
+				return merge(result, fallback, base)
+			}
+		case html.TextToken:
+			if inTitle && fallback.Title == "" {
+				fallback.Title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			inTitle = false
+		}
+	}
+}
+
+func readMeta(token html.Token, result *preview.Preview, fallback *preview.Preview) {
+	var property, content string
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "property", "name":
+			property = strings.ToLower(attr.Val)
+		case "content":
+			content = strings.TrimSpace(attr.Val)
+		}
+	}
+	switch property {
+	case "og:title":
+		result.Title = content
+	case "og:description":
+		result.Description = content
+	case "og:image":
+		result.Image = content
+	case "description":
+		fallback.Description = content
+	}
+}
+
+func merge(result preview.Preview, fallback preview.Preview, base *url.URL) preview.Preview {
+	if result.Title == "" {
+		result.Title = fallback.Title
+	}
+	if result.Description == "" {
+		result.Description = fallback.Description
+	}
+	result.Title = truncate(result.Title)
+	result.Description = truncate(result.Description)
+	if result.Image != "" {
+		image, err := base.Parse(result.Image)
+		if err != nil || checkScheme(image) != nil {
+			result.Image = ""
+		} else {
+			result.Image = image.String()
+		}
+	}
+	return result
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) > maxPreviewLength {
+		return string(runes[:maxPreviewLength])
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+	"redditclone/pkg/preview"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const page = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta name="description" content="fallback description">
+<meta property="og:title" content="OG title">
+<meta property="og:image" content="/img/thumb.png">
+</head><body><p>content</p></body></html>`
+
+func newTarget(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head><title>Just title</title></head></html>")
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{}")
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", 1000)+`<meta property="og:title" content="too far"></head></html>`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetch(t *testing.T) {
+	server := newTarget(t)
+	unfurler := NewUnfurler(time.Second, DefaultMaxBytes)
+	unfurler.AllowPrivate = true
+
+	res, err := unfurler.Fetch(context.Background(), server.URL+"/page")
+	assert.Nil(t, err)
+	assert.Equal(t, preview.Preview{
+		Title:       "OG title",
+		Description: "fallback description",
+		Image:       server.URL + "/img/thumb.png",
+	}, res)
+
+	res, err = unfurler.Fetch(context.Background(), server.URL+"/redirect")
+	assert.Nil(t, err)
+	assert.Equal(t, "OG title", res.Title)
+
+	res, err = unfurler.Fetch(context.Background(), server.URL+"/plain")
+	assert.Nil(t, err)
+	assert.Equal(t, preview.Preview{Title: "Just title"}, res)
+
+	_, err = unfurler.Fetch(context.Background(), server.URL+"/json")
+	assert.ErrorIs(t, err, ErrNotHTML)
+
+	_, err = unfurler.Fetch(context.Background(), server.URL+"/missing")
+	assert.NotNil(t, err)
+
+	_, err = unfurler.Fetch(context.Background(), "ftp://example.com/file")
+	assert.ErrorIs(t, err, ErrBadScheme)
+}
+
+func TestFetchLimits(t *testing.T) {
+	server := newTarget(t)
+
+	unfurler := NewUnfurler(time.Second, 1024)
+	unfurler.AllowPrivate = true
+	res, err := unfurler.Fetch(context.Background(), server.URL+"/huge")
+	assert.Nil(t, err)
+	assert.Equal(t, "", res.Title)
+
+	unfurler = NewUnfurler(100*time.Millisecond, DefaultMaxBytes)
+	unfurler.AllowPrivate = true
+	_, err = unfurler.Fetch(context.Background(), server.URL+"/slow")
+	assert.NotNil(t, err)
+}
+
+func TestSSRFGuard(t *testing.T) {
+	server := newTarget(t)
+	unfurler := NewUnfurler(time.Second, DefaultMaxBytes)
+
+	_, err := unfurler.Fetch(context.Background(), server.URL+"/page")
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), "loopback target must be blocked, got %v", err)
+
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1"} {
+		assert.True(t, IsPrivate(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "93.184.216.34", "2606:4700::1111"} {
+		assert.False(t, IsPrivate(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := logger.NopLogger{}
+
+	server := newTarget(t)
+	unfurler := NewUnfurler(time.Second, DefaultMaxBytes)
+	unfurler.AllowPrivate = true
+	repo := posts.NewMockPostsRepository(ctrl)
+	worker := NewWorker(unfurler, repo, logger, 1)
+	// the slow page eats nearly all of the fetch budget
+	worker.Timeout = 400 * time.Millisecond
+
+	assert.False(t, worker.Enqueue(posts.Post{ID: "1", Type: "text", Text: "hello"}))
+	assert.True(t, worker.Enqueue(posts.Post{ID: "2", Type: "link", URL: server.URL + "/slow"}))
+	assert.False(t, worker.Enqueue(posts.Post{ID: "3", Type: "link", URL: server.URL + "/plain"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	repo.EXPECT().SetPreview(gomock.Any(), "2", preview.Preview{
+		Title:       "OG title",
+		Description: "fallback description",
+		Image:       server.URL + "/img/thumb.png",
+	}).DoAndReturn(
+		func(ctx context.Context, postID string, p preview.Preview) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok && time.Until(deadline) > DefaultWriteTimeout/2, "write must get its own deadline")
+			cancel()
+			return nil
+		})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker did not process the queue")
+	}
+}
@@ -0,0 +1,78 @@
+package unfurl
+
+import (
+	"context"
+	"time"
+
+	"redditclone/pkg/logger"
+	"redditclone/pkg/posts"
+)
+
+// DefaultWriteTimeout bounds storing a preview. It runs on its own deadline
+// so a slow page cannot leave the write without time.
+const DefaultWriteTimeout = 2 * time.Second
+
+type Queue interface {
+	Enqueue(post posts.Post) bool
+}
+
+type Worker struct {
+	Unfurler  *Unfurler
+	PostsRepo posts.PostsRepository
+	Logger    logger.Logger
+	Timeout   time.Duration
+	// WriteTimeout is given to SetPreview after a successful fetch.
+	WriteTimeout time.Duration
+	queue        chan posts.Post
+}
+
+func NewWorker(unfurler *Unfurler, repo posts.PostsRepository, logger logger.Logger, queueSize int) *Worker {
+	return &Worker{
+		Unfurler:     unfurler,
+		PostsRepo:    repo,
+		Logger:       logger,
+		Timeout:      DefaultTimeout,
+		WriteTimeout: DefaultWriteTimeout,
+		queue:        make(chan posts.Post, queueSize),
+	}
+}
+
+func (w *Worker) Enqueue(post posts.Post) bool {
+	if post.Type != posts.TypeLink || post.URL == "" {
+		return false
+	}
+	select {
+	case w.queue <- post:
+		return true
+	default:
+		w.Logger.Log("Warn", "unfurl queue is full, skipping post "+post.ID)
+		return false
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case post := <-w.queue:
+			w.unfurl(ctx, post)
+		}
+	}
+}
+
+func (w *Worker) unfurl(ctx context.Context, post posts.Post) {
+	fetchCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	preview, err := w.Unfurler.Fetch(fetchCtx, post.URL)
+	cancel()
+	if err != nil {
+		w.Logger.LogW("Info", "unfurl failed", map[string]interface{}{"post": post.ID, "error": err.Error()})
+		return
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, w.WriteTimeout)
+	defer cancel()
+	err = w.PostsRepo.SetPreview(writeCtx, post.ID, preview)
+	if err != nil {
+		w.Logger.Log("Error", err.Error())
+	}
+}
@@ -1,12 +1,52 @@
 package user
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// After MaxTOTPFailures second-factor attempts without an accepted code the
+// account refuses codes until TOTPLockout has passed since the last one.
+const (
+	MaxTOTPFailures = 5
+	TOTPLockout     = 15 * time.Minute
+)
+
+var ErrEmailTaken = errors.New("email is already in use")
 
 type User struct {
 	Username string
 	Login    string
 	Password string
 	ID       string
+	Role     string
+	Email    string
+	Verified bool
+	// PasswordChanged is the unix time in nanoseconds of the last password
+	// reset, it invalidates reset tokens issued before it.
+	PasswordChanged int64
+}
+
+// TOTP is the second factor of a user. A secret is stored as soon as
+// enrollment starts but only enforced once Enabled is set.
+type TOTP struct {
+	Secret  string
+	Enabled bool
+	// LastStep is the time step of the last accepted code, a code is never
+	// accepted twice.
+	LastStep int64
+	Failures int
+	// FailedAt is the unix time of the last failure.
+	FailedAt int64
+	// Accepted counts the accepted codes, recovery codes included.
+	Accepted int64
 }
 
 //go:generate mockgen -source user.go -destination user_mock.go -package user UserRepo
@@ -14,4 +54,20 @@ type UserRepo interface {
 	AddNewUser(ctx context.Context, user User) (string, error)
 	Authenticate(ctx context.Context, user User) (string, error)
 	IsUser(ctx context.Context, username string, id string) (bool, error)
+	GetRole(ctx context.Context, id string) (string, error)
+	GetUser(ctx context.Context, id string) (User, error)
+	DeleteUser(ctx context.Context, id string) error
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	SetEmail(ctx context.Context, id string, email string) error
+	SetVerified(ctx context.Context, id string, email string) error
+	SetPassword(ctx context.Context, id string, password string) error
+	GetUserByIdentity(ctx context.Context, issuer string, subject string) (User, error)
+	LinkIdentity(ctx context.Context, id string, issuer string, subject string) error
+	GetTOTP(ctx context.Context, id string) (TOTP, error)
+	SetTOTPSecret(ctx context.Context, id string, secret string) error
+	EnableTOTP(ctx context.Context, id string, step int64, recoveryHashes []string) error
+	DisableTOTP(ctx context.Context, id string) error
+	UseTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, id string, hash string) (bool, error)
+	TakeTOTPAttempt(ctx context.Context, id string) (bool, error)
 }
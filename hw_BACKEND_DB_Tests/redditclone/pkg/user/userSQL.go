@@ -3,9 +3,17 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+const userColumns = "id, username, login, role, COALESCE(email, ''), verified, password_changed"
+
 type UserSQLRepo struct {
 	DB *sql.DB
 }
@@ -16,13 +24,27 @@ func NewUserSQLRepo(db *sql.DB) *UserSQLRepo {
 	}
 }
 
+func nullable(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// duplicateEmail reports a violation of the unique index on users.email.
+func duplicateEmail(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && strings.Contains(mysqlErr.Message, "email")
+}
+
 func (m *UserSQLRepo) AddNewUser(ctx context.Context, user User) (string, error) {
 	result, err := m.DB.ExecContext(ctx,
-		"INSERT INTO users (`username`, `login`, `password`) VALUES (?, ?, ?)",
+		"INSERT INTO users (`username`, `login`, `password`, `email`) VALUES (?, ?, ?, ?)",
 		user.Username,
 		user.Login,
 		user.Password,
+		nullable(user.Email),
 	)
+	if duplicateEmail(err) {
+		return "", ErrEmailTaken
+	}
 	if err != nil {
 		return "", err
 	}
@@ -61,3 +83,233 @@ func (m *UserSQLRepo) IsUser(ctx context.Context, username string, id string) (b
 	//
 	return true, nil
 }
+
+func (m *UserSQLRepo) GetRole(ctx context.Context, id string) (string, error) {
+	row := m.DB.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ?",
+		id,
+	)
+	var role string
+	err := row.Scan(&role)
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func scanUser(row *sql.Row) (User, error) {
+	user := User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Login, &user.Role, &user.Email, &user.Verified, &user.PasswordChanged)
+	if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (m *UserSQLRepo) GetUser(ctx context.Context, id string) (User, error) {
+	return scanUser(m.DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?",
+		id,
+	))
+}
+
+func (m *UserSQLRepo) GetUserByIdentity(ctx context.Context, issuer string, subject string) (User, error) {
+	return scanUser(m.DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = (SELECT userid FROM user_identities WHERE issuer = ? AND subject = ?)",
+		issuer,
+		subject,
+	))
+}
+
+func (m *UserSQLRepo) LinkIdentity(ctx context.Context, id string, issuer string, subject string) error {
+	_, err := m.DB.ExecContext(ctx, "INSERT INTO user_identities (`issuer`, `subject`, `userid`) VALUES (?, ?, ?)",
+		issuer,
+		subject,
+		id,
+	)
+	return err
+}
+
+func (m *UserSQLRepo) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	return scanUser(m.DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = ?",
+		email,
+	))
+}
+
+func (m *UserSQLRepo) DeleteUser(ctx context.Context, id string) error {
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM users WHERE id = ?",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error in deleteuser %w", err)
+	}
+	_, err = m.DB.ExecContext(ctx, "DELETE FROM user_identities WHERE userid = ?",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error in deleteuser %w", err)
+	}
+	_, err = m.DB.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE userid = ?",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("error in deleteuser %w", err)
+	}
+	return nil
+}
+
+// update does not look at the affected rows, MySQL reports 0 when the new
+// values equal the old ones.
+func (m *UserSQLRepo) update(ctx context.Context, query string, args ...interface{}) error {
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	if duplicateEmail(err) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// SetEmail replaces the address and marks it unverified.
+func (m *UserSQLRepo) SetEmail(ctx context.Context, id string, email string) error {
+	return m.update(ctx, "UPDATE users SET email = ?, verified = 0 WHERE id = ?",
+		nullable(email),
+		id,
+	)
+}
+
+func (m *UserSQLRepo) SetVerified(ctx context.Context, id string, email string) error {
+	return m.update(ctx, "UPDATE users SET verified = 1 WHERE id = ? AND email = ?",
+		id,
+		email,
+	)
+}
+
+func (m *UserSQLRepo) SetPassword(ctx context.Context, id string, password string) error {
+	return m.update(ctx, "UPDATE users SET password = ?, password_changed = ? WHERE id = ?",
+		password,
+		time.Now().UnixNano(),
+		id,
+	)
+}
+
+func (m *UserSQLRepo) GetTOTP(ctx context.Context, id string) (TOTP, error) {
+	row := m.DB.QueryRowContext(ctx, "SELECT COALESCE(totp_secret, ''), totp_enabled, totp_last_step, totp_failures, totp_failed, totp_accepted FROM users WHERE id = ?",
+		id,
+	)
+	totp := TOTP{}
+	err := row.Scan(&totp.Secret, &totp.Enabled, &totp.LastStep, &totp.Failures, &totp.FailedAt, &totp.Accepted)
+	if err != nil {
+		return TOTP{}, err
+	}
+	return totp, nil
+}
+
+// SetTOTPSecret starts an enrollment, it leaves an enabled second factor
+// alone.
+func (m *UserSQLRepo) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	return m.update(ctx, "UPDATE users SET totp_secret = ? WHERE id = ? AND totp_enabled = 0",
+		secret,
+		id,
+	)
+}
+
+// EnableTOTP finishes the enrollment and replaces the recovery codes.
+func (m *UserSQLRepo) EnableTOTP(ctx context.Context, id string, step int64, recoveryHashes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_enabled = 1, totp_last_step = ?, totp_failures = 0 WHERE id = ? AND totp_secret IS NOT NULL",
+		step,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE userid = ?",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_recovery_codes (`userid`, `hash`) VALUES (?, ?)",
+			id,
+			hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *UserSQLRepo) DisableTOTP(ctx context.Context, id string) error {
+	err := m.update(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled = 0, totp_last_step = 0, totp_failures = 0 WHERE id = ?",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	return m.update(ctx, "DELETE FROM user_recovery_codes WHERE userid = ?",
+		id,
+	)
+}
+
+// UseTOTPStep records an accepted code. It reports false when a code of
+// this or a later step was already used.
+func (m *UserSQLRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	result, err := m.DB.ExecContext(ctx, "UPDATE users SET totp_last_step = ?, totp_failures = 0, totp_accepted = totp_accepted + 1 WHERE id = ? AND totp_last_step < ?",
+		step,
+		id,
+		step,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (m *UserSQLRepo) UseRecoveryCode(ctx context.Context, id string, hash string) (bool, error) {
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE userid = ? AND hash = ?",
+		id,
+		hash,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	return true, m.update(ctx, "UPDATE users SET totp_failures = 0, totp_accepted = totp_accepted + 1 WHERE id = ?",
+		id,
+	)
+}
+
+// TakeTOTPAttempt counts an attempt as a failure before its code is
+// checked, an accepted code clears the count again. Checking the lockout
+// and counting in one statement keeps parallel guesses within
+// MaxTOTPFailures. It reports false during a lockout, and starts counting
+// again once the last failure is older than the lockout.
+func (m *UserSQLRepo) TakeTOTPAttempt(ctx context.Context, id string) (bool, error) {
+	now := time.Now()
+	expired := now.Add(-TOTPLockout).Unix()
+	result, err := m.DB.ExecContext(ctx, "UPDATE users SET totp_failures = IF(totp_failed < ?, 1, totp_failures + 1), totp_failed = ? WHERE id = ? AND (totp_failures < ? OR totp_failed < ?)",
+		expired,
+		now.Unix(),
+		id,
+		MaxTOTPFailures,
+		expired,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
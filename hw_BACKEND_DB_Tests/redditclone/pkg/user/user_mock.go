@@ -64,6 +64,123 @@ func (mr *MockUserRepoMockRecorder) Authenticate(ctx, user interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockUserRepo)(nil).Authenticate), ctx, user)
 }
 
+// DeleteUser mocks base method.
+func (m *MockUserRepo) DeleteUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserRepoMockRecorder) DeleteUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepo)(nil).DeleteUser), ctx, id)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockUserRepo) DisableTOTP(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockUserRepoMockRecorder) DisableTOTP(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockUserRepo)(nil).DisableTOTP), ctx, id)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockUserRepo) EnableTOTP(ctx context.Context, id string, step int64, recoveryHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, id, step, recoveryHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockUserRepoMockRecorder) EnableTOTP(ctx, id, step, recoveryHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockUserRepo)(nil).EnableTOTP), ctx, id, step, recoveryHashes)
+}
+
+// GetRole mocks base method.
+func (m *MockUserRepo) GetRole(ctx context.Context, id string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRole", ctx, id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRole indicates an expected call of GetRole.
+func (mr *MockUserRepoMockRecorder) GetRole(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockUserRepo)(nil).GetRole), ctx, id)
+}
+
+// GetTOTP mocks base method.
+func (m *MockUserRepo) GetTOTP(ctx context.Context, id string) (TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", ctx, id)
+	ret0, _ := ret[0].(TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockUserRepoMockRecorder) GetTOTP(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockUserRepo)(nil).GetTOTP), ctx, id)
+}
+
+// GetUser mocks base method.
+func (m *MockUserRepo) GetUser(ctx context.Context, id string) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, id)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockUserRepoMockRecorder) GetUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserRepo)(nil).GetUser), ctx, id)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockUserRepo) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockUserRepoMockRecorder) GetUserByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockUserRepo)(nil).GetUserByEmail), ctx, email)
+}
+
+// GetUserByIdentity mocks base method.
+func (m *MockUserRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", ctx, issuer, subject)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockUserRepoMockRecorder) GetUserByIdentity(ctx, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockUserRepo)(nil).GetUserByIdentity), ctx, issuer, subject)
+}
+
 // IsUser mocks base method.
 func (m *MockUserRepo) IsUser(ctx context.Context, username, id string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -78,3 +195,118 @@ func (mr *MockUserRepoMockRecorder) IsUser(ctx, username, id interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsUser", reflect.TypeOf((*MockUserRepo)(nil).IsUser), ctx, username, id)
 }
+
+// LinkIdentity mocks base method.
+func (m *MockUserRepo) LinkIdentity(ctx context.Context, id, issuer, subject string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkIdentity", ctx, id, issuer, subject)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockUserRepoMockRecorder) LinkIdentity(ctx, id, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockUserRepo)(nil).LinkIdentity), ctx, id, issuer, subject)
+}
+
+// SetEmail mocks base method.
+func (m *MockUserRepo) SetEmail(ctx context.Context, id, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEmail", ctx, id, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEmail indicates an expected call of SetEmail.
+func (mr *MockUserRepoMockRecorder) SetEmail(ctx, id, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEmail", reflect.TypeOf((*MockUserRepo)(nil).SetEmail), ctx, id, email)
+}
+
+// SetPassword mocks base method.
+func (m *MockUserRepo) SetPassword(ctx context.Context, id, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockUserRepoMockRecorder) SetPassword(ctx, id, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockUserRepo)(nil).SetPassword), ctx, id, password)
+}
+
+// SetTOTPSecret mocks base method.
+func (m *MockUserRepo) SetTOTPSecret(ctx context.Context, id, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPSecret", ctx, id, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPSecret indicates an expected call of SetTOTPSecret.
+func (mr *MockUserRepoMockRecorder) SetTOTPSecret(ctx, id, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPSecret", reflect.TypeOf((*MockUserRepo)(nil).SetTOTPSecret), ctx, id, secret)
+}
+
+// SetVerified mocks base method.
+func (m *MockUserRepo) SetVerified(ctx context.Context, id, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVerified", ctx, id, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVerified indicates an expected call of SetVerified.
+func (mr *MockUserRepoMockRecorder) SetVerified(ctx, id, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVerified", reflect.TypeOf((*MockUserRepo)(nil).SetVerified), ctx, id, email)
+}
+
+// TakeTOTPAttempt mocks base method.
+func (m *MockUserRepo) TakeTOTPAttempt(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeTOTPAttempt", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeTOTPAttempt indicates an expected call of TakeTOTPAttempt.
+func (mr *MockUserRepoMockRecorder) TakeTOTPAttempt(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeTOTPAttempt", reflect.TypeOf((*MockUserRepo)(nil).TakeTOTPAttempt), ctx, id)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockUserRepo) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, id, hash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockUserRepoMockRecorder) UseRecoveryCode(ctx, id, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserRepo)(nil).UseRecoveryCode), ctx, id, hash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockUserRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, id, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockUserRepoMockRecorder) UseTOTPStep(ctx, id, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockUserRepo)(nil).UseTOTPStep), ctx, id, step)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 )
 
 // go test -coverprofile=cover.out && go tool cover -html=cover.out -o cover.html
@@ -33,7 +34,7 @@ func TestAddNewUser(t *testing.T) {
 	// ok query
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs(username, login, paswword).
+		WithArgs(username, login, paswword, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	id, err := repo.AddNewUser(context.Background(), user)
@@ -53,7 +54,7 @@ func TestAddNewUser(t *testing.T) {
 	// bad query
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs(username, login, paswword).
+		WithArgs(username, login, paswword, nil).
 		WillReturnError(fmt.Errorf("db error"))
 
 	_, err = repo.AddNewUser(context.Background(), user)
@@ -68,7 +69,7 @@ func TestAddNewUser(t *testing.T) {
 	// last ID error
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs(username, login, paswword).
+		WithArgs(username, login, paswword, nil).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("something wrong")))
 	_, err = repo.AddNewUser(context.Background(), user)
 	if err == nil {
@@ -183,3 +184,240 @@ func TestIsUser(t *testing.T) {
 		return
 	}
 }
+
+func TestGetRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewUserSQLRepo(db)
+
+	id := "1"
+
+	rows := sqlmock.NewRows([]string{"role"})
+	mock.
+		ExpectQuery("SELECT role FROM users WHERE").
+		WithArgs(id).
+		WillReturnRows(rows)
+	_, err = repo.GetRole(context.Background(), id)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+
+	rows = sqlmock.NewRows([]string{"role"}).AddRow(RoleModerator)
+	mock.
+		ExpectQuery("SELECT role FROM users WHERE").
+		WithArgs(id).
+		WillReturnRows(rows)
+	role, err := repo.GetRole(context.Background(), id)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if role != RoleModerator {
+		t.Errorf("bad role: want %v, have %v", RoleModerator, role)
+		return
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewUserSQLRepo(db)
+
+	columns := []string{"id", "username", "login", "role", "email", "verified", "password_changed"}
+	rows := sqlmock.NewRows(columns).AddRow("1", "user", "log", RoleUser, "a@b.c", true, 7)
+	mock.
+		ExpectQuery("SELECT (.+) FROM users WHERE id = ").
+		WithArgs("1").
+		WillReturnRows(rows)
+	res, err := repo.GetUser(context.Background(), "1")
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	want := User{ID: "1", Username: "user", Login: "log", Role: RoleUser, Email: "a@b.c", Verified: true, PasswordChanged: 7}
+	if res != want {
+		t.Errorf("bad user: want %v, have %v", want, res)
+		return
+	}
+
+	mock.
+		ExpectExec("DELETE FROM users WHERE").
+		WithArgs("1").
+		WillReturnError(fmt.Errorf("db error"))
+	if err = repo.DeleteUser(context.Background(), "1"); err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewUserSQLRepo(db)
+
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'users.email'"}
+	mock.
+		ExpectExec("INSERT INTO users").
+		WithArgs("user", "log", "pass", "a@b.c").
+		WillReturnError(duplicate)
+	if _, err = repo.AddNewUser(context.Background(), User{Username: "user", Login: "log", Password: "pass", Email: "a@b.c"}); err != ErrEmailTaken {
+		t.Errorf("bad err: want %v, have %v", ErrEmailTaken, err)
+		return
+	}
+
+	mock.
+		ExpectExec("UPDATE users SET email = \\?, verified = 0 WHERE id = \\?").
+		WithArgs("a@b.c", "1").
+		WillReturnError(duplicate)
+	if err = repo.SetEmail(context.Background(), "1", "a@b.c"); err != ErrEmailTaken {
+		t.Errorf("bad err: want %v, have %v", ErrEmailTaken, err)
+		return
+	}
+
+	mock.
+		ExpectExec("UPDATE users SET verified = 1 WHERE id = \\? AND email = \\?").
+		WithArgs("1", "a@b.c").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err = repo.SetVerified(context.Background(), "1", "a@b.c"); err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	mock.
+		ExpectExec("UPDATE users SET password = \\?, password_changed = \\? WHERE id = \\?").
+		WithArgs("new", sqlmock.AnyArg(), "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err = repo.SetPassword(context.Background(), "1", "new"); err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	mock.
+		ExpectQuery("SELECT (.+) FROM users WHERE email = ").
+		WithArgs("a@b.c").
+		WillReturnError(fmt.Errorf("db error"))
+	if _, err = repo.GetUserByEmail(context.Background(), "a@b.c"); err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := NewUserSQLRepo(db)
+
+	rows := sqlmock.NewRows([]string{"secret", "enabled", "last_step", "failures", "failed", "accepted"}).AddRow("SECRET", true, 7, 2, 100, 3)
+	mock.
+		ExpectQuery("SELECT (.+) FROM users WHERE id = ").
+		WithArgs("1").
+		WillReturnRows(rows)
+	totp, err := repo.GetTOTP(context.Background(), "1")
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if want := (TOTP{Secret: "SECRET", Enabled: true, LastStep: 7, Failures: 2, FailedAt: 100, Accepted: 3}); totp != want {
+		t.Errorf("bad totp: want %v, have %v", want, totp)
+		return
+	}
+
+	// enabling replaces the recovery codes in one transaction
+	mock.ExpectBegin()
+	mock.
+		ExpectExec("UPDATE users SET totp_enabled = 1").
+		WithArgs(int64(7), "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("DELETE FROM user_recovery_codes WHERE userid = ").
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.
+		ExpectExec("INSERT INTO user_recovery_codes").
+		WithArgs("1", "h1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO user_recovery_codes").
+		WithArgs("1", "h2").
+		WillReturnError(fmt.Errorf("db error"))
+	mock.ExpectRollback()
+	if err = repo.EnableTOTP(context.Background(), "1", 7, []string{"h1", "h2"}); err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	// a step is only used once
+	mock.
+		ExpectExec("UPDATE users SET totp_last_step = \\?, totp_failures = 0, totp_accepted = totp_accepted \\+ 1 WHERE id = \\? AND totp_last_step < \\?").
+		WithArgs(int64(8), "1", int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if ok, err := repo.UseTOTPStep(context.Background(), "1", 8); ok || err != nil {
+		t.Errorf("bad result: want false, nil, have %v, %v", ok, err)
+		return
+	}
+
+	mock.
+		ExpectExec("DELETE FROM user_recovery_codes WHERE userid = \\? AND hash = \\?").
+		WithArgs("1", "h1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("UPDATE users SET totp_failures = 0, totp_accepted = totp_accepted \\+ 1 WHERE id = ").
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if ok, err := repo.UseRecoveryCode(context.Background(), "1", "h1"); !ok || err != nil {
+		t.Errorf("bad result: want true, nil, have %v, %v", ok, err)
+		return
+	}
+
+	// the lockout is checked and the attempt counted in one statement
+	mock.
+		ExpectExec("UPDATE users SET totp_failures = IF\\(totp_failed < \\?, 1, totp_failures \\+ 1\\), totp_failed = \\? WHERE id = \\? AND \\(totp_failures < \\? OR totp_failed < \\?\\)").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "1", MaxTOTPFailures, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if ok, err := repo.TakeTOTPAttempt(context.Background(), "1"); !ok || err != nil {
+		t.Errorf("bad result: want true, nil, have %v, %v", ok, err)
+		return
+	}
+	mock.
+		ExpectExec("UPDATE users SET totp_failures = IF").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "1", MaxTOTPFailures, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if ok, err := repo.TakeTOTPAttempt(context.Background(), "1"); ok || err != nil {
+		t.Errorf("bad result for a locked account: want false, nil, have %v, %v", ok, err)
+		return
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
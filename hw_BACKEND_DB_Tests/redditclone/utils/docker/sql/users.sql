@@ -9,6 +9,16 @@ CREATE TABLE `users` (
   `username` varchar(255) NOT NULL UNIQUE,
   `login` varchar(255) NOT NULL,
   `password` varchar(255) NOT NULL,
+  `role` varchar(32) NOT NULL DEFAULT 'user',
+  `email` varchar(255) NULL UNIQUE,
+  `verified` tinyint(1) NOT NULL DEFAULT 0,
+  `password_changed` bigint NOT NULL DEFAULT 0,
+  `totp_secret` varchar(64) NULL,
+  `totp_enabled` tinyint(1) NOT NULL DEFAULT 0,
+  `totp_last_step` bigint NOT NULL DEFAULT 0,
+  `totp_failures` int NOT NULL DEFAULT 0,
+  `totp_failed` bigint NOT NULL DEFAULT 0,
+  `totp_accepted` bigint NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
@@ -20,4 +30,30 @@ CREATE TABLE `sessions` (
   `expiration` bigint NOT NULL,
   `iat` bigint NOT NULL,
   PRIMARY KEY (`id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `account_deletions`;
+CREATE TABLE `account_deletions` (
+  `userid` varchar(255) NOT NULL,
+  `step` varchar(32) NOT NULL,
+  `error` text NOT NULL,
+  `created` bigint NOT NULL,
+  `updated` bigint NOT NULL,
+  PRIMARY KEY (`userid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `user_identities`;
+CREATE TABLE `user_identities` (
+  `issuer` varchar(255) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `userid` varchar(255) NOT NULL,
+  PRIMARY KEY (`issuer`, `subject`),
+  KEY (`userid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `user_recovery_codes`;
+CREATE TABLE `user_recovery_codes` (
+  `userid` varchar(255) NOT NULL,
+  `hash` char(64) NOT NULL,
+  PRIMARY KEY (`userid`, `hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
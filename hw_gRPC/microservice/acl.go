@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const defaultACLPollInterval = time.Second
+
+type ACL struct {
+	Policy *Policy
+	// Version starts at 1 and grows with every policy that replaces the
+	// previous one.
+	Version uint64
+	Mu      *sync.RWMutex
+}
+
+func NewACL(acl string) (*ACL, error) {
+	policy, err := ParseACL([]byte(acl))
+	if err != nil {
+		return nil, err
+	}
+	return &ACL{Policy: policy, Version: 1, Mu: &sync.RWMutex{}}, nil
+}
+
+// Explain reports whether the consumer may call the method and which rule
+// decided it. Rules limited to peer addresses do not apply, use ExplainFrom
+// for a call from a known peer.
+func (a *ACL) Explain(consumer, method string) Decision {
+	return a.ExplainFrom(consumer, method, "")
+}
+
+func (a *ACL) ExplainFrom(consumer, method, host string) Decision {
+	a.Mu.RLock()
+	defer a.Mu.RUnlock()
+	return a.Policy.Explain(consumer, method, host)
+}
+
+func (a *ACL) CheckPermission(consumer, method string) bool {
+	return a.Explain(consumer, method).Allowed
+}
+
+// Names reports whether the policy in force lists the consumer by name.
+func (a *ACL) Names(consumer string) bool {
+	a.Mu.RLock()
+	defer a.Mu.RUnlock()
+	return a.Policy.names(consumer)
+}
+
+// Swap installs a parsed policy. Loading the same policy again keeps the
+// version and reports no change.
+func (a *ACL) Swap(policy *Policy) (uint64, bool) {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+	if reflect.DeepEqual(a.Policy, policy) {
+		return a.Version, false
+	}
+	a.Policy = policy
+	a.Version++
+	return a.Version, true
+}
+
+// reloadACL reads the ACL file again and tells the Logging subscribers when
+// the policy changed. origin is the call that asked for the reload, nil for
+// the file watcher.
+func (a *AdminServerStruct) reloadACL(origin *Event) (*ACLStatus, error) {
+	data, err := os.ReadFile(a.ACLFile)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParseACL(data)
+	if err != nil {
+		return nil, err
+	}
+	version, changed := a.ACL.Swap(policy)
+	if changed {
+		event := &Event{Timestamp: time.Now().Unix()}
+		if origin != nil {
+			event.Consumer = origin.Consumer
+			event.Method = origin.Method
+			event.Host = origin.Host
+		}
+		event.Kind = EventKind_ACL_CHANGE
+		event.Detail = fmt.Sprintf("acl version %d loaded from %s", version, a.ACLFile)
+		a.notifyObservers(event)
+	}
+	return &ACLStatus{Version: version, Changed: changed}, nil
+}
+
+// watchACL polls the ACL file until ctx is done. A file that does not parse
+// is reported once and the current policy stays in force.
+func (a *AdminServerStruct) watchACL(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultACLPollInterval
+	}
+	last, _ := os.ReadFile(a.ACLFile)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			data, err := os.ReadFile(a.ACLFile)
+			if err != nil || bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			if _, err = a.reloadACL(nil); err != nil {
+				log.Println("acl reload rejected:", err)
+			}
+		}
+	}
+}
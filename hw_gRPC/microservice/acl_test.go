@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+const reloadACLData string = `{
+	"logger":    ["/main.Admin/Logging"],
+	"ops":       ["/main.Admin/ReloadACL"],
+	"biz_user":  ["/main.Biz/Check"]
+}`
+
+func TestParseACL(t *testing.T) {
+	for _, bad := range []string{
+		`{.;`,
+		`{"": ["/main.Biz/Check"]}`,
+		`{"biz": ["main.Biz/Check"]}`,
+		`{"biz": ["/main.Biz/Check/*/x"]}`,
+		`{"biz": "/main.Biz/Check"}`,
+	} {
+		if _, err := ParseACL([]byte(bad)); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+
+	acl, err := NewACL(ACLData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy, _ := ParseACL([]byte(ACLData))
+	if version, changed := acl.Swap(policy); changed || version != 1 {
+		t.Errorf("same policy: have version %d changed %v", version, changed)
+	}
+	policy, _ = ParseACL([]byte(`{"biz_user": ["/main.Biz/Test"]}`))
+	if version, changed := acl.Swap(policy); !changed || version != 2 {
+		t.Errorf("new policy: have version %d changed %v", version, changed)
+	}
+	if acl.CheckPermission("biz_user", "/main.Biz/Check") || !acl.CheckPermission("biz_user", "/main.Biz/Test") {
+		t.Errorf("new policy is not in force")
+	}
+}
+
+// startWithACLFile writes the policy to a file and serves it, the returned
+// stream gets the events of the logger consumer.
+func startWithACLFile(t *testing.T, poll time.Duration) (string, BizClient, AdminClient, func() *Event) {
+	path := filepath.Join(t.TempDir(), "acl.json")
+	if err := os.WriteFile(path, []byte(reloadACLData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, finish := context.WithCancel(context.Background())
+	err := Start(ctx, Config{Addr: listenAddr, ACLFile: path, ACLPollInterval: poll, Auth: []Authenticator{MetadataAuth{}}})
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	conn := getGrpcConn(t)
+	t.Cleanup(func() {
+		conn.Close()
+		finish()
+		wait(1)
+	})
+
+	adm := NewAdminClient(conn)
+	logStream, err := adm.Logging(getConsumerCtx("logger"), &LogFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+	nextACLChange := func() *Event {
+		for {
+			evt, err := logStream.Recv()
+			if err != nil {
+				t.Fatalf("unexpected error: %v, awaiting event", err)
+			}
+			if evt.Kind == EventKind_ACL_CHANGE {
+				return evt
+			}
+		}
+	}
+	return path, NewBizClient(conn), adm, nextACLChange
+}
+
+func TestACLWatch(t *testing.T) {
+	path, biz, _, nextACLChange := startWithACLFile(t, 20*time.Millisecond)
+
+	_, err := biz.Test(getConsumerCtx("biz_user"), &Nothing{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"logger": ["/main.Admin/Logging"], "biz_user": ["/main.Biz/*"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	evt := nextACLChange()
+	if evt.Detail != "acl version 2 loaded from "+path || evt.Consumer != "" {
+		t.Fatalf("bad audit event: %+v", evt)
+	}
+	if _, err = biz.Test(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("unexpected error after reload: %v", err)
+	}
+
+	// a broken file leaves the policy alone
+	if err = os.WriteFile(path, []byte(`{"biz_user": ["Test"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wait(5)
+	if _, err = biz.Test(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("unexpected error after bad edit: %v", err)
+	}
+}
+
+func TestACLReloadRPC(t *testing.T) {
+	path, biz, adm, nextACLChange := startWithACLFile(t, time.Hour)
+
+	// nothing changed
+	res, err := adm.ReloadACL(getConsumerCtx("ops"), &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Version != 1 || res.Changed {
+		t.Fatalf("bad status: %+v", res)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"biz_user": ["Test"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adm.ReloadACL(getConsumerCtx("ops"), &Nothing{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"logger": ["/main.Admin/Logging"], "ops": ["/main.Admin/ReloadACL"], "biz_user": ["/main.Biz/*"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	res, err = adm.ReloadACL(getConsumerCtx("ops"), &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Version != 2 || !res.Changed {
+		t.Fatalf("bad status: %+v", res)
+	}
+	evt := nextACLChange()
+	if evt.Consumer != "ops" || evt.Method != "/main.Admin/ReloadACL" {
+		t.Fatalf("bad audit event: %+v", evt)
+	}
+	if _, err = biz.Test(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("unexpected error after reload: %v", err)
+	}
+
+	// only consumers with the right may reload
+	if _, err = adm.ReloadACL(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestDeniedCallEvents(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	logStream, err := adm.Logging(getConsumerCtx("logger"), &LogFilter{Method: "/main.Biz/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statStream, err := adm.Statistics(getConsumerCtx("stat"), &StatInterval{IntervalSeconds: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+
+	biz.Test(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Check(context.Background(), &Nothing{})
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+
+	for _, want := range []*Event{
+		{Consumer: "biz_user", Method: "/main.Biz/Test", Decision: AccessDecision_DENIED, Detail: "no rule allows biz_user to call /main.Biz/Test"},
+		{Consumer: "", Method: "/main.Biz/Check", Decision: AccessDecision_UNAUTHENTICATED, Detail: "no credentials"},
+		{Consumer: "biz_user", Method: "/main.Biz/Check", Decision: AccessDecision_ALLOWED},
+	} {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		if evt.Consumer != want.Consumer || evt.Method != want.Method || evt.Decision != want.Decision || evt.Detail != want.Detail {
+			t.Errorf("have %+v, want %+v", evt, want)
+		}
+	}
+
+	stat, err := statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v, awaiting stat", err)
+	}
+	if stat.DeniedByMethod["/main.Biz/Test"] != 1 || stat.DeniedByConsumer["biz_user"] != 1 || stat.UnauthenticatedByMethod["/main.Biz/Check"] != 1 {
+		t.Errorf("bad denied counters: %v %v %v", stat.DeniedByMethod, stat.DeniedByConsumer, stat.UnauthenticatedByMethod)
+	}
+	if stat.ByMethod["/main.Biz/Test"] != 0 || stat.ByMethod["/main.Biz/Check"] != 1 {
+		t.Errorf("denied calls counted as served: %v", stat.ByMethod)
+	}
+}
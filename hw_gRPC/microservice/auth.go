@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	hmacScheme   = "HMAC "
+	bearerScheme = "Bearer "
+
+	defaultHMACSkew = 5 * time.Minute
+)
+
+// ErrNoCredentials means the call carries nothing the authenticator
+// understands, the next one in the chain gets a go.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator names the consumer behind a call.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (string, error)
+}
+
+type consumerKey struct{}
+
+func ConsumerFromContext(ctx context.Context) (string, bool) {
+	consumer, ok := ctx.Value(consumerKey{}).(string)
+	return consumer, ok
+}
+
+// Authenticate tries the authenticators in order. A call that fails one of
+// them is rejected even if a later one would accept it.
+func Authenticate(ctx context.Context, auths []Authenticator) (context.Context, error) {
+	for _, auth := range auths {
+		consumer, err := auth.Authenticate(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return context.WithValue(ctx, consumerKey{}, consumer), nil
+	}
+	return nil, status.Error(codes.Unauthenticated, ErrNoCredentials.Error())
+}
+
+func AuthUnaryInterceptor(auths []Authenticator, audit Auditor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		authCtx, err := Authenticate(ctx, auths)
+		if err != nil {
+			audit.Rejected(ctx, AccessDecision_UNAUTHENTICATED, status.Convert(err).Message())
+			return nil, err
+		}
+		ctx = authCtx
+		return handler(ctx, req)
+	}
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+func AuthStreamInterceptor(auths []Authenticator, audit Auditor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := Authenticate(stream.Context(), auths)
+		if err != nil {
+			audit.Rejected(stream.Context(), AccessDecision_UNAUTHENTICATED, status.Convert(err).Message())
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func authorization(ctx context.Context, scheme string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, val := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(val, scheme); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// MetadataAuth believes the consumer metadata value. It proves nothing
+// and is only meant for tests and trusted networks.
+type MetadataAuth struct{}
+
+func (MetadataAuth) Authenticate(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	val := md.Get("consumer")
+	if len(val) == 0 {
+		return "", ErrNoCredentials
+	}
+	if val[0] == "" {
+		return "", fmt.Errorf("empty consumer")
+	}
+	return val[0], nil
+}
+
+// CertAuth names the consumer after the common name of a verified client
+// certificate, or Consumers[common name] when Consumers is set. The server
+// needs a TLS config that asks for client certificates.
+type CertAuth struct {
+	Consumers map[string]string
+}
+
+func (c CertAuth) Authenticate(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", ErrNoCredentials
+	}
+	name := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+	if c.Consumers != nil {
+		consumer, ok := c.Consumers[name]
+		if !ok {
+			return "", fmt.Errorf("certificate %q is not mapped to a consumer", name)
+		}
+		return consumer, nil
+	}
+	if name == "" {
+		return "", fmt.Errorf("certificate without common name")
+	}
+	return name, nil
+}
+
+// HMACAuth checks "authorization: HMAC consumer:unix time:signature" where
+// the signature covers the consumer, the time and the method, so a captured
+// header only works for the same method within MaxSkew, five minutes when
+// it is zero.
+type HMACAuth struct {
+	Keys    map[string][]byte
+	MaxSkew time.Duration
+	Now     func() time.Time
+}
+
+func NewHMACAuth(keys map[string][]byte) *HMACAuth {
+	return &HMACAuth{
+		Keys:    keys,
+		MaxSkew: defaultHMACSkew,
+		Now:     time.Now,
+	}
+}
+
+func hmacSignature(key []byte, consumer string, unix string, method string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(consumer + "\n" + unix + "\n" + method))
+	return h.Sum(nil)
+}
+
+// SignHMAC builds the authorization value for one call.
+func SignHMAC(consumer string, key []byte, method string, t time.Time) string {
+	unix := strconv.FormatInt(t.Unix(), 10)
+	return hmacScheme + consumer + ":" + unix + ":" + hex.EncodeToString(hmacSignature(key, consumer, unix, method))
+}
+
+func (h *HMACAuth) Authenticate(ctx context.Context) (string, error) {
+	token, ok := authorization(ctx, hmacScheme)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed hmac token")
+	}
+	consumer, unix, sig := parts[0], parts[1], parts[2]
+	key, ok := h.Keys[consumer]
+	if !ok {
+		return "", fmt.Errorf("unknown consumer %q", consumer)
+	}
+	signed, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("malformed hmac token")
+	}
+	now, maxSkew := time.Now, h.MaxSkew
+	if h.Now != nil {
+		now = h.Now
+	}
+	if maxSkew == 0 {
+		maxSkew = defaultHMACSkew
+	}
+	if skew := now().Sub(time.Unix(signed, 0)); skew > maxSkew || skew < -maxSkew {
+		return "", fmt.Errorf("hmac token expired")
+	}
+	method, _ := grpc.Method(ctx)
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, hmacSignature(key, consumer, unix, method)) {
+		return "", fmt.Errorf("bad hmac signature")
+	}
+	return consumer, nil
+}
+
+// HMACCredentials signs every call of a client connection.
+type HMACCredentials struct {
+	Consumer string
+	Key      []byte
+}
+
+func (c HMACCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	info, ok := credentials.RequestInfoFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no request info")
+	}
+	return map[string]string{"authorization": SignHMAC(c.Consumer, c.Key, info.Method, time.Now())}, nil
+}
+
+// RequireTransportSecurity is false, the header does not reveal the key.
+func (c HMACCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// JWTAuth checks "authorization: Bearer <jwt>" and names the consumer after
+// the sub claim. HS256 tokens need Secret and RS256 tokens PublicKey; exp is
+// required, iss and aud are checked when Issuer and Audience are set.
+type JWTAuth struct {
+	Secret    []byte
+	PublicKey *rsa.PublicKey
+	Issuer    string
+	Audience  string
+	Now       func() time.Time
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+func (c jwtClaims) hasAudience(aud string) bool {
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil {
+		return one == aud
+	}
+	var many []string
+	if json.Unmarshal(c.Audience, &many) == nil {
+		for _, a := range many {
+			if a == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (j *JWTAuth) verifySignature(alg string, signed string, sig []byte) error {
+	switch {
+	case alg == "HS256" && j.Secret != nil:
+		h := hmac.New(sha256.New, j.Secret)
+		h.Write([]byte(signed))
+		if !hmac.Equal(sig, h.Sum(nil)) {
+			return fmt.Errorf("bad jwt signature")
+		}
+		return nil
+	case alg == "RS256" && j.PublicKey != nil:
+		sum := sha256.Sum256([]byte(signed))
+		if rsa.VerifyPKCS1v15(j.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+			return fmt.Errorf("bad jwt signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("jwt algorithm %q is not accepted", alg)
+}
+
+func (j *JWTAuth) Authenticate(ctx context.Context) (string, error) {
+	token, ok := authorization(ctx, bearerScheme)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed jwt")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(data, &header) != nil {
+		return "", fmt.Errorf("malformed jwt")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed jwt")
+	}
+	if err = j.verifySignature(header.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return "", err
+	}
+
+	claims := jwtClaims{}
+	data, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(data, &claims) != nil {
+		return "", fmt.Errorf("malformed jwt")
+	}
+	now := time.Now
+	if j.Now != nil {
+		now = j.Now
+	}
+	switch {
+	case claims.ExpiresAt == nil || now().Unix() >= *claims.ExpiresAt:
+		return "", fmt.Errorf("jwt expired")
+	case claims.NotBefore != nil && now().Unix() < *claims.NotBefore:
+		return "", fmt.Errorf("jwt not valid yet")
+	case j.Issuer != "" && claims.Issuer != j.Issuer:
+		return "", fmt.Errorf("jwt issuer %q is not accepted", claims.Issuer)
+	case j.Audience != "" && !claims.hasAudience(j.Audience):
+		return "", fmt.Errorf("jwt is not for %q", j.Audience)
+	case claims.Subject == "":
+		return "", fmt.Errorf("jwt without subject")
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+var jwtSecret = []byte("jwt secret")
+
+func signJWT(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func bearerCtx(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", bearerScheme+token))
+}
+
+func TestJWTAuth(t *testing.T) {
+	auth := &JWTAuth{Secret: jwtSecret, Issuer: "issuer", Audience: "microservice"}
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]interface{}{"sub": "biz_user", "iss": "issuer", "aud": []string{"other", "microservice"}, "exp": exp}
+
+	consumer, err := auth.Authenticate(bearerCtx(signJWT(t, jwtSecret, valid)))
+	if err != nil || consumer != "biz_user" {
+		t.Fatalf("valid token: have %q, %v", consumer, err)
+	}
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"biz_admin","exp":9999999999}`)) + "."
+	for name, token := range map[string]string{
+		"garbage":      "abc",
+		"alg none":     none,
+		"wrong secret": signJWT(t, []byte("other"), valid),
+		"expired":      signJWT(t, jwtSecret, map[string]interface{}{"sub": "biz_user", "iss": "issuer", "aud": "microservice", "exp": 1}),
+		"no exp":       signJWT(t, jwtSecret, map[string]interface{}{"sub": "biz_user", "iss": "issuer", "aud": "microservice"}),
+		"issuer":       signJWT(t, jwtSecret, map[string]interface{}{"sub": "biz_user", "iss": "other", "aud": "microservice", "exp": exp}),
+		"audience":     signJWT(t, jwtSecret, map[string]interface{}{"sub": "biz_user", "iss": "issuer", "aud": "other", "exp": exp}),
+		"no subject":   signJWT(t, jwtSecret, map[string]interface{}{"iss": "issuer", "aud": "microservice", "exp": exp}),
+	} {
+		if _, err := auth.Authenticate(bearerCtx(token)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := auth.Authenticate(getConsumerCtx("biz_user")); err != ErrNoCredentials {
+		t.Errorf("no bearer token: expected ErrNoCredentials, have %v", err)
+	}
+}
+
+type testPKI struct {
+	pool   *x509.CertPool
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testPKI{pool: pool, caCert: cert, caKey: key}
+}
+
+func (p *testPKI) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.caCert, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// TestAuthenticators serves over TLS with every authenticator and checks
+// that failed authentication and missing rights get different codes.
+func TestAuthenticators(t *testing.T) {
+	pki := newTestPKI(t)
+	hmacKey := []byte("hmac key")
+
+	ctx, finish := context.WithCancel(context.Background())
+	err := Start(ctx, Config{
+		Addr: listenAddr,
+		ACL:  ACLData,
+		Auth: []Authenticator{
+			CertAuth{Consumers: map[string]string{"billing.internal": "biz_user"}},
+			// a literal gets the default clock and skew
+			&HMACAuth{Keys: map[string][]byte{"biz_admin": hmacKey}},
+			&JWTAuth{Secret: jwtSecret},
+		},
+		TLS: &tls.Config{
+			Certificates: []tls.Certificate{pki.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)},
+			ClientCAs:    pki.pool,
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+		},
+	})
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	dial := func(cn string, opts ...grpc.DialOption) BizClient {
+		cfg := &tls.Config{RootCAs: pki.pool}
+		if cn != "" {
+			cfg.Certificates = []tls.Certificate{pki.issue(t, cn, 3, x509.ExtKeyUsageClientAuth)}
+		}
+		conn, err := grpc.Dial(listenAddr, append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))...)
+		if err != nil {
+			t.Fatalf("cant connect to grpc: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return NewBizClient(conn)
+	}
+	expect := func(name string, err error, want codes.Code) {
+		t.Helper()
+		if code := status.Code(err); code != want {
+			t.Errorf("%s: expected %v, have %v (%v)", name, want, code, err)
+		}
+	}
+	bearer := func(claims map[string]interface{}) context.Context {
+		return metadata.NewOutgoingContext(context.Background(), metadata.Pairs("authorization", bearerScheme+signJWT(t, jwtSecret, claims)))
+	}
+
+	anonymous := dial("")
+	_, err = anonymous.Check(context.Background(), &Nothing{})
+	expect("no credentials", err, codes.Unauthenticated)
+	_, err = anonymous.Check(getConsumerCtx("biz_admin"), &Nothing{})
+	expect("consumer metadata is not trusted", err, codes.Unauthenticated)
+
+	mapped := dial("billing.internal")
+	_, err = mapped.Check(context.Background(), &Nothing{})
+	expect("mapped certificate", err, codes.OK)
+	_, err = mapped.Test(context.Background(), &Nothing{})
+	expect("mapped certificate without rights", err, codes.PermissionDenied)
+	_, err = dial("stranger").Check(context.Background(), &Nothing{})
+	expect("unmapped certificate", err, codes.Unauthenticated)
+
+	signed := dial("", grpc.WithPerRPCCredentials(HMACCredentials{Consumer: "biz_admin", Key: hmacKey}))
+	_, err = signed.Test(context.Background(), &Nothing{})
+	expect("hmac", err, codes.OK)
+	forged := dial("", grpc.WithPerRPCCredentials(HMACCredentials{Consumer: "biz_admin", Key: []byte("guess")}))
+	_, err = forged.Test(context.Background(), &Nothing{})
+	expect("forged hmac", err, codes.Unauthenticated)
+	replayed := metadata.NewOutgoingContext(context.Background(),
+		metadata.Pairs("authorization", SignHMAC("biz_admin", hmacKey, "/main.Biz/Check", time.Now())))
+	_, err = anonymous.Test(replayed, &Nothing{})
+	expect("hmac for another method", err, codes.Unauthenticated)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	_, err = anonymous.Check(bearer(map[string]interface{}{"sub": "biz_user", "exp": exp}), &Nothing{})
+	expect("jwt", err, codes.OK)
+	_, err = anonymous.Test(bearer(map[string]interface{}{"sub": "biz_user", "exp": exp}), &Nothing{})
+	expect("jwt without rights", err, codes.PermissionDenied)
+	_, err = anonymous.Check(bearer(map[string]interface{}{"sub": "biz_user", "exp": 1}), &Nothing{})
+	expect("expired jwt", err, codes.Unauthenticated)
+}
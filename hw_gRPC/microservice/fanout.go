@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// DropPolicy says what happens to a subscriber whose buffer is full.
+type DropPolicy string
+
+const (
+	DropOldest DropPolicy = "drop-oldest"
+	DropNewest DropPolicy = "drop-newest"
+	Disconnect DropPolicy = "disconnect"
+
+	defaultEventBuffer = 1024
+)
+
+func (p DropPolicy) valid() bool {
+	return p == DropOldest || p == DropNewest || p == Disconnect
+}
+
+// subscriber buffers events for one stream in a ring, so publishing never
+// waits for a slow reader and the reader sees events in publish order.
+type subscriber struct {
+	mu     sync.Mutex
+	ring   []*Event
+	head   int
+	size   int
+	policy DropPolicy
+	match  eventMatcher
+	closed bool
+
+	// ready has room for one wake up, gone is closed when the subscriber
+	// is cut off under the Disconnect policy.
+	ready chan struct{}
+	gone  chan struct{}
+}
+
+// push reports whether an event was lost and whether the subscriber was
+// cut off.
+func (s *subscriber) push(event *Event) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, false
+	}
+	dropped := false
+	if s.size == len(s.ring) {
+		switch s.policy {
+		case DropNewest:
+			return true, false
+		case Disconnect:
+			s.closed = true
+			close(s.gone)
+			return true, true
+		default:
+			s.ring[s.head] = nil
+			s.head = (s.head + 1) % len(s.ring)
+			s.size--
+			dropped = true
+		}
+	}
+	s.ring[(s.head+s.size)%len(s.ring)] = event
+	s.size++
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return dropped, false
+}
+
+func (s *subscriber) pop() (*Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size == 0 {
+		return nil, false
+	}
+	event := s.ring[s.head]
+	s.ring[s.head] = nil
+	s.head = (s.head + 1) % len(s.ring)
+	s.size--
+	return event, true
+}
+
+// Fanout numbers every published event, keeps it in the history and hands
+// it to all subscribers. Dropped counts the events subscribers lost,
+// Disconnected the subscribers cut off.
+type Fanout struct {
+	mu      sync.Mutex
+	subs    map[*subscriber]struct{}
+	buffer  int
+	policy  DropPolicy
+	history *History
+	seq     uint64
+
+	dropped      atomic.Uint64
+	disconnected atomic.Uint64
+}
+
+func NewFanout(buffer int, policy DropPolicy, history *History) (*Fanout, error) {
+	if buffer == 0 {
+		buffer = defaultEventBuffer
+	}
+	if policy == "" {
+		policy = DropOldest
+	}
+	if buffer < 0 {
+		return nil, fmt.Errorf("event buffer must be positive")
+	}
+	if !policy.valid() {
+		return nil, fmt.Errorf("unknown drop policy %q", policy)
+	}
+	f := &Fanout{
+		subs:    make(map[*subscriber]struct{}),
+		buffer:  buffer,
+		policy:  policy,
+		history: history,
+	}
+	if history != nil {
+		f.seq = history.LastSeq()
+	}
+	return f, nil
+}
+
+// Subscribe only buffers the events match accepts, nil means all of them.
+// With replay the subscriber starts with the matching history; taking it
+// under the publish lock means no event is missed or seen twice.
+func (f *Fanout) Subscribe(match eventMatcher, replay bool) *subscriber {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var past []*Event
+	if replay && f.history != nil {
+		for _, event := range f.history.Events() {
+			if match == nil || match(event) {
+				past = append(past, event)
+			}
+		}
+	}
+	s := &subscriber{
+		ring:   make([]*Event, f.buffer+len(past)),
+		policy: f.policy,
+		match:  match,
+		ready:  make(chan struct{}, 1),
+		gone:   make(chan struct{}),
+	}
+	for _, event := range past {
+		s.push(event)
+	}
+	f.subs[s] = struct{}{}
+	return s
+}
+
+func (f *Fanout) Unsubscribe(s *subscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subs, s)
+}
+
+// Publish sets the event Seq and never blocks on subscribers. Events are
+// shared between them and must not be changed afterwards. The history file
+// is written after the publish lock is released.
+func (f *Fanout) Publish(event *Event) {
+	f.publish(event)
+	if f.history != nil {
+		if err := f.history.Flush(); err != nil {
+			log.Println("event history:", err)
+		}
+	}
+}
+
+func (f *Fanout) publish(event *Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	event.Seq = f.seq
+	if f.history != nil {
+		f.history.Append(event)
+	}
+	for s := range f.subs {
+		if s.match != nil && !s.match(event) {
+			continue
+		}
+		dropped, cut := s.push(event)
+		if dropped {
+			f.dropped.Add(1)
+		}
+		if cut {
+			f.disconnected.Add(1)
+			delete(f.subs, s)
+		}
+	}
+}
+
+func (f *Fanout) Dropped() uint64 {
+	return f.dropped.Load()
+}
+
+func (f *Fanout) Disconnected() uint64 {
+	return f.disconnected.Load()
+}
+
+func (f *Fanout) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.history == nil {
+		return nil
+	}
+	return f.history.Close()
+}
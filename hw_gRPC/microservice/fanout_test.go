@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	grpc "google.golang.org/grpc"
+)
+
+func publishN(f *Fanout, n int) {
+	for i := 1; i <= n; i++ {
+		f.Publish(&Event{Timestamp: int64(i)})
+	}
+}
+
+func drain(s *subscriber) []int64 {
+	got := []int64{}
+	for event, ok := s.pop(); ok; event, ok = s.pop() {
+		got = append(got, event.Timestamp)
+	}
+	return got
+}
+
+func TestFanoutPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy  DropPolicy
+		want    []int64
+		dropped uint64
+		cut     uint64
+	}{
+		{DropOldest, []int64{3, 4, 5}, 2, 0},
+		{DropNewest, []int64{1, 2, 3}, 2, 0},
+		{Disconnect, []int64{1, 2, 3}, 1, 1},
+	} {
+		f, err := NewFanout(3, tc.policy, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slow := f.Subscribe(nil, false)
+		publishN(f, 5)
+
+		if got := drain(slow); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: have %v, want %v", tc.policy, got, tc.want)
+		}
+		if f.Dropped() != tc.dropped || f.Disconnected() != tc.cut {
+			t.Errorf("%s: have %d dropped %d disconnected, want %d %d", tc.policy, f.Dropped(), f.Disconnected(), tc.dropped, tc.cut)
+		}
+		select {
+		case <-slow.gone:
+			if tc.policy != Disconnect {
+				t.Errorf("%s: subscriber was disconnected", tc.policy)
+			}
+		default:
+			if tc.policy == Disconnect {
+				t.Errorf("%s: subscriber was not disconnected", tc.policy)
+			}
+		}
+	}
+}
+
+func TestFanoutOrder(t *testing.T) {
+	f, _ := NewFanout(0, "", nil)
+	first, second := f.Subscribe(nil, false), f.Subscribe(nil, false)
+	publishN(f, 100)
+	f.Unsubscribe(second)
+	publishN(f, 1)
+
+	if got := drain(first); len(got) != 101 || got[0] != 1 || got[99] != 100 || got[100] != 1 {
+		t.Errorf("first subscriber: have %v", got)
+	}
+	if got := drain(second); len(got) != 100 || got[99] != 100 {
+		t.Errorf("second subscriber: have %v", got)
+	}
+	if _, err := NewFanout(10, "block", nil); err == nil {
+		t.Errorf("expected error for unknown policy")
+	}
+}
+
+// statStream is a Statistics stream that keeps what it is sent.
+type statStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	started chan struct{}
+	sent    chan *Stat
+}
+
+func (s *statStream) Context() context.Context {
+	close(s.started)
+	return s.ctx
+}
+
+func (s *statStream) Send(stat *Stat) error {
+	s.sent <- stat
+	return nil
+}
+
+func TestStatisticsDrops(t *testing.T) {
+	f, _ := NewFanout(1, Disconnect, nil)
+	f.Subscribe(nil, false)
+	publishN(f, 3) // before the stream, not counted
+	f.Subscribe(nil, false)
+	admin := &AdminServerStruct{Events: f, Stats: NewStatsHub()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &statStream{ctx: ctx, started: make(chan struct{}), sent: make(chan *Stat, 1)}
+	go admin.Statistics(&StatInterval{IntervalSeconds: 1}, stream)
+	<-stream.started
+	time.Sleep(50 * time.Millisecond)
+	publishN(f, 3)
+
+	select {
+	case stat := <-stream.sent:
+		if stat.EventsDropped != 1 || stat.SubscribersDisconnected != 1 {
+			t.Errorf("have %d dropped %d disconnected, want 1 1", stat.EventsDropped, stat.SubscribersDisconnected)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no stat sent")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"path"
+)
+
+// eventMatcher is a compiled LogFilter, nil matches every event.
+type eventMatcher func(*Event) bool
+
+func compileFilter(f *LogFilter) (eventMatcher, error) {
+	if f == nil {
+		return nil, nil
+	}
+	consumers := f.GetConsumers()
+	method := f.GetMethod()
+	since := f.GetSince()
+	afterSeq := f.GetAfterSeq()
+	if method != "" {
+		if _, err := path.Match(method, ""); err != nil {
+			return nil, fmt.Errorf("bad method pattern %q", method)
+		}
+	}
+	var hosts *net.IPNet
+	if f.GetHost() != "" {
+		var err error
+		if hosts, err = parseNet(f.GetHost()); err != nil {
+			return nil, err
+		}
+	}
+	if len(consumers) == 0 && method == "" && hosts == nil && since == 0 && afterSeq == 0 {
+		return nil, nil
+	}
+
+	return func(event *Event) bool {
+		if event.Timestamp < since || event.Seq <= afterSeq {
+			return false
+		}
+		if len(consumers) > 0 && !contains(consumers, event.Consumer) {
+			return false
+		}
+		if method != "" {
+			if ok, _ := path.Match(method, event.Method); !ok {
+				return false
+			}
+		}
+		if hosts != nil {
+			host, _, err := net.SplitHostPort(event.Host)
+			if err != nil {
+				host = event.Host
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !hosts.Contains(ip) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+func TestCompileFilter(t *testing.T) {
+	event := &Event{Consumer: "biz_user", Method: "/main.Biz/Check", Host: "10.1.2.3:5000", Timestamp: 100, Seq: 7}
+	for _, tc := range []struct {
+		filter *LogFilter
+		match  bool
+	}{
+		{&LogFilter{}, true},
+		{&LogFilter{Consumers: []string{"logger", "biz_user"}}, true},
+		{&LogFilter{Consumers: []string{"logger"}}, false},
+		{&LogFilter{Method: "/main.Biz/*"}, true},
+		{&LogFilter{Method: "/main.Admin/*"}, false},
+		{&LogFilter{Host: "10.1.0.0/16"}, true},
+		{&LogFilter{Host: "10.1.2.4"}, false},
+		{&LogFilter{Since: 100}, true},
+		{&LogFilter{Since: 101}, false},
+		{&LogFilter{AfterSeq: 6}, true},
+		{&LogFilter{AfterSeq: 7}, false},
+		{&LogFilter{Consumers: []string{"biz_user"}, Method: "/main.Biz/Check", Host: "10.1.2.3", Since: 50}, true},
+	} {
+		match, err := compileFilter(tc.filter)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.filter, err)
+		}
+		if got := match == nil || match(event); got != tc.match {
+			t.Errorf("%v: have %v, want %v", tc.filter, got, tc.match)
+		}
+	}
+
+	for _, bad := range []*LogFilter{{Method: "/main.Biz/["}, {Host: "localhost"}} {
+		if _, err := compileFilter(bad); err == nil {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
+
+func TestLoggingFilter(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	bad, err := adm.Logging(getConsumerCtx("logger"), &LogFilter{Method: "["})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = bad.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+
+	logStream, err := adm.Logging(getConsumerCtx("logger"), &LogFilter{Consumers: []string{"biz_admin"}, Method: "/main.Biz/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+	adm.Logging(getConsumerCtx("biz_admin"), &LogFilter{})
+	biz.Add(getConsumerCtx("biz_admin"), &Nothing{})
+
+	for _, want := range []string{"/main.Biz/Test", "/main.Biz/Add"} {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		if evt.Consumer != "biz_admin" || evt.Method != want {
+			t.Errorf("have %s %s, want biz_admin %s", evt.Consumer, evt.Method, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const defaultHistorySize = 1000
+
+// History keeps the last events for replay. With a file every event is
+// appended to it as a JSON line and the file is read back on start, so
+// sequence numbers keep growing across restarts. The file is rewritten
+// from memory once it holds twice as many events as the history.
+//
+// Append only touches memory, the file is written by Flush under its own
+// lock, so publishing never waits for the disk. path does not change after
+// NewHistory; file and lines belong to fileMu alone.
+type History struct {
+	mu      sync.Mutex
+	ring    []*Event
+	head    int
+	size    int
+	pending []*Event
+	closed  bool
+
+	fileMu sync.Mutex
+	path   string
+	file   *os.File
+	lines  int
+}
+
+func NewHistory(size int, path string) (*History, error) {
+	if size == 0 {
+		size = defaultHistorySize
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("history size must be positive")
+	}
+	h := &History{ring: make([]*Event, size), path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err = h.load(data); err != nil {
+		return nil, err
+	}
+	h.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// load reads the file contents. A crash while appending leaves the last
+// line torn; it is dropped and cut off the file, a bad line before it is
+// an error.
+func (h *History) load(data []byte) error {
+	for offset := 0; offset < len(data); {
+		line, rest, complete := bytes.Cut(data[offset:], []byte("\n"))
+		event := &Event{}
+		if err := protojson.Unmarshal(line, event); err != nil {
+			if complete && len(rest) > 0 {
+				return fmt.Errorf("history %s: %w", h.path, err)
+			}
+			log.Printf("history %s: dropping torn last record: %v", h.path, err)
+			return os.Truncate(h.path, int64(offset))
+		}
+		h.add(event)
+		h.lines++
+		if !complete {
+			// the record made it but its newline did not
+			return appendNewline(h.path)
+		}
+		offset += len(line) + 1
+	}
+	return nil
+}
+
+func appendNewline(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write([]byte("\n")); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (h *History) add(event *Event) {
+	if h.size == len(h.ring) {
+		h.head = (h.head + 1) % len(h.ring)
+		h.size--
+	}
+	h.ring[(h.head+h.size)%len(h.ring)] = event
+	h.size++
+}
+
+// Append stores the event in memory and queues it for Flush until the
+// history is closed.
+func (h *History) Append(event *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.add(event)
+	if h.path != "" && !h.closed {
+		h.pending = append(h.pending, event)
+	}
+}
+
+// Flush writes the queued events to the file in the order they were
+// appended. A failed write loses them from the file, not from memory.
+func (h *History) Flush() error {
+	h.fileMu.Lock()
+	defer h.fileMu.Unlock()
+	h.mu.Lock()
+	pending := h.pending
+	h.pending = nil
+	var events []*Event
+	if h.file != nil && h.lines+len(pending) >= 2*len(h.ring) {
+		events = h.events()
+	}
+	h.mu.Unlock()
+	if h.file == nil || len(pending) == 0 {
+		return nil
+	}
+	if events != nil {
+		return h.compact(events)
+	}
+
+	var buf bytes.Buffer
+	for _, event := range pending {
+		line, err := protojson.Marshal(event)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+	h.lines += len(pending)
+	_, err := h.file.Write(buf.Bytes())
+	return err
+}
+
+// compact rewrites the file with events, h.fileMu must be held.
+func (h *History) compact(events []*Event) error {
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for _, event := range events {
+		line, err := protojson.Marshal(event)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err = w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), h.path); err != nil {
+		return err
+	}
+	h.file.Close()
+	h.lines = len(events)
+	h.file, err = os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+// Events returns the history oldest first.
+func (h *History) Events() []*Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.events()
+}
+
+func (h *History) events() []*Event {
+	events := make([]*Event, 0, h.size)
+	for i := 0; i < h.size; i++ {
+		events = append(events, h.ring[(h.head+i)%len(h.ring)])
+	}
+	return events
+}
+
+// LastSeq is 0 for an empty history.
+func (h *History) LastSeq() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.size == 0 {
+		return 0
+	}
+	return h.ring[(h.head+h.size-1)%len(h.ring)].Seq
+}
+
+// Close flushes what is queued and closes the file. Events appended after
+// it stay in memory only.
+func (h *History) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	err := h.Flush()
+	h.fileMu.Lock()
+	defer h.fileMu.Unlock()
+	if h.file == nil {
+		return err
+	}
+	if closeErr := h.file.Close(); err == nil {
+		err = closeErr
+	}
+	h.file = nil
+	return err
+}
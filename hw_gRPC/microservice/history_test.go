@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func seqs(events []*Event) []uint64 {
+	got := []uint64{}
+	for _, event := range events {
+		got = append(got, event.Seq)
+	}
+	return got
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	history, err := NewHistory(3, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := NewFanout(0, "", history)
+	publishN(f, 8)
+	f.Close()
+
+	history, err = NewHistory(3, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	if got := seqs(history.Events()); !reflect.DeepEqual(got, []uint64{6, 7, 8}) {
+		t.Errorf("reloaded history: have %v", got)
+	}
+	if history.LastSeq() != 8 {
+		t.Errorf("have last seq %d, want 8", history.LastSeq())
+	}
+	if history.lines > 6 {
+		t.Errorf("history file was not compacted: %d lines", history.lines)
+	}
+}
+
+func TestHistoryTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	whole := "{\"seq\":\"1\"}\n{\"seq\":\"2\"}\n"
+	for _, tc := range []struct {
+		name string
+		data string
+		want []uint64
+	}{
+		{"partial record", whole + `{"seq":"3","consu`, []uint64{1, 2}},
+		{"missing newline", whole + `{"seq":"3"}`, []uint64{1, 2, 3}},
+	} {
+		if err := os.WriteFile(path, []byte(tc.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		history, err := NewHistory(10, path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := seqs(history.Events()); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: have %v, want %v", tc.name, got, tc.want)
+		}
+		f, _ := NewFanout(0, "", history)
+		publishN(f, 1)
+		f.Close()
+
+		history, err = NewHistory(10, path)
+		if err != nil {
+			t.Fatalf("%s: reopen: %v", tc.name, err)
+		}
+		want := append(tc.want, tc.want[len(tc.want)-1]+1)
+		if got := seqs(history.Events()); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: after append have %v, want %v", tc.name, got, want)
+		}
+		history.Close()
+	}
+
+	// only the last line may be torn
+	if err := os.WriteFile(path, []byte(`{"seq":"1","consu`+"\n"+whole), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHistory(10, path); err == nil {
+		t.Errorf("expected error for a bad record before the end")
+	}
+}
+
+func TestHistoryFileConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	history, err := NewHistory(100, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := NewFanout(0, "", history)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			publishN(f, 25)
+		}()
+	}
+	wg.Wait()
+	f.Close()
+
+	// the file is written outside the publish lock but still in seq order
+	history, err = NewHistory(100, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	got := seqs(history.Events())
+	if len(got) != 100 || !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] < got[j] }) || got[99] != 200 {
+		t.Errorf("reloaded history out of order: %v", got)
+	}
+}
+
+func TestHistoryCloseWhilePublishing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	history, err := NewHistory(4, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := NewFanout(0, "", history)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			publishN(f, 50)
+		}()
+	}
+	// the small history compacts the file over and over while it is closed
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			history.Flush()
+		}
+		history.Close()
+	}()
+	wg.Wait()
+	if err = history.Flush(); err != nil {
+		t.Errorf("flush after close: %v", err)
+	}
+	if got := history.LastSeq(); got != 200 {
+		t.Errorf("have last seq %d, want 200", got)
+	}
+
+	history, err = NewHistory(4, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	if got := seqs(history.Events()); !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] < got[j] }) {
+		t.Errorf("reloaded history out of order: %v", got)
+	}
+}
+
+func TestFanoutReplay(t *testing.T) {
+	history, _ := NewHistory(5, "")
+	f, _ := NewFanout(1, Disconnect, history)
+	publishN(f, 7)
+
+	match, _ := compileFilter(&LogFilter{AfterSeq: 4})
+	sub := f.Subscribe(match, true)
+	f.Publish(&Event{Timestamp: 8})
+	if got := drain(sub); !reflect.DeepEqual(got, []int64{5, 6, 7, 8}) {
+		t.Errorf("have %v, want replayed 5 6 7 and live 8", got)
+	}
+	everything := f.Subscribe(nil, true)
+	if got := drain(everything); !reflect.DeepEqual(got, []int64{4, 5, 6, 7, 8}) {
+		t.Errorf("have %v, want the whole history", got)
+	}
+}
+
+func TestLoggingReplay(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+
+	recv := func(filter *LogFilter, n int) []*Event {
+		streamCtx, cancel := context.WithCancel(getConsumerCtx("logger"))
+		defer cancel()
+		logStream, err := adm.Logging(streamCtx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := []*Event{}
+		for i := 0; i < n; i++ {
+			evt, err := logStream.Recv()
+			if err != nil {
+				t.Fatalf("unexpected error: %v, awaiting event", err)
+			}
+			events = append(events, evt)
+		}
+		return events
+	}
+
+	replayed := recv(&LogFilter{Since: 1, Method: "/main.Biz/*"}, 2)
+	if replayed[0].Method != "/main.Biz/Check" || replayed[1].Method != "/main.Biz/Add" || replayed[0].Seq >= replayed[1].Seq {
+		t.Fatalf("bad replay: %v", replayed)
+	}
+
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+	resumed := recv(&LogFilter{AfterSeq: replayed[1].Seq, Method: "/main.Biz/*"}, 1)
+	if resumed[0].Method != "/main.Biz/Test" {
+		t.Errorf("resumed with %v, want the Test call", resumed[0])
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unknownConsumer labels the calls of consumers the ACL does not list by
+// name, so a client cannot add series by making up consumer names.
+const unknownConsumer = "unknown"
+
+// ServerMetrics has the usual grpc_server_* series for every call, denied
+// ones included, and the Statistics counters per consumer.
+type ServerMetrics struct {
+	ACL      *ACL
+	Registry *prometheus.Registry
+	Started  *prometheus.CounterVec
+	Handled  *prometheus.CounterVec
+	Latency  *prometheus.HistogramVec
+	Calls    *prometheus.CounterVec
+}
+
+func NewServerMetrics(acl *ACL, events *Fanout) *ServerMetrics {
+	labels := []string{"grpc_type", "grpc_service", "grpc_method"}
+	m := &ServerMetrics{
+		ACL:      acl,
+		Registry: prometheus.NewRegistry(),
+		Started: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_started_total",
+			Help: "Number of RPCs started on the server.",
+		}, labels),
+		Handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Number of RPCs completed on the server, regardless of success or failure.",
+		}, append(labels, "grpc_code")),
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Response latency of RPCs handled by the server.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		Calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "microservice",
+			Name:      "consumer_calls_total",
+			Help:      "Number of calls per consumer, method and access decision.",
+		}, []string{"consumer", "method", "decision"}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.Started,
+		m.Handled,
+		m.Latency,
+		m.Calls,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: "microservice",
+			Name:      "admin_events_dropped_total",
+			Help:      "Number of events Logging subscribers lost because they fell behind.",
+		}, func() float64 { return float64(events.Dropped()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: "microservice",
+			Name:      "admin_subscribers_disconnected_total",
+			Help:      "Number of Logging subscribers cut off because they fell behind.",
+		}, func() float64 { return float64(events.Disconnected()) }),
+	)
+	return m
+}
+
+func (m *ServerMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// countEvent is fed from the admin events, so it sees the same calls as
+// Logging and Statistics. Consumers matched only by a wildcard, or not at
+// all, are counted as unknown.
+func (m *ServerMetrics) countEvent(event *Event) {
+	if event.Kind != EventKind_CALL {
+		return
+	}
+	consumer := event.Consumer
+	if consumer == "" || !m.ACL.Names(consumer) {
+		consumer = unknownConsumer
+	}
+	m.Calls.WithLabelValues(consumer, event.Method, event.Decision.String()).Inc()
+}
+
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
+
+func (m *ServerMetrics) observe(kind, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	m.Handled.WithLabelValues(kind, service, method, status.Code(err).String()).Inc()
+	m.Latency.WithLabelValues(kind, service, method).Observe(time.Since(start).Seconds())
+}
+
+func (m *ServerMetrics) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		service, method := splitMethod(info.FullMethod)
+		m.Started.WithLabelValues("unary", service, method).Inc()
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observe("unary", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func (m *ServerMetrics) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		kind := "bidi_stream"
+		switch {
+		case info.IsServerStream && !info.IsClientStream:
+			kind = "server_stream"
+		case info.IsClientStream && !info.IsServerStream:
+			kind = "client_stream"
+		}
+		service, method := splitMethod(info.FullMethod)
+		m.Started.WithLabelValues(kind, service, method).Inc()
+		start := time.Now()
+		err := handler(srv, stream)
+		m.observe(kind, info.FullMethod, start, err)
+		return err
+	}
+}
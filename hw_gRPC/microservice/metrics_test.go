@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	metricsAddr = "127.0.0.1:8083"
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+func TestExporters(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData, WithMetrics(metricsAddr), WithTracing(provider))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+
+	traced := metadata.AppendToOutgoingContext(getConsumerCtx("biz_user"), "traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err = biz.Check(traced, &Nothing{}); err != nil {
+		t.Fatal(err)
+	}
+	biz.Test(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("made_up"), &Nothing{})
+
+	resp, err := http.Get("http://" + metricsAddr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), `consumer="made_up"`) {
+		t.Errorf("consumer unknown to the ACL got its own series")
+	}
+	for _, want := range []string{
+		`grpc_server_started_total{grpc_method="Check",grpc_service="main.Biz",grpc_type="unary"} 1`,
+		`grpc_server_handled_total{grpc_code="OK",grpc_method="Check",grpc_service="main.Biz",grpc_type="unary"} 1`,
+		`grpc_server_handled_total{grpc_code="PermissionDenied",grpc_method="Test",grpc_service="main.Biz",grpc_type="unary"} 1`,
+		`grpc_server_handling_seconds_count{grpc_method="Check",grpc_service="main.Biz",grpc_type="unary"} 1`,
+		`microservice_consumer_calls_total{consumer="biz_user",decision="ALLOWED",method="/main.Biz/Check"} 1`,
+		`microservice_consumer_calls_total{consumer="biz_user",decision="DENIED",method="/main.Biz/Test"} 1`,
+		`microservice_consumer_calls_total{consumer="unknown",decision="DENIED",method="/main.Biz/Add"} 1`,
+		`microservice_admin_events_dropped_total 0`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("no %s in metrics", want)
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("have %d spans, want 3", len(spans))
+	}
+	check := spans[0]
+	if check.Name() != "main.Biz/Check" || check.SpanContext().TraceID().String() != traceID || !check.Parent().IsRemote() {
+		t.Errorf("span does not continue the incoming trace: %s %s", check.Name(), check.SpanContext().TraceID())
+	}
+	if !hasAttribute(check.Attributes(), attribute.String("rpc.consumer", "biz_user")) {
+		t.Errorf("no consumer on span: %v", check.Attributes())
+	}
+	if denied := spans[1]; denied.Status().Description != "no rights" || denied.SpanContext().TraceID().String() == traceID {
+		t.Errorf("bad span for the denied call: %v %s", denied.Status(), denied.SpanContext().TraceID())
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
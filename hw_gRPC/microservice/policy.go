@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Rule grants or denies the consumers the methods. Consumers are glob
+// patterns over consumer names or @group references, methods are glob
+// patterns over full method names where * stops at a slash, so
+// "/main.Biz/*" is every Biz method and "/main.*/Check" is Check on every
+// service. When From is set the rule only applies to peers in one of the
+// listed addresses or CIDR ranges.
+type Rule struct {
+	Effect    string   `json:"effect"`
+	Consumers []string `json:"consumers"`
+	Methods   []string `json:"methods"`
+	From      []string `json:"from,omitempty"`
+
+	nets []*net.IPNet
+}
+
+// Quota lets the consumers make Burst calls at once and Rate calls a second
+// after that. Every consumer gets its own token bucket, or one per method
+// with PerMethod. A call has to fit in every quota that matches it.
+type Quota struct {
+	Consumers []string `json:"consumers"`
+	Methods   []string `json:"methods"`
+	Rate      float64  `json:"rate"`
+	Burst     float64  `json:"burst"`
+	PerMethod bool     `json:"per_method,omitempty"`
+}
+
+// Policy is the ACL file. Any deny rule that matches wins over the allow
+// rules, and a call no rule allows is denied.
+//
+// The original format, a map of consumer to method patterns, is still
+// accepted and read as one allow rule per consumer.
+type Policy struct {
+	Groups map[string][]string `json:"groups,omitempty"`
+	Rules  []Rule              `json:"rules"`
+	Quotas []Quota             `json:"quotas,omitempty"`
+}
+
+// Decision is the outcome of a check. Rule is the index of the deciding
+// rule, -1 when nothing matched and the call is denied by default.
+type Decision struct {
+	Allowed bool
+	Rule    int
+	Reason  string
+}
+
+func (r Rule) String() string {
+	s := fmt.Sprintf("%s %s to %s", r.Effect, strings.Join(r.Consumers, ","), strings.Join(r.Methods, ","))
+	if len(r.From) > 0 {
+		s += " from " + strings.Join(r.From, ",")
+	}
+	return s
+}
+
+func validMethodPattern(pattern string) bool {
+	if !strings.HasPrefix(pattern, "/") || strings.Count(pattern, "/") != 2 {
+		return false
+	}
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+func parseNet(from string) (*net.IPNet, error) {
+	if strings.Contains(from, "/") {
+		_, ipNet, err := net.ParseCIDR(from)
+		return ipNet, err
+	}
+	ip := net.ParseIP(from)
+	if ip == nil {
+		return nil, fmt.Errorf("bad address %q", from)
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// legacyRules reads {"consumer": ["/method/pattern"]}. It reports false when
+// data is a Policy.
+func legacyRules(data []byte) ([]Rule, bool, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, err
+	}
+	for _, key := range []string{"rules", "quotas"} {
+		if val, ok := raw[key]; ok {
+			var names []string
+			if json.Unmarshal(val, &names) != nil || len(names) == 0 {
+				return nil, false, nil
+			}
+		}
+	}
+
+	var mp map[string][]string
+	if err := json.Unmarshal(data, &mp); err != nil {
+		return nil, false, err
+	}
+	consumers := make([]string, 0, len(mp))
+	for consumer := range mp {
+		consumers = append(consumers, consumer)
+	}
+	sort.Strings(consumers)
+	rules := make([]Rule, 0, len(consumers))
+	for _, consumer := range consumers {
+		rules = append(rules, Rule{Effect: EffectAllow, Consumers: []string{consumer}, Methods: mp[consumer]})
+	}
+	return rules, true, nil
+}
+
+// ParseACL checks the whole policy before anything uses it, so a bad edit
+// never replaces a working policy.
+func ParseACL(acl []byte) (*Policy, error) {
+	policy := &Policy{}
+	rules, legacy, err := legacyRules(acl)
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		policy.Rules = rules
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(acl))
+		dec.DisallowUnknownFields()
+		if err = dec.Decode(policy); err != nil {
+			return nil, err
+		}
+	}
+
+	for group, members := range policy.Groups {
+		if group == "" || len(members) == 0 {
+			return nil, fmt.Errorf("acl: group %q: no members", group)
+		}
+		for _, member := range members {
+			if member == "" || strings.HasPrefix(member, "@") {
+				return nil, fmt.Errorf("acl: group %q: bad member %q", group, member)
+			}
+		}
+	}
+	for i := range policy.Rules {
+		if err = policy.compile(&policy.Rules[i]); err != nil {
+			return nil, fmt.Errorf("acl: rule %d: %w", i, err)
+		}
+	}
+	for i, q := range policy.Quotas {
+		if err = policy.checkPatterns(q.Consumers, q.Methods); err == nil && (q.Rate <= 0 || q.Burst < 1) {
+			err = fmt.Errorf("rate must be positive and burst at least 1")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("acl: quota %d: %w", i, err)
+		}
+	}
+	return policy, nil
+}
+
+func (p *Policy) compile(r *Rule) error {
+	if r.Effect != EffectAllow && r.Effect != EffectDeny {
+		return fmt.Errorf("effect must be %q or %q", EffectAllow, EffectDeny)
+	}
+	if err := p.checkPatterns(r.Consumers, r.Methods); err != nil {
+		return err
+	}
+	for _, from := range r.From {
+		ipNet, err := parseNet(from)
+		if err != nil {
+			return err
+		}
+		r.nets = append(r.nets, ipNet)
+	}
+	return nil
+}
+
+func (p *Policy) checkPatterns(consumers, methods []string) error {
+	if len(consumers) == 0 || len(methods) == 0 {
+		return fmt.Errorf("consumers and methods are required")
+	}
+	for _, consumer := range consumers {
+		if group, ok := strings.CutPrefix(consumer, "@"); ok {
+			if _, known := p.Groups[group]; !known {
+				return fmt.Errorf("unknown group %q", group)
+			}
+			continue
+		}
+		if _, err := path.Match(consumer, ""); consumer == "" || err != nil {
+			return fmt.Errorf("bad consumer %q", consumer)
+		}
+	}
+	for _, method := range methods {
+		if !validMethodPattern(method) {
+			return fmt.Errorf("bad method %q", method)
+		}
+	}
+	return nil
+}
+
+func (p *Policy) matchConsumer(patterns []string, consumer string) bool {
+	for _, pattern := range patterns {
+		if group, ok := strings.CutPrefix(pattern, "@"); ok {
+			if p.matchConsumer(p.Groups[group], consumer) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, consumer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// names reports whether the policy spells the consumer out in a rule, a
+// quota or a group instead of only matching it with a wildcard.
+func (p *Policy) names(consumer string) bool {
+	literal := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if pattern == consumer {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range p.Rules {
+		if literal(r.Consumers) {
+			return true
+		}
+	}
+	for _, q := range p.Quotas {
+		if literal(q.Consumers) {
+			return true
+		}
+	}
+	for _, members := range p.Groups {
+		if literal(members) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFrom takes the peer as host:port or a bare host. A rule with
+// addresses never matches an unknown peer.
+func (r *Rule) matchFrom(host string) bool {
+	if len(r.nets) == 0 {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range r.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) Explain(consumer, method, host string) Decision {
+	allow := -1
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !p.matchConsumer(r.Consumers, consumer) || !matchMethod(r.Methods, method) || !r.matchFrom(host) {
+			continue
+		}
+		if r.Effect == EffectDeny {
+			return Decision{Allowed: false, Rule: i, Reason: fmt.Sprintf("rule %d: %s", i, r)}
+		}
+		if allow < 0 {
+			allow = i
+		}
+	}
+	if allow < 0 {
+		return Decision{Allowed: false, Rule: -1, Reason: fmt.Sprintf("no rule allows %s to call %s", consumer, method)}
+	}
+	return Decision{Allowed: true, Rule: allow, Reason: fmt.Sprintf("rule %d: %s", allow, &p.Rules[allow])}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+const policyData string = `{
+	"groups": {
+		"biz":   ["biz_user", "biz_admin"],
+		"batch": ["job-*"]
+	},
+	"rules": [
+		{"effect": "allow", "consumers": ["@biz"], "methods": ["/main.Biz/*"]},
+		{"effect": "deny",  "consumers": ["biz_user"], "methods": ["/main.Biz/Test"]},
+		{"effect": "allow", "consumers": ["@batch"], "methods": ["/main.*/Check"], "from": ["10.0.0.0/8", "::1"]},
+		{"effect": "deny",  "consumers": ["*"], "methods": ["/main.Admin/*"], "from": ["192.168.0.0/16"]},
+		{"effect": "allow", "consumers": ["logger"], "methods": ["/main.Admin/Logging"]}
+	]
+}`
+
+func TestPolicyExplain(t *testing.T) {
+	acl, err := NewACL(policyData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		consumer, method, host string
+		allowed                bool
+		rule                   int
+	}{
+		{"biz_admin", "/main.Biz/Test", "", true, 0},
+		{"biz_user", "/main.Biz/Check", "", true, 0},
+		// the deny comes after the allow and still wins
+		{"biz_user", "/main.Biz/Test", "", false, 1},
+		// groups expand to glob patterns
+		{"job-42", "/main.Biz/Check", "10.1.2.3:4000", true, 2},
+		{"job-42", "/main.Admin/Check", "[::1]:4000", true, 2},
+		{"job-42", "/main.Biz/Check", "127.0.0.1:4000", false, -1},
+		// address rules never match an unknown peer
+		{"job-42", "/main.Biz/Check", "", false, -1},
+		{"logger", "/main.Admin/Logging", "127.0.0.1:4000", true, 4},
+		{"logger", "/main.Admin/Logging", "192.168.1.1:4000", false, 3},
+		{"unknown", "/main.Biz/Check", "", false, -1},
+		// * stops at a slash
+		{"biz_user", "/main.Biz/Check/x", "", false, -1},
+	} {
+		d := acl.ExplainFrom(tc.consumer, tc.method, tc.host)
+		if d.Allowed != tc.allowed || d.Rule != tc.rule {
+			t.Errorf("%s %s from %q: want %v by rule %d, have %+v", tc.consumer, tc.method, tc.host, tc.allowed, tc.rule, d)
+		}
+	}
+
+	d := acl.Explain("biz_user", "/main.Biz/Test")
+	if d.Reason != "rule 1: deny biz_user to /main.Biz/Test" {
+		t.Errorf("bad reason: %q", d.Reason)
+	}
+	d = acl.Explain("unknown", "/main.Biz/Check")
+	if d.Reason != "no rule allows unknown to call /main.Biz/Check" {
+		t.Errorf("bad reason: %q", d.Reason)
+	}
+}
+
+func TestPolicyLegacy(t *testing.T) {
+	acl, err := NewACL(ACLData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the old prefix match let /main.Biz/* through to any service starting
+	// with main.Biz
+	if acl.CheckPermission("biz_admin", "/main.BizX/Check") {
+		t.Errorf("/main.Biz/* matched another service")
+	}
+	if d := acl.Explain("biz_user", "/main.Biz/Add"); !d.Allowed || d.Reason != "rule 1: allow biz_user to /main.Biz/Check,/main.Biz/Add" {
+		t.Errorf("bad decision: %+v", d)
+	}
+
+	// a consumer may still be called rules in the old format
+	if _, err = ParseACL([]byte(`{"rules": ["/main.Biz/Check"]}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPolicyErrors(t *testing.T) {
+	for _, bad := range []string{
+		`{"rules": [{"effect": "maybe", "consumers": ["a"], "methods": ["/main.Biz/*"]}]}`,
+		`{"rules": [{"effect": "allow", "methods": ["/main.Biz/*"]}]}`,
+		`{"rules": [{"effect": "allow", "consumers": ["@nobody"], "methods": ["/main.Biz/*"]}]}`,
+		`{"rules": [{"effect": "allow", "consumers": ["a"], "methods": ["/main.Biz/["]}]}`,
+		`{"rules": [{"effect": "allow", "consumers": ["a"], "methods": ["/main.Biz/*"], "from": ["10.0.0.0/33"]}]}`,
+		`{"rules": [{"effect": "allow", "consumers": ["a"], "methods": ["/main.Biz/*"], "from": ["localhost"]}]}`,
+		`{"rules": [{"effect": "allow", "consumers": ["a"], "methods": ["/main.Biz/*"], "when": "always"}]}`,
+		`{"groups": {"g": ["@h"]}, "rules": []}`,
+		`{"groups": {"g": []}, "rules": []}`,
+	} {
+		if _, err := ParseACL([]byte(bad)); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
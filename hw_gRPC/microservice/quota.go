@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type bucketKey struct {
+	quota    int
+	consumer string
+	method   string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps the token buckets of the ACL quotas. Buckets start full and
+// outlive a new ACL version as long as their quota is left as it was.
+// Consumers the ACL does not name share one bucket per quota, so made-up
+// consumer names cannot add buckets.
+type Limiter struct {
+	mu      sync.Mutex
+	version uint64
+	quotas  []Quota
+	buckets map[bucketKey]*bucket
+	Now     func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[bucketKey]*bucket),
+		Now:     time.Now,
+	}
+}
+
+// snapshot returns the policy in force. When it is newer than the one the
+// buckets were kept for, the buckets of quotas it still has unchanged move
+// to their new index and the rest are forgotten. l.mu must be held.
+func (l *Limiter) snapshot(acl *ACL) (*Policy, uint64) {
+	acl.Mu.RLock()
+	policy, version := acl.Policy, acl.Version
+	acl.Mu.RUnlock()
+	if version == l.version {
+		return policy, version
+	}
+
+	moved := make(map[int]int)
+	taken := make(map[int]bool)
+	for i, old := range l.quotas {
+		for j, q := range policy.Quotas {
+			if !taken[j] && reflect.DeepEqual(old, q) {
+				moved[i] = j
+				taken[j] = true
+				break
+			}
+		}
+	}
+	buckets := make(map[bucketKey]*bucket)
+	for key, b := range l.buckets {
+		j, ok := moved[key.quota]
+		if !ok {
+			continue
+		}
+		// a group edit can leave the quota as it was but drop the consumer,
+		// or stop naming it so it belongs in the shared bucket
+		named := policy.names(key.consumer) && policy.matchConsumer(policy.Quotas[j].Consumers, key.consumer)
+		if key.consumer != "" && !named {
+			continue
+		}
+		key.quota = j
+		buckets[key] = b
+	}
+	l.version, l.quotas, l.buckets = version, policy.Quotas, buckets
+	return policy, version
+}
+
+func refill(b *bucket, q Quota, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(q.Burst, b.tokens+elapsed*q.Rate)
+	}
+	b.last = now
+}
+
+// Take spends a token from every bucket the call falls in. When one of them
+// is empty nothing is spent and the wait until all have a token is returned.
+func (l *Limiter) Take(acl *ACL, consumer, method string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	policy, _ := l.snapshot(acl)
+	now := l.Now()
+
+	owner := consumer
+	if !policy.names(consumer) {
+		owner = ""
+	}
+	var taken []*bucket
+	wait := 0.0
+	for i, q := range policy.Quotas {
+		if !policy.matchConsumer(q.Consumers, consumer) || !matchMethod(q.Methods, method) {
+			continue
+		}
+		key := bucketKey{quota: i, consumer: owner}
+		if q.PerMethod {
+			key.method = method
+		}
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{tokens: q.Burst, last: now}
+			l.buckets[key] = b
+		}
+		refill(b, q, now)
+		if b.tokens < 1 {
+			wait = math.Max(wait, (1-b.tokens)/q.Rate)
+		}
+		taken = append(taken, b)
+	}
+	if wait > 0 {
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	for _, b := range taken {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// Usage lists the buckets of the policy in force with their tokens as of now.
+func (l *Limiter) Usage(acl *ACL) *QuotaReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	policy, version := l.snapshot(acl)
+	now := l.Now()
+
+	report := &QuotaReport{AclVersion: version}
+	for key, b := range l.buckets {
+		q := policy.Quotas[key.quota]
+		refill(b, q, now)
+		consumer := key.consumer
+		if consumer == "" {
+			consumer = unknownConsumer
+		}
+		report.Buckets = append(report.Buckets, &QuotaBucket{
+			Quota:    uint32(key.quota),
+			Consumer: consumer,
+			Method:   key.method,
+			Tokens:   b.tokens,
+			Burst:    q.Burst,
+			Rate:     q.Rate,
+		})
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool {
+		a, b := report.Buckets[i], report.Buckets[j]
+		if a.Quota != b.Quota {
+			return a.Quota < b.Quota
+		}
+		if a.Consumer != b.Consumer {
+			return a.Consumer < b.Consumer
+		}
+		return a.Method < b.Method
+	})
+	return report
+}
+
+// quotaExceeded carries the wait as RetryInfo in the status details and,
+// for clients that do not read those, as retry-after seconds in a trailer.
+func quotaExceeded(wait time.Duration) (metadata.MD, error) {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("quota exceeded, retry in %s", wait.Round(time.Millisecond)))
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+	seconds := int64(math.Ceil(wait.Seconds()))
+	return metadata.Pairs("retry-after", strconv.FormatInt(seconds, 10)), st.Err()
+}
+
+func QuotaUnaryInterceptor(acl *ACL, limiter *Limiter, audit Auditor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		consumer, _ := ConsumerFromContext(ctx)
+		if ok, wait := limiter.Take(acl, consumer, info.FullMethod); !ok {
+			trailer, err := quotaExceeded(wait)
+			audit.Rejected(ctx, AccessDecision_QUOTA_EXCEEDED, status.Convert(err).Message())
+			grpc.SetTrailer(ctx, trailer)
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func QuotaStreamInterceptor(acl *ACL, limiter *Limiter, audit Auditor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		consumer, _ := ConsumerFromContext(stream.Context())
+		if ok, wait := limiter.Take(acl, consumer, info.FullMethod); !ok {
+			trailer, err := quotaExceeded(wait)
+			audit.Rejected(stream.Context(), AccessDecision_QUOTA_EXCEEDED, status.Convert(err).Message())
+			stream.SetTrailer(trailer)
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func (a *AdminServerStruct) QuotaUsage(ctx context.Context, n *Nothing) (*QuotaReport, error) {
+	return a.Limiter.Usage(a.ACL), nil
+}
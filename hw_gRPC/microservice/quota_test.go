@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+const quotaACLData string = `{
+	"rules": [
+		{"effect": "allow", "consumers": ["biz_*"], "methods": ["/main.Biz/*"]},
+		{"effect": "allow", "consumers": ["ops"], "methods": ["/main.Admin/*"]}
+	],
+	"quotas": [
+		{"consumers": ["biz_*"], "methods": ["/main.Biz/*"], "rate": 1, "burst": 3},
+		{"consumers": ["biz_user"], "methods": ["/main.Biz/*"], "rate": 1, "burst": 1, "per_method": true}
+	]
+}`
+
+func TestLimiter(t *testing.T) {
+	acl, err := NewACL(quotaACLData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000, 0)
+	limiter := NewLimiter()
+	limiter.Now = func() time.Time { return now }
+	take := func(consumer, method string) (bool, time.Duration) {
+		return limiter.Take(acl, consumer, method)
+	}
+
+	// biz_user has one call per method, and three in total
+	for _, method := range []string{"/main.Biz/Check", "/main.Biz/Add"} {
+		if ok, _ := take("biz_user", method); !ok {
+			t.Fatalf("first %s refused", method)
+		}
+	}
+	if ok, wait := take("biz_user", "/main.Biz/Check"); ok || wait != time.Second {
+		t.Errorf("second Check: have %v %v, want refused for 1s", ok, wait)
+	}
+	if ok, _ := take("biz_user", "/main.Biz/Test"); !ok {
+		t.Errorf("third call refused")
+	}
+	if ok, _ := take("biz_admin", "/main.Biz/Test"); !ok {
+		t.Errorf("other consumer refused")
+	}
+	if ok, _ := take("ops", "/main.Admin/QuotaUsage"); !ok {
+		t.Errorf("call without quota refused")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, wait := take("biz_user", "/main.Biz/Add"); ok || wait != 500*time.Millisecond {
+		t.Errorf("have %v %v, want refused for 500ms", ok, wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := take("biz_user", "/main.Biz/Add"); !ok {
+		t.Errorf("refill did not happen")
+	}
+
+	report := limiter.Usage(acl)
+	if report.AclVersion != 1 || len(report.Buckets) != 5 {
+		t.Fatalf("bad report: %v", report)
+	}
+	// biz_admin only matches a wildcard, so it gets the shared bucket
+	if b := report.Buckets[1]; b.Quota != 0 || b.Consumer != "unknown" || b.Tokens != 3 {
+		t.Errorf("bad shared bucket: %v", b)
+	}
+
+	// made-up consumers neither add buckets nor get their own burst
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		if ok, _ := take(fmt.Sprintf("biz_%d", i), "/main.Biz/Check"); ok {
+			allowed++
+		}
+	}
+	if report = limiter.Usage(acl); allowed != 3 || len(report.Buckets) != 5 {
+		t.Errorf("have %d calls allowed and %d buckets, want 3 and 5", allowed, len(report.Buckets))
+	}
+
+	// a new policy keeps the buckets of the quotas it leaves alone
+	policy, _ := ParseACL([]byte(quotaACLData))
+	policy.Quotas[0].Burst = 10
+	acl.Swap(policy)
+	report = limiter.Usage(acl)
+	if report.AclVersion != 2 || len(report.Buckets) != 3 {
+		t.Fatalf("bad report after a quota change: %v", report)
+	}
+	for _, b := range report.Buckets {
+		if b.Quota != 1 || b.Consumer != "biz_user" {
+			t.Errorf("bucket of the changed quota survived: %v", b)
+		}
+	}
+	if ok, wait := take("biz_user", "/main.Biz/Add"); ok || wait != time.Second {
+		t.Errorf("have %v %v, want the spent Add bucket kept", ok, wait)
+	}
+
+	policy, _ = ParseACL([]byte(quotaACLData))
+	policy.Quotas[0].Burst = 10
+	policy.Quotas[0], policy.Quotas[1] = policy.Quotas[1], policy.Quotas[0]
+	policy.Rules = policy.Rules[:1]
+	acl.Swap(policy)
+	report = limiter.Usage(acl)
+	if report.AclVersion != 3 || len(report.Buckets) != 4 || report.Buckets[0].Quota != 0 || report.Buckets[0].Method != "/main.Biz/Add" {
+		t.Errorf("buckets did not follow their quotas: %v", report)
+	}
+
+	for _, bad := range []string{
+		`{"rules": [], "quotas": [{"consumers": ["a"], "methods": ["/main.Biz/*"], "rate": 0, "burst": 1}]}`,
+		`{"rules": [], "quotas": [{"consumers": ["a"], "methods": ["/main.Biz/*"], "rate": 1, "burst": 0.5}]}`,
+		`{"rules": [], "quotas": [{"consumers": ["@none"], "methods": ["/main.Biz/*"], "rate": 1, "burst": 1}]}`,
+		`{"rules": [], "quotas": [{"consumers": ["a"], "methods": ["Check"], "rate": 1, "burst": 1}]}`,
+	} {
+		if _, err := ParseACL([]byte(bad)); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestQuotaInterceptor(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, quotaACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	logStream, err := adm.Logging(getConsumerCtx("ops"), &LogFilter{Method: "/main.Biz/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statStream, err := adm.Statistics(getConsumerCtx("ops"), &StatInterval{IntervalSeconds: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+
+	if _, err = biz.Check(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatal(err)
+	}
+	trailer := metadata.MD{}
+	_, err = biz.Check(getConsumerCtx("biz_user"), &Nothing{}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if got := trailer.Get("retry-after"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("bad retry-after trailer: %v", got)
+	}
+	details := status.Convert(err).Details()
+	if len(details) != 1 {
+		t.Fatalf("no retry info: %v", details)
+	}
+	if info, ok := details[0].(*errdetails.RetryInfo); !ok || info.RetryDelay.AsDuration() <= 0 || info.RetryDelay.AsDuration() > time.Second {
+		t.Errorf("bad retry info: %v", details[0])
+	}
+
+	// the refused call is audited like the ones the ACL turns away
+	for _, want := range []AccessDecision{AccessDecision_ALLOWED, AccessDecision_QUOTA_EXCEEDED} {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		if evt.Consumer != "biz_user" || evt.Method != "/main.Biz/Check" || evt.Decision != want {
+			t.Errorf("have %+v, want %s", evt, want)
+		}
+		if want == AccessDecision_QUOTA_EXCEEDED && !strings.HasPrefix(evt.Detail, "quota exceeded") {
+			t.Errorf("bad detail: %q", evt.Detail)
+		}
+	}
+	stat, err := statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v, awaiting stat", err)
+	}
+	if stat.QuotaExceededByMethod["/main.Biz/Check"] != 1 || stat.QuotaExceededByConsumer["biz_user"] != 1 || len(stat.DeniedByMethod) != 0 {
+		t.Errorf("bad quota counters: %v %v %v", stat.QuotaExceededByMethod, stat.QuotaExceededByConsumer, stat.DeniedByMethod)
+	}
+	if stat.ByMethod["/main.Biz/Check"] != 1 {
+		t.Errorf("refused call counted as served: %v", stat.ByMethod)
+	}
+
+	report, err := adm.QuotaUsage(getConsumerCtx("ops"), &Nothing{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Buckets) != 2 || report.Buckets[0].Consumer != "biz_user" || report.Buckets[1].Method != "/main.Biz/Check" {
+		t.Errorf("bad report: %v", report.Buckets)
+	}
+}
@@ -2,18 +2,19 @@ package main
 
 import (
 	context "context"
-	"encoding/json"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
-	"strings"
-	"sync"
+	"net/http"
+	"os"
 	"time"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	grpc "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
@@ -22,56 +23,12 @@ import (
 // обращаю ваше внимание - в этом задании запрещены глобальные переменные
 // если хочется, то для красоты можно разнести логику по разным файликам
 
-type ACL struct {
-	Rights map[string][]string
-	Mu     *sync.RWMutex
-}
-
-func NewACL(acl string) (*ACL, error) {
-	var mp map[string][]string
-	err := json.Unmarshal([]byte(acl), &mp)
-
-	for consumer, rights := range mp {
-		for i := range rights {
-			rights[i] = strings.TrimSuffix(rights[i], "/*")
-		}
-		mp[consumer] = rights
-	}
-
-	if err != nil {
-		return nil, err
-	}
-	return &ACL{Rights: mp, Mu: &sync.RWMutex{}}, nil
-}
-
-func (a *ACL) CheckPermission(consumer, method string) bool {
-	var rights []string
-	var ok bool
-	a.Mu.RLock()
-	defer a.Mu.RUnlock()
-	if rights, ok = a.Rights[consumer]; !ok {
-		return false
-	}
-	for _, right := range rights {
-		if strings.HasPrefix(method, right) {
-			return true
-		}
-	}
-	return false
-}
-
+// EventFromContext needs the consumer set by the authentication
+// interceptors.
 func EventFromContext(ctx context.Context) (*Event, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
+	consumer, ok := ConsumerFromContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("couldn't metadat from context")
-	}
-
-	var val []string
-	if val, ok = md["consumer"]; !ok {
-		return nil, fmt.Errorf("couldn't get consumer from context")
-	}
-	if (len(val)) == 0 {
-		return nil, fmt.Errorf("empty consumer")
+		return nil, fmt.Errorf("no authenticated consumer")
 	}
 
 	methodName, ok := grpc.Method(ctx)
@@ -86,7 +43,7 @@ func EventFromContext(ctx context.Context) (*Event, error) {
 
 	event := &Event{
 		Method:    methodName,
-		Consumer:  val[0],
+		Consumer:  consumer,
 		Host:      clientPeer.Addr.String(),
 		Timestamp: time.Now().Unix(),
 	}
@@ -94,41 +51,47 @@ func EventFromContext(ctx context.Context) (*Event, error) {
 	return event, nil
 }
 
-func ACLInterception(ctx context.Context, acl *ACL) (bool, error) {
+// Auditor hears about the calls the interceptors turn away. Their context
+// has no consumer when authentication failed.
+type Auditor interface {
+	Rejected(ctx context.Context, decision AccessDecision, reason string)
+}
+
+func ACLInterception(ctx context.Context, acl *ACL) (Decision, error) {
 	event, err := EventFromContext(ctx)
 	if err != nil {
-		return false, err
+		return Decision{}, err
 	}
 
-	res := acl.CheckPermission(event.Consumer, event.Method)
-
-	return res, nil
+	return acl.ExplainFrom(event.Consumer, event.Method, event.Host), nil
 }
 
-func ACLUnaryInterceptor(acl *ACL) grpc.UnaryServerInterceptor {
+func ACLUnaryInterceptor(acl *ACL, audit Auditor) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 
 		res, err := ACLInterception(ctx, acl)
 		if err != nil {
 			return nil, status.Error(codes.Unauthenticated, err.Error())
 		}
-		if !res {
-			return nil, status.Error(codes.Unauthenticated, "no rights")
+		if !res.Allowed {
+			audit.Rejected(ctx, AccessDecision_DENIED, res.Reason)
+			return nil, status.Error(codes.PermissionDenied, "no rights")
 		}
 		resp, err := handler(ctx, req)
 		return resp, err
 	}
 }
 
-func ACLStreamInterceptor(acl *ACL) grpc.StreamServerInterceptor {
+func ACLStreamInterceptor(acl *ACL, audit Auditor) grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 
 		res, err := ACLInterception(stream.Context(), acl)
 		if err != nil {
-			return err
+			return status.Error(codes.Unauthenticated, err.Error())
 		}
-		if !res {
-			return status.Error(codes.Unauthenticated, "no rights")
+		if !res.Allowed {
+			audit.Rejected(stream.Context(), AccessDecision_DENIED, res.Reason)
+			return status.Error(codes.PermissionDenied, "no rights")
 		}
 		err = handler(srv, stream)
 		return err
@@ -142,8 +105,12 @@ func AdminUnaryInterceptor(a *AdminServerStruct) grpc.UnaryServerInterceptor {
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
 		}
+		start := time.Now()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("rpc.consumer", res.Consumer))
 		a.notifyObservers(res)
+		a.Stats.CallStarted(res.Consumer, res.Method)
 		resp, err := handler(ctx, req)
+		a.Stats.CallFinished(res.Method, status.Code(err), time.Since(start))
 		return resp, err
 	}
 }
@@ -154,32 +121,148 @@ func AdminStreamInterceptor(a *AdminServerStruct) grpc.StreamServerInterceptor {
 		if err != nil {
 			return status.Error(codes.Internal, err.Error())
 		}
+		start := time.Now()
+		trace.SpanFromContext(stream.Context()).SetAttributes(attribute.String("rpc.consumer", res.Consumer))
 		a.notifyObservers(res)
-
+		a.Stats.CallStarted(res.Consumer, res.Method)
 		err = handler(srv, stream)
+		a.Stats.CallFinished(res.Method, status.Code(err), time.Since(start))
 		return err
 	}
 }
 
-func StartMyMicroservice(ctx context.Context, addr string, acl string) error {
-	lis, err := net.Listen("tcp", addr)
+// Config describes a server. The ACL comes from ACLFile when it is set,
+// the file is then watched and can be reloaded with Admin.ReloadACL.
+// Auth is tried in order on every call and is required; TLS is needed for
+// CertAuth. Every Logging stream buffers up to EventBuffer
+// events and DropPolicy decides what happens when it is full. The last
+// HistorySize events are kept for replay, in HistoryFile as well when set.
+// MetricsAddr and TracerProvider turn on the exporters.
+type Config struct {
+	Addr            string
+	ACL             string
+	ACLFile         string
+	ACLPollInterval time.Duration
+	Auth            []Authenticator
+	TLS             *tls.Config
+	EventBuffer     int
+	DropPolicy      DropPolicy
+	HistorySize     int
+	HistoryFile     string
+	MetricsAddr     string
+	TracerProvider  trace.TracerProvider
+}
+
+// Option changes the Config StartMyMicroservice starts with.
+type Option func(*Config)
+
+// WithMetrics serves Prometheus metrics at http://addr/metrics.
+func WithMetrics(addr string) Option {
+	return func(cfg *Config) {
+		cfg.MetricsAddr = addr
+	}
+}
+
+// WithTracing traces every call with spans from provider.
+func WithTracing(provider trace.TracerProvider) Option {
+	return func(cfg *Config) {
+		cfg.TracerProvider = provider
+	}
+}
+
+// StartMyMicroservice trusts the consumer metadata, see MetadataAuth.
+func StartMyMicroservice(ctx context.Context, addr string, acl string, opts ...Option) error {
+	cfg := Config{Addr: addr, ACL: acl, Auth: []Authenticator{MetadataAuth{}}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Start(ctx, cfg)
+}
+
+func Start(ctx context.Context, cfg Config) error {
+	if len(cfg.Auth) == 0 {
+		return fmt.Errorf("no authenticators configured")
+	}
+	acl := cfg.ACL
+	if cfg.ACLFile != "" {
+		data, err := os.ReadFile(cfg.ACLFile)
+		if err != nil {
+			return err
+		}
+		acl = string(data)
+	}
+	aclManager, err := NewACL(acl)
 	if err != nil {
 		return err
 	}
 
-	aclManager, err := NewACL(acl)
+	history, err := NewHistory(cfg.HistorySize, cfg.HistoryFile)
 	if err != nil {
-		lis.Close()
+		return err
+	}
+	events, err := NewFanout(cfg.EventBuffer, cfg.DropPolicy, history)
+	if err != nil {
+		history.Close()
 		return err
 	}
 
-	admin := NewAdminServerStruct()
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		events.Close()
+		return err
+	}
 
-	server := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(ACLUnaryInterceptor(aclManager), AdminUnaryInterceptor(admin)),
-		grpc.ChainStreamInterceptor(ACLStreamInterceptor(aclManager), AdminStreamInterceptor(admin)),
+	admin := NewAdminServerStruct(aclManager, cfg.ACLFile, events)
+
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsLis, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			lis.Close()
+			events.Close()
+			return err
+		}
+		admin.Metrics = NewServerMetrics(aclManager, events)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", admin.Metrics.Handler())
+		metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go metricsServer.Serve(metricsLis)
+		unary = append(unary, admin.Metrics.UnaryInterceptor())
+		stream = append(stream, admin.Metrics.StreamInterceptor())
+	}
+	if cfg.TracerProvider != nil {
+		tracing := NewTracing(cfg.TracerProvider)
+		unary = append(unary, tracing.UnaryInterceptor())
+		stream = append(stream, tracing.StreamInterceptor())
+	}
+	unary = append(unary,
+		AuthUnaryInterceptor(cfg.Auth, admin),
+		ACLUnaryInterceptor(aclManager, admin),
+		QuotaUnaryInterceptor(aclManager, admin.Limiter, admin),
+		AdminUnaryInterceptor(admin),
+	)
+	stream = append(stream,
+		AuthStreamInterceptor(cfg.Auth, admin),
+		ACLStreamInterceptor(aclManager, admin),
+		QuotaStreamInterceptor(aclManager, admin.Limiter, admin),
+		AdminStreamInterceptor(admin),
 	)
 
+	if cfg.ACLFile != "" {
+		go admin.watchACL(ctx, cfg.ACLPollInterval)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+	server := grpc.NewServer(opts...)
+
 	RegisterAdminServer(server, admin)
 	RegisterBizServer(server, NewBizLogic())
 
@@ -188,11 +271,15 @@ func StartMyMicroservice(ctx context.Context, addr string, acl string) error {
 		fmt.Println("Stopping")
 		server.GracefulStop()
 		lis.Close()
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		events.Close()
 		fmt.Println("Stopped")
 	}()
 
 	go func() {
-		fmt.Println("starting server at", addr)
+		fmt.Println("starting server at", cfg.Addr)
 		if err := server.Serve(lis); err != nil {
 			log.Fatalln("failed to serve:", err)
 		}
@@ -203,108 +290,119 @@ func StartMyMicroservice(ctx context.Context, addr string, acl string) error {
 
 type AdminServerStruct struct {
 	UnimplementedAdminServer
-	Observers map[string]chan interface{}
-	Mu        *sync.Mutex
+	Events  *Fanout
+	Stats   *StatsHub
+	Metrics *ServerMetrics
+	Limiter *Limiter
+	ACL     *ACL
+	ACLFile string
 }
 
-func NewAdminServerStruct() *AdminServerStruct {
+func NewAdminServerStruct(acl *ACL, aclFile string, events *Fanout) *AdminServerStruct {
 	return &AdminServerStruct{
-		Observers: make(map[string]chan interface{}, 0),
-		Mu:        &sync.Mutex{},
+		Events:  events,
+		Stats:   NewStatsHub(),
+		Limiter: NewLimiter(),
+		ACL:     acl,
+		ACLFile: aclFile,
 	}
 }
 
-func (a *AdminServerStruct) addNewObserver(ch chan interface{}) string {
-	a.Mu.Lock()
-	defer a.Mu.Unlock()
-	stamp := uuid.New().String()
-	a.Observers[stamp] = ch
-	return stamp
+func (a *AdminServerStruct) notifyObservers(event *Event) {
+	if a.Metrics != nil {
+		a.Metrics.countEvent(event)
+	}
+	a.Events.Publish(event)
 }
 
-func (a *AdminServerStruct) deleteObserver(key string) {
-	a.Mu.Lock()
-	defer a.Mu.Unlock()
-	delete(a.Observers, key)
+// Rejected logs the call with the decision and counts it in Statistics.
+func (a *AdminServerStruct) Rejected(ctx context.Context, decision AccessDecision, reason string) {
+	event := &Event{
+		Kind:      EventKind_CALL,
+		Decision:  decision,
+		Detail:    reason,
+		Timestamp: time.Now().Unix(),
+	}
+	event.Consumer, _ = ConsumerFromContext(ctx)
+	event.Method, _ = grpc.Method(ctx)
+	if clientPeer, ok := peer.FromContext(ctx); ok {
+		event.Host = clientPeer.Addr.String()
+	}
+	a.notifyObservers(event)
+	a.Stats.CallRejected(event.Consumer, event.Method, decision)
 }
 
-func (a *AdminServerStruct) notifyObservers(event *Event) {
-	a.Mu.Lock()
-	defer a.Mu.Unlock()
-	for _, v := range a.Observers {
-		localEvent := Event{
-			Consumer:  event.Consumer,
-			Method:    event.Method,
-			Host:      event.Host,
-			Timestamp: event.Timestamp,
-		}
-		go func(c chan interface{}) {
-			c <- localEvent
-		}(v)
-	}
+// errFellBehind ends a stream cut off under the Disconnect policy.
+func errFellBehind() error {
+	return status.Error(codes.ResourceExhausted, "subscriber fell behind, events were dropped")
 }
 
-func (a *AdminServerStruct) Logging(n *Nothing, log Admin_LoggingServer) error {
-	logChan := make(chan interface{})
-	stamp := a.addNewObserver(logChan)
-	defer a.deleteObserver(stamp)
+func (a *AdminServerStruct) Logging(filter *LogFilter, log Admin_LoggingServer) error {
+	match, err := compileFilter(filter)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	sub := a.Events.Subscribe(match, filter.GetSince() > 0 || filter.GetAfterSeq() > 0)
+	defer a.Events.Unsubscribe(sub)
 
 	ctx := log.Context()
 	for {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("aborted")
-		case val := <-logChan:
-			event := Event{
-				Consumer:  val.(Event).Consumer,
-				Host:      val.(Event).Host,
-				Method:    val.(Event).Method,
-				Timestamp: val.(Event).Timestamp,
-			}
-			err := log.Send(&event)
-			if err != nil {
-				return fmt.Errorf("error in logging: %s", err.Error())
+		case <-sub.gone:
+			return errFellBehind()
+		case <-sub.ready:
+			for event, ok := sub.pop(); ok; event, ok = sub.pop() {
+				err := log.Send(event)
+				if err != nil {
+					return fmt.Errorf("error in logging: %s", err.Error())
+				}
 			}
 		}
 	}
 }
 
 func (a *AdminServerStruct) Statistics(s *StatInterval, stat Admin_StatisticsServer) error {
-	statChan := make(chan interface{})
-	stamp := a.addNewObserver(statChan)
-	defer a.deleteObserver(stamp)
+	window := a.Stats.Subscribe()
+	defer a.Stats.Unsubscribe(window)
 
-	stats := Stat{
-		ByMethod:   make(map[string]uint64),
-		ByConsumer: make(map[string]uint64),
-		Timestamp:  time.Now().Unix(),
-	}
 	ticker := time.NewTicker(time.Duration(s.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
 	ctx := stat.Context()
+	dropped, disconnected := a.Events.Dropped(), a.Events.Disconnected()
 	for {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("aborted")
 		case <-ticker.C:
-			err := stat.Send(&stats)
+			next := a.Stats.Flush(window)
+			next.EventsDropped = a.Events.Dropped() - dropped
+			next.SubscribersDisconnected = a.Events.Disconnected() - disconnected
+			dropped, disconnected = dropped+next.EventsDropped, disconnected+next.SubscribersDisconnected
+			err := stat.Send(next)
 			if err != nil {
 				return fmt.Errorf("error in statistics: %s", err.Error())
 			}
-			stats.ByConsumer = make(map[string]uint64)
-			stats.ByMethod = make(map[string]uint64)
-		case val := <-statChan:
-			event := Event{
-				Consumer:  val.(Event).Consumer,
-				Host:      val.(Event).Host,
-				Method:    val.(Event).Method,
-				Timestamp: val.(Event).Timestamp,
-			}
-			stats.ByConsumer[event.Consumer] += 1
-			stats.ByMethod[event.Method] += 1
 		}
 	}
 }
 
+func (a *AdminServerStruct) ReloadACL(ctx context.Context, n *Nothing) (*ACLStatus, error) {
+	if a.ACLFile == "" {
+		return nil, status.Error(codes.FailedPrecondition, "acl is not loaded from a file")
+	}
+	origin, err := EventFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	res, err := a.reloadACL(origin)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return res, nil
+}
+
 type BizLogic struct {
 	UnimplementedBizServer
 }
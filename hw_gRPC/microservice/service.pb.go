@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.26.0
-// 	protoc        v4.25.1
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
 // source: service.proto
 
 package main
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -20,24 +21,123 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type EventKind int32
+
+const (
+	EventKind_CALL       EventKind = 0
+	EventKind_ACL_CHANGE EventKind = 1
+)
+
+// Enum value maps for EventKind.
+var (
+	EventKind_name = map[int32]string{
+		0: "CALL",
+		1: "ACL_CHANGE",
+	}
+	EventKind_value = map[string]int32{
+		"CALL":       0,
+		"ACL_CHANGE": 1,
+	}
+)
+
+func (x EventKind) Enum() *EventKind {
+	p := new(EventKind)
+	*p = x
+	return p
+}
+
+func (x EventKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_service_proto_enumTypes[0].Descriptor()
+}
+
+func (EventKind) Type() protoreflect.EnumType {
+	return &file_service_proto_enumTypes[0]
+}
+
+func (x EventKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventKind.Descriptor instead.
+func (EventKind) EnumDescriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{0}
+}
+
+type AccessDecision int32
+
+const (
+	AccessDecision_ALLOWED         AccessDecision = 0
+	AccessDecision_DENIED          AccessDecision = 1
+	AccessDecision_UNAUTHENTICATED AccessDecision = 2
+	AccessDecision_QUOTA_EXCEEDED  AccessDecision = 3
+)
+
+// Enum value maps for AccessDecision.
+var (
+	AccessDecision_name = map[int32]string{
+		0: "ALLOWED",
+		1: "DENIED",
+		2: "UNAUTHENTICATED",
+		3: "QUOTA_EXCEEDED",
+	}
+	AccessDecision_value = map[string]int32{
+		"ALLOWED":         0,
+		"DENIED":          1,
+		"UNAUTHENTICATED": 2,
+		"QUOTA_EXCEEDED":  3,
+	}
+)
+
+func (x AccessDecision) Enum() *AccessDecision {
+	p := new(AccessDecision)
+	*p = x
+	return p
+}
+
+func (x AccessDecision) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AccessDecision) Descriptor() protoreflect.EnumDescriptor {
+	return file_service_proto_enumTypes[1].Descriptor()
+}
+
+func (AccessDecision) Type() protoreflect.EnumType {
+	return &file_service_proto_enumTypes[1]
+}
+
+func (x AccessDecision) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AccessDecision.Descriptor instead.
+func (AccessDecision) EnumDescriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{1}
+}
+
 type Event struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Consumer      string                 `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	Method        string                 `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	Host          string                 `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	Kind          EventKind              `protobuf:"varint,5,opt,name=kind,proto3,enum=main.EventKind" json:"kind,omitempty"`
+	Detail        string                 `protobuf:"bytes,6,opt,name=detail,proto3" json:"detail,omitempty"`
+	Seq           uint64                 `protobuf:"varint,7,opt,name=seq,proto3" json:"seq,omitempty"`
+	Decision      AccessDecision         `protobuf:"varint,8,opt,name=decision,proto3,enum=main.AccessDecision" json:"decision,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Timestamp int64  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Consumer  string `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
-	Method    string `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
-	Host      string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Event) Reset() {
 	*x = Event{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Event) String() string {
@@ -48,7 +148,7 @@ func (*Event) ProtoMessage() {}
 
 func (x *Event) ProtoReflect() protoreflect.Message {
 	mi := &file_service_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -91,23 +191,140 @@ func (x *Event) GetHost() string {
 	return ""
 }
 
-type Stat struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+func (x *Event) GetKind() EventKind {
+	if x != nil {
+		return x.Kind
+	}
+	return EventKind_CALL
+}
+
+func (x *Event) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *Event) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Event) GetDecision() AccessDecision {
+	if x != nil {
+		return x.Decision
+	}
+	return AccessDecision_ALLOWED
+}
+
+type MethodStat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Completed     uint64                 `protobuf:"varint,1,opt,name=completed,proto3" json:"completed,omitempty"`
+	Errors        uint64                 `protobuf:"varint,2,opt,name=errors,proto3" json:"errors,omitempty"`
+	ByCode        map[string]uint64      `protobuf:"bytes,3,rep,name=by_code,json=byCode,proto3" json:"by_code,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	P50Ms         float64                `protobuf:"fixed64,4,opt,name=p50_ms,json=p50Ms,proto3" json:"p50_ms,omitempty"`
+	P95Ms         float64                `protobuf:"fixed64,5,opt,name=p95_ms,json=p95Ms,proto3" json:"p95_ms,omitempty"`
+	P99Ms         float64                `protobuf:"fixed64,6,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-	Timestamp  int64             `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	ByMethod   map[string]uint64 `protobuf:"bytes,2,rep,name=by_method,json=byMethod,proto3" json:"by_method,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	ByConsumer map[string]uint64 `protobuf:"bytes,3,rep,name=by_consumer,json=byConsumer,proto3" json:"by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+func (x *MethodStat) Reset() {
+	*x = MethodStat{}
+	mi := &file_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *Stat) Reset() {
-	*x = Stat{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[1]
+func (x *MethodStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MethodStat) ProtoMessage() {}
+
+func (x *MethodStat) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[1]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MethodStat.ProtoReflect.Descriptor instead.
+func (*MethodStat) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MethodStat) GetCompleted() uint64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *MethodStat) GetErrors() uint64 {
+	if x != nil {
+		return x.Errors
+	}
+	return 0
+}
+
+func (x *MethodStat) GetByCode() map[string]uint64 {
+	if x != nil {
+		return x.ByCode
+	}
+	return nil
+}
+
+func (x *MethodStat) GetP50Ms() float64 {
+	if x != nil {
+		return x.P50Ms
+	}
+	return 0
+}
+
+func (x *MethodStat) GetP95Ms() float64 {
+	if x != nil {
+		return x.P95Ms
+	}
+	return 0
+}
+
+func (x *MethodStat) GetP99Ms() float64 {
+	if x != nil {
+		return x.P99Ms
+	}
+	return 0
+}
+
+type Stat struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp               int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ByMethod                map[string]uint64      `protobuf:"bytes,2,rep,name=by_method,json=byMethod,proto3" json:"by_method,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	ByConsumer              map[string]uint64      `protobuf:"bytes,3,rep,name=by_consumer,json=byConsumer,proto3" json:"by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Methods                 map[string]*MethodStat `protobuf:"bytes,4,rep,name=methods,proto3" json:"methods,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DeniedByMethod          map[string]uint64      `protobuf:"bytes,5,rep,name=denied_by_method,json=deniedByMethod,proto3" json:"denied_by_method,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	DeniedByConsumer        map[string]uint64      `protobuf:"bytes,6,rep,name=denied_by_consumer,json=deniedByConsumer,proto3" json:"denied_by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	UnauthenticatedByMethod map[string]uint64      `protobuf:"bytes,7,rep,name=unauthenticated_by_method,json=unauthenticatedByMethod,proto3" json:"unauthenticated_by_method,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	EventsDropped           uint64                 `protobuf:"varint,8,opt,name=events_dropped,json=eventsDropped,proto3" json:"events_dropped,omitempty"`
+	SubscribersDisconnected uint64                 `protobuf:"varint,9,opt,name=subscribers_disconnected,json=subscribersDisconnected,proto3" json:"subscribers_disconnected,omitempty"`
+	QuotaExceededByMethod   map[string]uint64      `protobuf:"bytes,10,rep,name=quota_exceeded_by_method,json=quotaExceededByMethod,proto3" json:"quota_exceeded_by_method,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	QuotaExceededByConsumer map[string]uint64      `protobuf:"bytes,11,rep,name=quota_exceeded_by_consumer,json=quotaExceededByConsumer,proto3" json:"quota_exceeded_by_consumer,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *Stat) Reset() {
+	*x = Stat{}
+	mi := &file_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Stat) String() string {
@@ -117,8 +334,8 @@ func (x *Stat) String() string {
 func (*Stat) ProtoMessage() {}
 
 func (x *Stat) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_service_proto_msgTypes[2]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -130,7 +347,7 @@ func (x *Stat) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Stat.ProtoReflect.Descriptor instead.
 func (*Stat) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{1}
+	return file_service_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Stat) GetTimestamp() int64 {
@@ -154,21 +371,74 @@ func (x *Stat) GetByConsumer() map[string]uint64 {
 	return nil
 }
 
-type StatInterval struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *Stat) GetMethods() map[string]*MethodStat {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *Stat) GetDeniedByMethod() map[string]uint64 {
+	if x != nil {
+		return x.DeniedByMethod
+	}
+	return nil
+}
 
-	IntervalSeconds uint64 `protobuf:"varint,1,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"`
+func (x *Stat) GetDeniedByConsumer() map[string]uint64 {
+	if x != nil {
+		return x.DeniedByConsumer
+	}
+	return nil
+}
+
+func (x *Stat) GetUnauthenticatedByMethod() map[string]uint64 {
+	if x != nil {
+		return x.UnauthenticatedByMethod
+	}
+	return nil
+}
+
+func (x *Stat) GetEventsDropped() uint64 {
+	if x != nil {
+		return x.EventsDropped
+	}
+	return 0
+}
+
+func (x *Stat) GetSubscribersDisconnected() uint64 {
+	if x != nil {
+		return x.SubscribersDisconnected
+	}
+	return 0
+}
+
+func (x *Stat) GetQuotaExceededByMethod() map[string]uint64 {
+	if x != nil {
+		return x.QuotaExceededByMethod
+	}
+	return nil
+}
+
+func (x *Stat) GetQuotaExceededByConsumer() map[string]uint64 {
+	if x != nil {
+		return x.QuotaExceededByConsumer
+	}
+	return nil
+}
+
+type StatInterval struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IntervalSeconds uint64                 `protobuf:"varint,1,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *StatInterval) Reset() {
 	*x = StatInterval{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[2]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *StatInterval) String() string {
@@ -178,8 +448,8 @@ func (x *StatInterval) String() string {
 func (*StatInterval) ProtoMessage() {}
 
 func (x *StatInterval) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_service_proto_msgTypes[3]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -191,7 +461,7 @@ func (x *StatInterval) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatInterval.ProtoReflect.Descriptor instead.
 func (*StatInterval) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{2}
+	return file_service_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *StatInterval) GetIntervalSeconds() uint64 {
@@ -202,20 +472,17 @@ func (x *StatInterval) GetIntervalSeconds() uint64 {
 }
 
 type Nothing struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dummy         bool                   `protobuf:"varint,1,opt,name=dummy,proto3" json:"dummy,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Dummy bool `protobuf:"varint,1,opt,name=dummy,proto3" json:"dummy,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Nothing) Reset() {
 	*x = Nothing{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_service_proto_msgTypes[3]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Nothing) String() string {
@@ -225,8 +492,8 @@ func (x *Nothing) String() string {
 func (*Nothing) ProtoMessage() {}
 
 func (x *Nothing) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[3]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_service_proto_msgTypes[4]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -238,7 +505,7 @@ func (x *Nothing) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Nothing.ProtoReflect.Descriptor instead.
 func (*Nothing) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{3}
+	return file_service_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Nothing) GetDummy() bool {
@@ -248,97 +515,450 @@ func (x *Nothing) GetDummy() bool {
 	return false
 }
 
-var File_service_proto protoreflect.FileDescriptor
+type LogFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Consumers     []string               `protobuf:"bytes,1,rep,name=consumers,proto3" json:"consumers,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Host          string                 `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Since         int64                  `protobuf:"varint,4,opt,name=since,proto3" json:"since,omitempty"`
+	AfterSeq      uint64                 `protobuf:"varint,5,opt,name=after_seq,json=afterSeq,proto3" json:"after_seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogFilter) Reset() {
+	*x = LogFilter{}
+	mi := &file_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogFilter) ProtoMessage() {}
+
+func (x *LogFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogFilter.ProtoReflect.Descriptor instead.
+func (*LogFilter) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LogFilter) GetConsumers() []string {
+	if x != nil {
+		return x.Consumers
+	}
+	return nil
+}
+
+func (x *LogFilter) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *LogFilter) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *LogFilter) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *LogFilter) GetAfterSeq() uint64 {
+	if x != nil {
+		return x.AfterSeq
+	}
+	return 0
+}
+
+type ACLStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Changed       bool                   `protobuf:"varint,2,opt,name=changed,proto3" json:"changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ACLStatus) Reset() {
+	*x = ACLStatus{}
+	mi := &file_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ACLStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACLStatus) ProtoMessage() {}
+
+func (x *ACLStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACLStatus.ProtoReflect.Descriptor instead.
+func (*ACLStatus) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ACLStatus) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ACLStatus) GetChanged() bool {
+	if x != nil {
+		return x.Changed
+	}
+	return false
+}
+
+type QuotaBucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quota         uint32                 `protobuf:"varint,1,opt,name=quota,proto3" json:"quota,omitempty"`
+	Consumer      string                 `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	Method        string                 `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	Tokens        float64                `protobuf:"fixed64,4,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	Burst         float64                `protobuf:"fixed64,5,opt,name=burst,proto3" json:"burst,omitempty"`
+	Rate          float64                `protobuf:"fixed64,6,opt,name=rate,proto3" json:"rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaBucket) Reset() {
+	*x = QuotaBucket{}
+	mi := &file_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaBucket) ProtoMessage() {}
+
+func (x *QuotaBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaBucket.ProtoReflect.Descriptor instead.
+func (*QuotaBucket) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *QuotaBucket) GetQuota() uint32 {
+	if x != nil {
+		return x.Quota
+	}
+	return 0
+}
+
+func (x *QuotaBucket) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
 
-var file_service_proto_rawDesc = []byte{
-	0x0a, 0x0d, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x04, 0x6d, 0x61, 0x69, 0x6e, 0x22, 0x6d, 0x0a, 0x05, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1c,
-	0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1a, 0x0a, 0x08,
-	0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
-	0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68,
-	0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64,
-	0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
-	0x68, 0x6f, 0x73, 0x74, 0x22, 0x94, 0x02, 0x0a, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1c, 0x0a,
-	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x35, 0x0a, 0x09, 0x62,
-	0x79, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18,
-	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x2e, 0x42, 0x79, 0x4d, 0x65, 0x74,
-	0x68, 0x6f, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x62, 0x79, 0x4d, 0x65, 0x74, 0x68,
-	0x6f, 0x64, 0x12, 0x3b, 0x0a, 0x0b, 0x62, 0x79, 0x5f, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65,
-	0x72, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53,
-	0x74, 0x61, 0x74, 0x2e, 0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e,
-	0x74, 0x72, 0x79, 0x52, 0x0a, 0x62, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x1a,
-	0x3b, 0x0a, 0x0d, 0x42, 0x79, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79,
-	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
-	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3d, 0x0a, 0x0f,
-	0x42, 0x79, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
-	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
-	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
-	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x39, 0x0a, 0x0c, 0x53,
-	0x74, 0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x29, 0x0a, 0x10, 0x69,
-	0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53,
-	0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x1f, 0x0a, 0x07, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e,
-	0x67, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x75, 0x6d, 0x6d, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x05, 0x64, 0x75, 0x6d, 0x6d, 0x79, 0x32, 0x64, 0x0a, 0x05, 0x41, 0x64, 0x6d, 0x69, 0x6e,
-	0x12, 0x29, 0x0a, 0x07, 0x4c, 0x6f, 0x67, 0x67, 0x69, 0x6e, 0x67, 0x12, 0x0d, 0x2e, 0x6d, 0x61,
-	0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0b, 0x2e, 0x6d, 0x61, 0x69,
-	0x6e, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x30, 0x0a, 0x0a, 0x53,
-	0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x73, 0x12, 0x12, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
-	0x2e, 0x53, 0x74, 0x61, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x1a, 0x0a, 0x2e,
-	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x22, 0x00, 0x30, 0x01, 0x32, 0x7d, 0x0a,
-	0x03, 0x42, 0x69, 0x7a, 0x12, 0x27, 0x0a, 0x05, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x0d, 0x2e,
-	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d,
-	0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x12, 0x25, 0x0a,
-	0x03, 0x41, 0x64, 0x64, 0x12, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68,
-	0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69,
-	0x6e, 0x67, 0x22, 0x00, 0x12, 0x26, 0x0a, 0x04, 0x54, 0x65, 0x73, 0x74, 0x12, 0x0d, 0x2e, 0x6d,
-	0x61, 0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x0d, 0x2e, 0x6d, 0x61,
-	0x69, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x42, 0x09, 0x5a, 0x07,
-	0x2e, 0x2f, 0x3b, 0x6d, 0x61, 0x69, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *QuotaBucket) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *QuotaBucket) GetTokens() float64 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *QuotaBucket) GetBurst() float64 {
+	if x != nil {
+		return x.Burst
+	}
+	return 0
 }
 
+func (x *QuotaBucket) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+type QuotaReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AclVersion    uint64                 `protobuf:"varint,1,opt,name=acl_version,json=aclVersion,proto3" json:"acl_version,omitempty"`
+	Buckets       []*QuotaBucket         `protobuf:"bytes,2,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaReport) Reset() {
+	*x = QuotaReport{}
+	mi := &file_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaReport) ProtoMessage() {}
+
+func (x *QuotaReport) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaReport.ProtoReflect.Descriptor instead.
+func (*QuotaReport) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QuotaReport) GetAclVersion() uint64 {
+	if x != nil {
+		return x.AclVersion
+	}
+	return 0
+}
+
+func (x *QuotaReport) GetBuckets() []*QuotaBucket {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+var File_service_proto protoreflect.FileDescriptor
+
+const file_service_proto_rawDesc = "" +
+	"\n" +
+	"\rservice.proto\x12\x04main\"\xee\x01\n" +
+	"\x05Event\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x1a\n" +
+	"\bconsumer\x18\x02 \x01(\tR\bconsumer\x12\x16\n" +
+	"\x06method\x18\x03 \x01(\tR\x06method\x12\x12\n" +
+	"\x04host\x18\x04 \x01(\tR\x04host\x12#\n" +
+	"\x04kind\x18\x05 \x01(\x0e2\x0f.main.EventKindR\x04kind\x12\x16\n" +
+	"\x06detail\x18\x06 \x01(\tR\x06detail\x12\x10\n" +
+	"\x03seq\x18\a \x01(\x04R\x03seq\x120\n" +
+	"\bdecision\x18\b \x01(\x0e2\x14.main.AccessDecisionR\bdecision\"\xf9\x01\n" +
+	"\n" +
+	"MethodStat\x12\x1c\n" +
+	"\tcompleted\x18\x01 \x01(\x04R\tcompleted\x12\x16\n" +
+	"\x06errors\x18\x02 \x01(\x04R\x06errors\x125\n" +
+	"\aby_code\x18\x03 \x03(\v2\x1c.main.MethodStat.ByCodeEntryR\x06byCode\x12\x15\n" +
+	"\x06p50_ms\x18\x04 \x01(\x01R\x05p50Ms\x12\x15\n" +
+	"\x06p95_ms\x18\x05 \x01(\x01R\x05p95Ms\x12\x15\n" +
+	"\x06p99_ms\x18\x06 \x01(\x01R\x05p99Ms\x1a9\n" +
+	"\vByCodeEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\xa6\n" +
+	"\n" +
+	"\x04Stat\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x125\n" +
+	"\tby_method\x18\x02 \x03(\v2\x18.main.Stat.ByMethodEntryR\bbyMethod\x12;\n" +
+	"\vby_consumer\x18\x03 \x03(\v2\x1a.main.Stat.ByConsumerEntryR\n" +
+	"byConsumer\x121\n" +
+	"\amethods\x18\x04 \x03(\v2\x17.main.Stat.MethodsEntryR\amethods\x12H\n" +
+	"\x10denied_by_method\x18\x05 \x03(\v2\x1e.main.Stat.DeniedByMethodEntryR\x0edeniedByMethod\x12N\n" +
+	"\x12denied_by_consumer\x18\x06 \x03(\v2 .main.Stat.DeniedByConsumerEntryR\x10deniedByConsumer\x12c\n" +
+	"\x19unauthenticated_by_method\x18\a \x03(\v2'.main.Stat.UnauthenticatedByMethodEntryR\x17unauthenticatedByMethod\x12%\n" +
+	"\x0eevents_dropped\x18\b \x01(\x04R\reventsDropped\x129\n" +
+	"\x18subscribers_disconnected\x18\t \x01(\x04R\x17subscribersDisconnected\x12^\n" +
+	"\x18quota_exceeded_by_method\x18\n" +
+	" \x03(\v2%.main.Stat.QuotaExceededByMethodEntryR\x15quotaExceededByMethod\x12d\n" +
+	"\x1aquota_exceeded_by_consumer\x18\v \x03(\v2'.main.Stat.QuotaExceededByConsumerEntryR\x17quotaExceededByConsumer\x1a;\n" +
+	"\rByMethodEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1a=\n" +
+	"\x0fByConsumerEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1aL\n" +
+	"\fMethodsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
+	"\x05value\x18\x02 \x01(\v2\x10.main.MethodStatR\x05value:\x028\x01\x1aA\n" +
+	"\x13DeniedByMethodEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1aC\n" +
+	"\x15DeniedByConsumerEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1aJ\n" +
+	"\x1cUnauthenticatedByMethodEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1aH\n" +
+	"\x1aQuotaExceededByMethodEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\x1aJ\n" +
+	"\x1cQuotaExceededByConsumerEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"9\n" +
+	"\fStatInterval\x12)\n" +
+	"\x10interval_seconds\x18\x01 \x01(\x04R\x0fintervalSeconds\"\x1f\n" +
+	"\aNothing\x12\x14\n" +
+	"\x05dummy\x18\x01 \x01(\bR\x05dummy\"\x88\x01\n" +
+	"\tLogFilter\x12\x1c\n" +
+	"\tconsumers\x18\x01 \x03(\tR\tconsumers\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x12\n" +
+	"\x04host\x18\x03 \x01(\tR\x04host\x12\x14\n" +
+	"\x05since\x18\x04 \x01(\x03R\x05since\x12\x1b\n" +
+	"\tafter_seq\x18\x05 \x01(\x04R\bafterSeq\"?\n" +
+	"\tACLStatus\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12\x18\n" +
+	"\achanged\x18\x02 \x01(\bR\achanged\"\x99\x01\n" +
+	"\vQuotaBucket\x12\x14\n" +
+	"\x05quota\x18\x01 \x01(\rR\x05quota\x12\x1a\n" +
+	"\bconsumer\x18\x02 \x01(\tR\bconsumer\x12\x16\n" +
+	"\x06method\x18\x03 \x01(\tR\x06method\x12\x16\n" +
+	"\x06tokens\x18\x04 \x01(\x01R\x06tokens\x12\x14\n" +
+	"\x05burst\x18\x05 \x01(\x01R\x05burst\x12\x12\n" +
+	"\x04rate\x18\x06 \x01(\x01R\x04rate\"[\n" +
+	"\vQuotaReport\x12\x1f\n" +
+	"\vacl_version\x18\x01 \x01(\x04R\n" +
+	"aclVersion\x12+\n" +
+	"\abuckets\x18\x02 \x03(\v2\x11.main.QuotaBucketR\abuckets*%\n" +
+	"\tEventKind\x12\b\n" +
+	"\x04CALL\x10\x00\x12\x0e\n" +
+	"\n" +
+	"ACL_CHANGE\x10\x01*R\n" +
+	"\x0eAccessDecision\x12\v\n" +
+	"\aALLOWED\x10\x00\x12\n" +
+	"\n" +
+	"\x06DENIED\x10\x01\x12\x13\n" +
+	"\x0fUNAUTHENTICATED\x10\x02\x12\x12\n" +
+	"\x0eQUOTA_EXCEEDED\x10\x032\xc7\x01\n" +
+	"\x05Admin\x12+\n" +
+	"\aLogging\x12\x0f.main.LogFilter\x1a\v.main.Event\"\x000\x01\x120\n" +
+	"\n" +
+	"Statistics\x12\x12.main.StatInterval\x1a\n" +
+	".main.Stat\"\x000\x01\x12-\n" +
+	"\tReloadACL\x12\r.main.Nothing\x1a\x0f.main.ACLStatus\"\x00\x120\n" +
+	"\n" +
+	"QuotaUsage\x12\r.main.Nothing\x1a\x11.main.QuotaReport\"\x002}\n" +
+	"\x03Biz\x12'\n" +
+	"\x05Check\x12\r.main.Nothing\x1a\r.main.Nothing\"\x00\x12%\n" +
+	"\x03Add\x12\r.main.Nothing\x1a\r.main.Nothing\"\x00\x12&\n" +
+	"\x04Test\x12\r.main.Nothing\x1a\r.main.Nothing\"\x00B\tZ\a./;mainb\x06proto3"
+
 var (
 	file_service_proto_rawDescOnce sync.Once
-	file_service_proto_rawDescData = file_service_proto_rawDesc
+	file_service_proto_rawDescData []byte
 )
 
 func file_service_proto_rawDescGZIP() []byte {
 	file_service_proto_rawDescOnce.Do(func() {
-		file_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_service_proto_rawDescData)
+		file_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_service_proto_rawDesc), len(file_service_proto_rawDesc)))
 	})
 	return file_service_proto_rawDescData
 }
 
-var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
-var file_service_proto_goTypes = []interface{}{
-	(*Event)(nil),        // 0: main.Event
-	(*Stat)(nil),         // 1: main.Stat
-	(*StatInterval)(nil), // 2: main.StatInterval
-	(*Nothing)(nil),      // 3: main.Nothing
-	nil,                  // 4: main.Stat.ByMethodEntry
-	nil,                  // 5: main.Stat.ByConsumerEntry
+var file_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_service_proto_goTypes = []any{
+	(EventKind)(0),       // 0: main.EventKind
+	(AccessDecision)(0),  // 1: main.AccessDecision
+	(*Event)(nil),        // 2: main.Event
+	(*MethodStat)(nil),   // 3: main.MethodStat
+	(*Stat)(nil),         // 4: main.Stat
+	(*StatInterval)(nil), // 5: main.StatInterval
+	(*Nothing)(nil),      // 6: main.Nothing
+	(*LogFilter)(nil),    // 7: main.LogFilter
+	(*ACLStatus)(nil),    // 8: main.ACLStatus
+	(*QuotaBucket)(nil),  // 9: main.QuotaBucket
+	(*QuotaReport)(nil),  // 10: main.QuotaReport
+	nil,                  // 11: main.MethodStat.ByCodeEntry
+	nil,                  // 12: main.Stat.ByMethodEntry
+	nil,                  // 13: main.Stat.ByConsumerEntry
+	nil,                  // 14: main.Stat.MethodsEntry
+	nil,                  // 15: main.Stat.DeniedByMethodEntry
+	nil,                  // 16: main.Stat.DeniedByConsumerEntry
+	nil,                  // 17: main.Stat.UnauthenticatedByMethodEntry
+	nil,                  // 18: main.Stat.QuotaExceededByMethodEntry
+	nil,                  // 19: main.Stat.QuotaExceededByConsumerEntry
 }
 var file_service_proto_depIdxs = []int32{
-	4, // 0: main.Stat.by_method:type_name -> main.Stat.ByMethodEntry
-	5, // 1: main.Stat.by_consumer:type_name -> main.Stat.ByConsumerEntry
-	3, // 2: main.Admin.Logging:input_type -> main.Nothing
-	2, // 3: main.Admin.Statistics:input_type -> main.StatInterval
-	3, // 4: main.Biz.Check:input_type -> main.Nothing
-	3, // 5: main.Biz.Add:input_type -> main.Nothing
-	3, // 6: main.Biz.Test:input_type -> main.Nothing
-	0, // 7: main.Admin.Logging:output_type -> main.Event
-	1, // 8: main.Admin.Statistics:output_type -> main.Stat
-	3, // 9: main.Biz.Check:output_type -> main.Nothing
-	3, // 10: main.Biz.Add:output_type -> main.Nothing
-	3, // 11: main.Biz.Test:output_type -> main.Nothing
-	7, // [7:12] is the sub-list for method output_type
-	2, // [2:7] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0,  // 0: main.Event.kind:type_name -> main.EventKind
+	1,  // 1: main.Event.decision:type_name -> main.AccessDecision
+	11, // 2: main.MethodStat.by_code:type_name -> main.MethodStat.ByCodeEntry
+	12, // 3: main.Stat.by_method:type_name -> main.Stat.ByMethodEntry
+	13, // 4: main.Stat.by_consumer:type_name -> main.Stat.ByConsumerEntry
+	14, // 5: main.Stat.methods:type_name -> main.Stat.MethodsEntry
+	15, // 6: main.Stat.denied_by_method:type_name -> main.Stat.DeniedByMethodEntry
+	16, // 7: main.Stat.denied_by_consumer:type_name -> main.Stat.DeniedByConsumerEntry
+	17, // 8: main.Stat.unauthenticated_by_method:type_name -> main.Stat.UnauthenticatedByMethodEntry
+	18, // 9: main.Stat.quota_exceeded_by_method:type_name -> main.Stat.QuotaExceededByMethodEntry
+	19, // 10: main.Stat.quota_exceeded_by_consumer:type_name -> main.Stat.QuotaExceededByConsumerEntry
+	9,  // 11: main.QuotaReport.buckets:type_name -> main.QuotaBucket
+	3,  // 12: main.Stat.MethodsEntry.value:type_name -> main.MethodStat
+	7,  // 13: main.Admin.Logging:input_type -> main.LogFilter
+	5,  // 14: main.Admin.Statistics:input_type -> main.StatInterval
+	6,  // 15: main.Admin.ReloadACL:input_type -> main.Nothing
+	6,  // 16: main.Admin.QuotaUsage:input_type -> main.Nothing
+	6,  // 17: main.Biz.Check:input_type -> main.Nothing
+	6,  // 18: main.Biz.Add:input_type -> main.Nothing
+	6,  // 19: main.Biz.Test:input_type -> main.Nothing
+	2,  // 20: main.Admin.Logging:output_type -> main.Event
+	4,  // 21: main.Admin.Statistics:output_type -> main.Stat
+	8,  // 22: main.Admin.ReloadACL:output_type -> main.ACLStatus
+	10, // 23: main.Admin.QuotaUsage:output_type -> main.QuotaReport
+	6,  // 24: main.Biz.Check:output_type -> main.Nothing
+	6,  // 25: main.Biz.Add:output_type -> main.Nothing
+	6,  // 26: main.Biz.Test:output_type -> main.Nothing
+	20, // [20:27] is the sub-list for method output_type
+	13, // [13:20] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_service_proto_init() }
@@ -346,72 +966,22 @@ func file_service_proto_init() {
 	if File_service_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Event); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Stat); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StatInterval); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Nothing); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_service_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   6,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_service_proto_rawDesc), len(file_service_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_service_proto_goTypes,
 		DependencyIndexes: file_service_proto_depIdxs,
+		EnumInfos:         file_service_proto_enumTypes,
 		MessageInfos:      file_service_proto_msgTypes,
 	}.Build()
 	File_service_proto = out.File
-	file_service_proto_rawDesc = nil
 	file_service_proto_goTypes = nil
 	file_service_proto_depIdxs = nil
 }
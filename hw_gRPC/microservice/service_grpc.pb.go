@@ -18,8 +18,10 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AdminClient interface {
-	Logging(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (Admin_LoggingClient, error)
+	Logging(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (Admin_LoggingClient, error)
 	Statistics(ctx context.Context, in *StatInterval, opts ...grpc.CallOption) (Admin_StatisticsClient, error)
+	ReloadACL(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ACLStatus, error)
+	QuotaUsage(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*QuotaReport, error)
 }
 
 type adminClient struct {
@@ -30,7 +32,7 @@ func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
 	return &adminClient{cc}
 }
 
-func (c *adminClient) Logging(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (Admin_LoggingClient, error) {
+func (c *adminClient) Logging(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (Admin_LoggingClient, error) {
 	stream, err := c.cc.NewStream(ctx, &Admin_ServiceDesc.Streams[0], "/main.Admin/Logging", opts...)
 	if err != nil {
 		return nil, err
@@ -94,12 +96,32 @@ func (x *adminStatisticsClient) Recv() (*Stat, error) {
 	return m, nil
 }
 
+func (c *adminClient) ReloadACL(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ACLStatus, error) {
+	out := new(ACLStatus)
+	err := c.cc.Invoke(ctx, "/main.Admin/ReloadACL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) QuotaUsage(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*QuotaReport, error) {
+	out := new(QuotaReport)
+	err := c.cc.Invoke(ctx, "/main.Admin/QuotaUsage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AdminServer is the server API for Admin service.
 // All implementations must embed UnimplementedAdminServer
 // for forward compatibility
 type AdminServer interface {
-	Logging(*Nothing, Admin_LoggingServer) error
+	Logging(*LogFilter, Admin_LoggingServer) error
 	Statistics(*StatInterval, Admin_StatisticsServer) error
+	ReloadACL(context.Context, *Nothing) (*ACLStatus, error)
+	QuotaUsage(context.Context, *Nothing) (*QuotaReport, error)
 	mustEmbedUnimplementedAdminServer()
 }
 
@@ -107,12 +129,18 @@ type AdminServer interface {
 type UnimplementedAdminServer struct {
 }
 
-func (UnimplementedAdminServer) Logging(*Nothing, Admin_LoggingServer) error {
+func (UnimplementedAdminServer) Logging(*LogFilter, Admin_LoggingServer) error {
 	return status.Errorf(codes.Unimplemented, "method Logging not implemented")
 }
 func (UnimplementedAdminServer) Statistics(*StatInterval, Admin_StatisticsServer) error {
 	return status.Errorf(codes.Unimplemented, "method Statistics not implemented")
 }
+func (UnimplementedAdminServer) ReloadACL(context.Context, *Nothing) (*ACLStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadACL not implemented")
+}
+func (UnimplementedAdminServer) QuotaUsage(context.Context, *Nothing) (*QuotaReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuotaUsage not implemented")
+}
 func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
 
 // UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
@@ -127,7 +155,7 @@ func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
 }
 
 func _Admin_Logging_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(Nothing)
+	m := new(LogFilter)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -168,13 +196,58 @@ func (x *adminStatisticsServer) Send(m *Stat) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Admin_ReloadACL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ReloadACL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Admin/ReloadACL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ReloadACL(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_QuotaUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).QuotaUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Admin/QuotaUsage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).QuotaUsage(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Admin_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "main.Admin",
 	HandlerType: (*AdminServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReloadACL",
+			Handler:    _Admin_ReloadACL_Handler,
+		},
+		{
+			MethodName: "QuotaUsage",
+			Handler:    _Admin_QuotaUsage_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Logging",
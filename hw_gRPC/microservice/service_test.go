@@ -131,16 +131,19 @@ func TestACL(t *testing.T) {
 	biz := NewBizClient(conn)
 	adm := NewAdminClient(conn)
 
-	for idx, ctx := range []context.Context{
-		context.Background(),       // нет поля для ACL
-		getConsumerCtx("unknown"),  // поле есть, неизвестный консюмер
-		getConsumerCtx("biz_user"), // поле есть, нет доступа
+	for idx, tc := range []struct {
+		ctx  context.Context
+		code codes.Code
+	}{
+		{context.Background(), codes.Unauthenticated},        // нет поля для ACL
+		{getConsumerCtx("unknown"), codes.PermissionDenied},  // поле есть, неизвестный консюмер
+		{getConsumerCtx("biz_user"), codes.PermissionDenied}, // поле есть, нет доступа
 	} {
-		_, err = biz.Test(ctx, &Nothing{})
+		_, err = biz.Test(tc.ctx, &Nothing{})
 		if err == nil {
 			t.Fatalf("[%d] ACL fail: expected err on disallowed method", idx)
-		} else if code := status.Code(err); code != codes.Unauthenticated {
-			t.Fatalf("[%d] ACL fail: expected Unauthenticated code, got %v", idx, code)
+		} else if code := status.Code(err); code != tc.code {
+			t.Fatalf("[%d] ACL fail: expected %v code, got %v", idx, tc.code, code)
 		}
 	}
 
@@ -159,15 +162,15 @@ func TestACL(t *testing.T) {
 	}
 
 	// ACL на методах, которые возвращают поток данных
-	logger, err := adm.Logging(getConsumerCtx("unknown"), &Nothing{})
+	logger, err := adm.Logging(getConsumerCtx("unknown"), &LogFilter{})
 	if err != nil {
 		t.Fatal(err)
 	}
 	_, err = logger.Recv()
 	if err == nil {
 		t.Fatalf("ACL fail: expected err on disallowed method")
-	} else if code := status.Code(err); code != codes.Unauthenticated {
-		t.Fatalf("ACL fail: expected Unauthenticated code, got %v", code)
+	} else if code := status.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("ACL fail: expected PermissionDenied code, got %v", code)
 	}
 }
 
@@ -189,13 +192,13 @@ func TestLogging(t *testing.T) {
 	biz := NewBizClient(conn)
 	adm := NewAdminClient(conn)
 
-	logStream1, err := adm.Logging(getConsumerCtx("logger"), &Nothing{})
+	logStream1, err := adm.Logging(getConsumerCtx("logger"), &LogFilter{})
 	if err != nil {
 		t.Fatal(err)
 	}
 	time.Sleep(1 * time.Millisecond)
 
-	logStream2, err := adm.Logging(getConsumerCtx("logger"), &Nothing{})
+	logStream2, err := adm.Logging(getConsumerCtx("logger"), &LogFilter{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// maxLatencySamples bounds the latencies kept per method and interval,
+// beyond it a uniform sample is kept.
+const maxLatencySamples = 4096
+
+type methodWindow struct {
+	completed uint64
+	errors    uint64
+	byCode    map[string]uint64
+	latencies []time.Duration
+}
+
+// statWindow collects one Statistics stream's interval.
+type statWindow struct {
+	byMethod   map[string]uint64
+	byConsumer map[string]uint64
+	methods    map[string]*methodWindow
+
+	deniedByMethod          map[string]uint64
+	deniedByConsumer        map[string]uint64
+	unauthenticatedByMethod map[string]uint64
+	quotaByMethod           map[string]uint64
+	quotaByConsumer         map[string]uint64
+}
+
+func newStatWindow() *statWindow {
+	return &statWindow{
+		byMethod:                make(map[string]uint64),
+		byConsumer:              make(map[string]uint64),
+		methods:                 make(map[string]*methodWindow),
+		deniedByMethod:          make(map[string]uint64),
+		deniedByConsumer:        make(map[string]uint64),
+		unauthenticatedByMethod: make(map[string]uint64),
+		quotaByMethod:           make(map[string]uint64),
+		quotaByConsumer:         make(map[string]uint64),
+	}
+}
+
+func (w *statWindow) method(name string) *methodWindow {
+	m, ok := w.methods[name]
+	if !ok {
+		m = &methodWindow{byCode: make(map[string]uint64)}
+		w.methods[name] = m
+	}
+	return m
+}
+
+// StatsHub feeds the Statistics streams. Calls are counted when they start,
+// so a stream shows up while it runs, and their code and latency are added
+// when they finish. Calls turned away are only counted as denied,
+// unauthenticated or over quota.
+type StatsHub struct {
+	mu      sync.Mutex
+	windows map[*statWindow]struct{}
+}
+
+func NewStatsHub() *StatsHub {
+	return &StatsHub{windows: make(map[*statWindow]struct{})}
+}
+
+func (h *StatsHub) Subscribe() *statWindow {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w := newStatWindow()
+	h.windows[w] = struct{}{}
+	return w
+}
+
+func (h *StatsHub) Unsubscribe(w *statWindow) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.windows, w)
+}
+
+func (h *StatsHub) CallStarted(consumer, method string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.windows {
+		w.byConsumer[consumer]++
+		w.byMethod[method]++
+	}
+}
+
+func (h *StatsHub) CallFinished(method string, code codes.Code, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.windows {
+		m := w.method(method)
+		m.completed++
+		m.byCode[code.String()]++
+		if code != codes.OK {
+			m.errors++
+		}
+		if len(m.latencies) < maxLatencySamples {
+			m.latencies = append(m.latencies, latency)
+		} else if i := rand.Int63n(int64(m.completed)); i < maxLatencySamples {
+			m.latencies[i] = latency
+		}
+	}
+}
+
+func (h *StatsHub) CallRejected(consumer, method string, decision AccessDecision) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.windows {
+		switch decision {
+		case AccessDecision_UNAUTHENTICATED:
+			w.unauthenticatedByMethod[method]++
+		case AccessDecision_QUOTA_EXCEEDED:
+			w.quotaByMethod[method]++
+			w.quotaByConsumer[consumer]++
+		default:
+			w.deniedByMethod[method]++
+			w.deniedByConsumer[consumer]++
+		}
+	}
+}
+
+// Flush returns the window as a Stat and starts a new interval.
+func (h *StatsHub) Flush(w *statWindow) *Stat {
+	h.mu.Lock()
+	current := *w
+	*w = *newStatWindow()
+	h.mu.Unlock()
+
+	stat := &Stat{
+		Timestamp:  time.Now().Unix(),
+		ByMethod:   current.byMethod,
+		ByConsumer: current.byConsumer,
+		Methods:    make(map[string]*MethodStat, len(current.methods)),
+
+		DeniedByMethod:          current.deniedByMethod,
+		DeniedByConsumer:        current.deniedByConsumer,
+		UnauthenticatedByMethod: current.unauthenticatedByMethod,
+		QuotaExceededByMethod:   current.quotaByMethod,
+		QuotaExceededByConsumer: current.quotaByConsumer,
+	}
+	for name, m := range current.methods {
+		sort.Slice(m.latencies, func(i, j int) bool { return m.latencies[i] < m.latencies[j] })
+		stat.Methods[name] = &MethodStat{
+			Completed: m.completed,
+			Errors:    m.errors,
+			ByCode:    m.byCode,
+			P50Ms:     percentile(m.latencies, 0.50),
+			P95Ms:     percentile(m.latencies, 0.95),
+			P99Ms:     percentile(m.latencies, 0.99),
+		}
+	}
+	return stat
+}
+
+// percentile takes sorted latencies and uses the nearest rank.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank]) / float64(time.Millisecond)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestStatsHub(t *testing.T) {
+	hub := NewStatsHub()
+	w := hub.Subscribe()
+	for i := 1; i <= 100; i++ {
+		code := codes.OK
+		if i%10 == 0 {
+			code = codes.NotFound
+		}
+		hub.CallStarted("biz_user", "/main.Biz/Check")
+		hub.CallFinished("/main.Biz/Check", code, time.Duration(i)*time.Millisecond)
+	}
+	hub.CallStarted("logger", "/main.Admin/Logging")
+
+	stat := hub.Flush(w)
+	if stat.ByMethod["/main.Biz/Check"] != 100 || stat.ByConsumer["logger"] != 1 {
+		t.Errorf("bad counters: %v %v", stat.ByMethod, stat.ByConsumer)
+	}
+	if _, ok := stat.Methods["/main.Admin/Logging"]; ok {
+		t.Errorf("running call has a completion entry")
+	}
+	check := stat.Methods["/main.Biz/Check"]
+	if check.Completed != 100 || check.Errors != 10 || !reflect.DeepEqual(check.ByCode, map[string]uint64{"OK": 90, "NotFound": 10}) {
+		t.Errorf("bad completions: %v", check)
+	}
+	if check.P50Ms != 50 || check.P95Ms != 95 || check.P99Ms != 99 {
+		t.Errorf("bad percentiles: %v %v %v", check.P50Ms, check.P95Ms, check.P99Ms)
+	}
+
+	if stat = hub.Flush(w); len(stat.ByMethod) != 0 || len(stat.Methods) != 0 {
+		t.Errorf("interval was not reset: %v", stat)
+	}
+	hub.Unsubscribe(w)
+	hub.CallStarted("biz_user", "/main.Biz/Check")
+	if len(w.byMethod) != 0 {
+		t.Errorf("unsubscribed window still counts")
+	}
+}
+
+func TestStatisticsCompletions(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	statStream, err := adm.Statistics(getConsumerCtx("stat"), &StatInterval{IntervalSeconds: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Check(getConsumerCtx("biz_admin"), &Nothing{})
+
+	stat, err := statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v, awaiting stat", err)
+	}
+	check := stat.Methods["/main.Biz/Check"]
+	if check == nil || check.Completed != 2 || check.Errors != 0 || check.ByCode["OK"] != 2 {
+		t.Errorf("bad completions: %v", stat.Methods)
+	}
+}
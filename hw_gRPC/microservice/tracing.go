@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "microservice"
+
+// metadataCarrier lets the propagator read traceparent from gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if val := metadata.MD(c).Get(key); len(val) > 0 {
+		return val[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Tracing starts a server span per call and continues an incoming W3C
+// traceparent if there is one.
+type Tracing struct {
+	Tracer     trace.Tracer
+	Propagator propagation.TextMapPropagator
+}
+
+func NewTracing(provider trace.TracerProvider) *Tracing {
+	return &Tracing{
+		Tracer:     provider.Tracer(instrumentationName),
+		Propagator: propagation.TraceContext{},
+	}
+}
+
+func (t *Tracing) start(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = t.Propagator.Extract(ctx, metadataCarrier(md))
+	}
+	service, method := splitMethod(fullMethod)
+	return t.Tracer.Start(ctx, fullMethod[1:],
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+		),
+	)
+}
+
+func finishSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if code != codes.OK {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+func (t *Tracing) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := t.start(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		finishSpan(span, err)
+		return resp, err
+	}
+}
+
+func (t *Tracing) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := t.start(stream.Context(), info.FullMethod)
+		err := handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+		finishSpan(span, err)
+		return err
+	}
+}